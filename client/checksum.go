@@ -0,0 +1,90 @@
+package client
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc64"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+const objectTypeHeader = "X-Oss-Object-Type"
+
+// ErrChecksumMismatch is returned when a downloaded file does not match the checksum stored with the object.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// VerifyChecksum compares the content of filePath with the checksum found in the object headers.
+// The CRC64 calculated by OSS is preferred. Objects without it are verified against the Content-MD5
+// or, for objects uploaded in a single request, the ETag. Objects without any usable checksum are accepted.
+func VerifyChecksum(filePath string, objectHeader http.Header) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	verifier := newChecksumVerifier()
+	_, err = io.Copy(verifier, file)
+	if err != nil {
+		return fmt.Errorf("failed to calculate checksum: %w", err)
+	}
+
+	return verifier.verify(objectHeader)
+}
+
+// checksumVerifier hashes the content written to it, so that streamed content can be verified
+// against the checksum found in the object headers once it has been written completely.
+type checksumVerifier struct {
+	crc hash.Hash64
+	md5 hash.Hash
+}
+
+func newChecksumVerifier() *checksumVerifier {
+	return &checksumVerifier{crc: crc64.New(oss.CrcTable()), md5: md5.New()}
+}
+
+func (v *checksumVerifier) Write(p []byte) (int, error) {
+	_, _ = v.crc.Write(p)
+	return v.md5.Write(p)
+}
+
+func (v *checksumVerifier) verify(objectHeader http.Header) error {
+	if expected := objectHeader.Get(oss.HTTPHeaderOssCRC64); expected != "" {
+		expectedCRC, err := strconv.ParseUint(expected, 10, 64)
+		if err != nil {
+			return fmt.Errorf("parsing CRC64 '%s' of object: %w", expected, err)
+		}
+
+		if actualCRC := v.crc.Sum64(); actualCRC != expectedCRC {
+			return fmt.Errorf("%w: expected CRC64 %d, got %d", ErrChecksumMismatch, expectedCRC, actualCRC)
+		}
+		return nil
+	}
+
+	if expected := objectHeader.Get(oss.HTTPHeaderContentMD5); expected != "" {
+		if actual := base64.StdEncoding.EncodeToString(v.md5.Sum(nil)); actual != expected {
+			return fmt.Errorf("%w: expected Content-MD5 %s, got %s", ErrChecksumMismatch, expected, actual)
+		}
+		return nil
+	}
+
+	// The ETag of objects uploaded with a multipart upload is not the MD5 of their content
+	expected := strings.Trim(objectHeader.Get(oss.HTTPHeaderEtag), `"`)
+	if expected != "" && objectHeader.Get(objectTypeHeader) == "Normal" && !strings.Contains(expected, "-") {
+		if actual := hex.EncodeToString(v.md5.Sum(nil)); !strings.EqualFold(actual, expected) {
+			return fmt.Errorf("%w: expected ETag %s, got %s", ErrChecksumMismatch, expected, actual)
+		}
+	}
+
+	return nil
+}
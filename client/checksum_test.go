@@ -0,0 +1,87 @@
+package client_test
+
+import (
+	"errors"
+	"hash/crc64"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/client"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("VerifyChecksum", func() {
+	var filePath string
+
+	BeforeEach(func() {
+		file, err := os.CreateTemp("", "ali-storage-cli-checksum")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = file.WriteString("foo")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(file.Close()).To(Succeed())
+		filePath = file.Name()
+	})
+
+	AfterEach(func() {
+		_ = os.Remove(filePath)
+	})
+
+	Context("when the object has a CRC64", func() {
+		It("accepts a matching file", func() {
+			header := http.Header{}
+			header.Set(oss.HTTPHeaderOssCRC64, strconv.FormatUint(crc64.Checksum([]byte("foo"), oss.CrcTable()), 10))
+
+			Expect(client.VerifyChecksum(filePath, header)).To(Succeed())
+		})
+
+		It("rejects a different file", func() {
+			header := http.Header{}
+			header.Set(oss.HTTPHeaderOssCRC64, strconv.FormatUint(crc64.Checksum([]byte("bar"), oss.CrcTable()), 10))
+			header.Set(oss.HTTPHeaderContentMD5, "rL0Y20zC+Fzt72VPzMSk2A==")
+
+			err := client.VerifyChecksum(filePath, header)
+			Expect(errors.Is(err, client.ErrChecksumMismatch)).To(BeTrue())
+		})
+	})
+
+	Context("when the object has a Content-MD5", func() {
+		It("accepts a matching file", func() {
+			header := http.Header{}
+			header.Set(oss.HTTPHeaderContentMD5, "rL0Y20zC+Fzt72VPzMSk2A==")
+
+			Expect(client.VerifyChecksum(filePath, header)).To(Succeed())
+		})
+
+		It("rejects a different file", func() {
+			header := http.Header{}
+			header.Set(oss.HTTPHeaderContentMD5, "1B2M2Y8AsgTpgAmY7PhCfg==")
+
+			err := client.VerifyChecksum(filePath, header)
+			Expect(errors.Is(err, client.ErrChecksumMismatch)).To(BeTrue())
+		})
+	})
+
+	Context("when the object only has an ETag", func() {
+		It("compares the ETag of objects uploaded in a single request", func() {
+			header := http.Header{}
+			header.Set(oss.HTTPHeaderEtag, `"ACBD18DB4CC2F85CEDEF654FCCC4A4D8"`)
+			header.Set("X-Oss-Object-Type", "Normal")
+			Expect(client.VerifyChecksum(filePath, header)).To(Succeed())
+
+			header.Set(oss.HTTPHeaderEtag, `"D41D8CD98F00B204E9800998ECF8427E"`)
+			err := client.VerifyChecksum(filePath, header)
+			Expect(errors.Is(err, client.ErrChecksumMismatch)).To(BeTrue())
+		})
+
+		It("ignores the ETag of objects uploaded with a multipart upload", func() {
+			header := http.Header{}
+			header.Set(oss.HTTPHeaderEtag, `"D41D8CD98F00B204E9800998ECF8427E-3"`)
+			header.Set("X-Oss-Object-Type", "Multipart")
+
+			Expect(client.VerifyChecksum(filePath, header)).To(Succeed())
+		})
+	})
+})
@@ -1,13 +1,16 @@
 package client
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"net/http"
 	"os"
 	"strings"
+	"sync"
 )
 
 type AliBlobstore struct {
@@ -18,43 +21,200 @@ func New(storageClient StorageClient) (AliBlobstore, error) {
 	return AliBlobstore{storageClient: storageClient}, nil
 }
 
-func (client *AliBlobstore) Put(sourceFilePath string, destinationObject string) error {
+func (client *AliBlobstore) Put(ctx context.Context, sourceFilePath string, destinationObject string) error {
 	sourceFileMD5, err := client.getMD5(sourceFilePath)
 	if err != nil {
 		return err
 	}
 
-	err = client.storageClient.Upload(sourceFilePath, sourceFileMD5, destinationObject)
+	err = client.storageClient.Upload(ctx, sourceFilePath, sourceFileMD5, destinationObject)
 	if err != nil {
 		return fmt.Errorf("upload failure: %w", err)
 	}
 
-	log.Println("Successfully uploaded file")
 	return nil
 }
 
-func (client *AliBlobstore) Get(sourceObject string, destinationFilePath string) error {
-	return client.storageClient.Download(sourceObject, destinationFilePath)
+// PutReader uploads the content read from reader to key. A negative size stands for content of unknown size,
+// which is read until EOF. Cancelling ctx aborts the upload.
+func (client *AliBlobstore) PutReader(ctx context.Context, key string, reader io.Reader, size int64, opts PutOptions) error {
+	err := client.storageClient.UploadStream(ctx, reader, size, key, opts)
+	if err != nil {
+		return fmt.Errorf("upload failure: %w", err)
+	}
+
+	return nil
+}
+
+// GetWriter writes the content of key to writer. On a checksum mismatch ErrChecksumMismatch is returned
+// after the content has been written.
+func (client *AliBlobstore) GetWriter(ctx context.Context, key string, writer io.Writer, opts GetOptions) error {
+	reader, err := client.storageClient.OpenStream(ctx, key, opts)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	_, err = io.Copy(writer, reader)
+	return err
+}
+
+// Open returns a reader of the content of key, which has to be closed by the caller.
+// Reading the complete content returns ErrChecksumMismatch instead of io.EOF if the content is corrupted.
+func (client *AliBlobstore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	return client.storageClient.OpenStream(ctx, key, GetOptions{})
+}
+
+func (client *AliBlobstore) Get(ctx context.Context, sourceObject string, destinationFilePath string) error {
+	return client.storageClient.Download(ctx, sourceObject, destinationFilePath)
+}
+
+func (client *AliBlobstore) Delete(ctx context.Context, object string) error {
+	return client.storageClient.Delete(ctx, object)
+}
+
+// DeleteRecursive deletes all blobs below prefix, one listed page of up to 1000 blobs at a time. The pages are deleted
+// while the next ones are listed, up to deleteConcurrency at once. An empty prefix is rejected, as it stands for the
+// whole bucket.
+// The returned error reports whether any blob could not be deleted, the result lists the individual keys.
+func (client *AliBlobstore) DeleteRecursive(ctx context.Context, prefix string) (DeleteResult, error) {
+	if prefix == "" {
+		return DeleteResult{}, errors.New("refusing to delete recursively with an empty prefix, which stands for the whole bucket")
+	}
+
+	var (
+		mutex     sync.Mutex
+		wg        sync.WaitGroup
+		result    DeleteResult
+		deleteErr error
+	)
+	semaphore := make(chan struct{}, deleteConcurrency)
+
+	// failed tells whether a page could not be deleted, which stops listing further pages
+	failed := func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return deleteErr != nil
+	}
+
+	var listErr error
+	continuationToken := ""
+	for !failed() {
+		listed, nextContinuationToken, err := client.storageClient.ListPage(ctx, prefix, continuationToken)
+		if err != nil {
+			listErr = fmt.Errorf("listing blobs: %w", err)
+			break
+		}
+
+		if len(listed.Objects) > 0 {
+			objects := make([]string, 0, len(listed.Objects))
+			for _, object := range listed.Objects {
+				objects = append(objects, object.Key)
+			}
+
+			wg.Add(1)
+			semaphore <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+
+				deleted, err := client.storageClient.DeleteObjects(ctx, objects)
+
+				mutex.Lock()
+				defer mutex.Unlock()
+				result.Deleted = append(result.Deleted, deleted.Deleted...)
+				result.Failed = append(result.Failed, deleted.Failed...)
+				if err != nil && deleteErr == nil {
+					deleteErr = err
+				}
+			}()
+		}
+
+		if nextContinuationToken == "" {
+			break
+		}
+		continuationToken = nextContinuationToken
+	}
+	wg.Wait()
+
+	if listErr != nil {
+		return result, listErr
+	}
+	if deleteErr != nil {
+		return result, deleteErr
+	}
+	if len(result.Failed) > 0 {
+		return result, fmt.Errorf("failed to delete %d of %d blobs", len(result.Failed), len(result.Deleted)+len(result.Failed))
+	}
+
+	return result, nil
 }
 
-func (client *AliBlobstore) Delete(object string) error {
-	return client.storageClient.Delete(object)
+// Copy copies a blob server-side, destinationBucketName defaults to the configured bucket if empty.
+func (client *AliBlobstore) Copy(ctx context.Context, sourceObject string, destinationBucketName string, destinationObject string) error {
+	return client.storageClient.Copy(ctx, sourceObject, destinationBucketName, destinationObject)
 }
 
-func (client *AliBlobstore) Exists(object string) (bool, error) {
-	return client.storageClient.Exists(object)
+// Move copies a blob server-side and deletes the source blob once the copy succeeded. Moving a blob onto itself fails,
+// as copying a blob onto itself is refused.
+func (client *AliBlobstore) Move(ctx context.Context, sourceObject string, destinationBucketName string, destinationObject string) error {
+	err := client.storageClient.Copy(ctx, sourceObject, destinationBucketName, destinationObject)
+	if err != nil {
+		return err
+	}
+
+	return client.storageClient.Delete(ctx, sourceObject)
+}
+
+func (client *AliBlobstore) Exists(ctx context.Context, object string) (bool, error) {
+	return client.storageClient.Exists(ctx, object)
 }
 
-func (client *AliBlobstore) Sign(object string, action string, expiredInSec int64) (string, error) {
-	action = strings.ToUpper(action)
-	switch action {
-	case "PUT":
-		return client.storageClient.SignedUrlPut(object, expiredInSec)
-	case "GET":
-		return client.storageClient.SignedUrlGet(object, expiredInSec)
+func (client *AliBlobstore) Stat(ctx context.Context, object string) (ObjectProperties, error) {
+	return client.storageClient.Head(ctx, object)
+}
+
+func (client *AliBlobstore) List(ctx context.Context, prefix string, delimiter string, maxKeys int) (ListResult, error) {
+	return client.storageClient.List(ctx, prefix, delimiter, maxKeys)
+}
+
+// Sign returns a URL authorizing a get or put of object until it expires, see SignURL.
+func (client *AliBlobstore) Sign(ctx context.Context, object string, action string, expiredInSec int64) (string, error) {
+	signedURL, err := client.SignURL(ctx, object, action, expiredInSec, SignOptions{})
+	return signedURL.URL, err
+}
+
+// SignURL returns a URL authorizing a get, put, head or delete of object until it expires, with the headers the
+// request through the URL has to send.
+func (client *AliBlobstore) SignURL(ctx context.Context, object string, action string, expiredInSec int64, opts SignOptions) (SignedURL, error) {
+	method := strings.ToUpper(action)
+	switch method {
+	case http.MethodGet, http.MethodPut, http.MethodHead, http.MethodDelete:
 	default:
-		return "", fmt.Errorf("action not implemented: %s", action)
+		return SignedURL{}, fmt.Errorf("action not implemented: %s", action)
+	}
+
+	err := opts.Validate(method)
+	if err != nil {
+		return SignedURL{}, err
+	}
+
+	return client.storageClient.SignURL(ctx, object, method, expiredInSec, opts)
+}
+
+// SignPost returns a signed policy and the form fields authorizing PostObject uploads of blobs which satisfy the
+// conditions of opts until it expires, in a positive number of seconds.
+func (client *AliBlobstore) SignPost(ctx context.Context, expiredInSec int64, opts PostPolicyOptions) (SignedPostPolicy, error) {
+	err := opts.Validate()
+	if err != nil {
+		return SignedPostPolicy{}, err
 	}
+	err = validatePostExpiration(expiredInSec)
+	if err != nil {
+		return SignedPostPolicy{}, err
+	}
+
+	return client.storageClient.SignPostPolicy(ctx, expiredInSec, opts)
 }
 
 func (client *AliBlobstore) getMD5(filePath string) (string, error) {
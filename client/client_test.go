@@ -1,12 +1,20 @@
 package client_test
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"github.com/cloudfoundry/bosh-ali-storage-cli/client"
 	"github.com/cloudfoundry/bosh-ali-storage-cli/client/clientfakes"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"io"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing/iotest"
 )
 
 var _ = Describe("Client", func() {
@@ -20,10 +28,10 @@ var _ = Describe("Client", func() {
 
 			tmpFile, err := os.CreateTemp("", "azure-storage-cli-test")
 
-			aliBlobstore.Put(tmpFile.Name(), "destination_object")
+			aliBlobstore.Put(context.Background(), tmpFile.Name(), "destination_object")
 
 			Expect(storageClient.UploadCallCount()).To(Equal(1))
-			sourceFilePath, sourceFileMD5, destination := storageClient.UploadArgsForCall(0)
+			_, sourceFilePath, sourceFileMD5, destination := storageClient.UploadArgsForCall(0)
 
 			Expect(sourceFilePath).To(BeAssignableToTypeOf("source/file/path"))
 			Expect(sourceFileMD5).To(Equal("1B2M2Y8AsgTpgAmY7PhCfg=="))
@@ -31,6 +39,64 @@ var _ = Describe("Client", func() {
 		})
 	})
 
+	Context("PutReader", func() {
+		It("uploads the content of the reader to a blob", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+
+			aliBlobstore, _ := client.New(&storageClient)
+			opts := client.PutOptions{ContentType: "text/plain", Metadata: map[string]string{"owner": "bosh"}}
+			err := aliBlobstore.PutReader(context.Background(), "destination_object", strings.NewReader("foo"), 3, opts)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, source, size, destination, options := storageClient.UploadStreamArgsForCall(0)
+			Expect(source).To(Equal(strings.NewReader("foo")))
+			Expect(size).To(Equal(int64(3)))
+			Expect(destination).To(Equal("destination_object"))
+			Expect(options).To(Equal(opts))
+		})
+	})
+
+	Context("GetWriter", func() {
+		It("writes the content of the blob to the writer", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+			storageClient.OpenStreamReturns(io.NopCloser(strings.NewReader("foo")), nil)
+
+			aliBlobstore, _ := client.New(&storageClient)
+			destination := &bytes.Buffer{}
+			err := aliBlobstore.GetWriter(context.Background(), "source_object", destination, client.GetOptions{IfMatch: "etag"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(destination.String()).To(Equal("foo"))
+
+			_, sourceObject, options := storageClient.OpenStreamArgsForCall(0)
+			Expect(sourceObject).To(Equal("source_object"))
+			Expect(options.IfMatch).To(Equal("etag"))
+		})
+
+		It("returns the error of a failed read", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+			storageClient.OpenStreamReturns(io.NopCloser(iotest.ErrReader(client.ErrChecksumMismatch)), nil)
+
+			aliBlobstore, _ := client.New(&storageClient)
+			err := aliBlobstore.GetWriter(context.Background(), "source_object", &bytes.Buffer{}, client.GetOptions{})
+			Expect(errors.Is(err, client.ErrChecksumMismatch)).To(BeTrue())
+		})
+	})
+
+	Context("Open", func() {
+		It("returns a reader of the blob", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+			storageClient.OpenStreamReturns(io.NopCloser(strings.NewReader("foo")), nil)
+
+			aliBlobstore, _ := client.New(&storageClient)
+			reader, err := aliBlobstore.Open(context.Background(), "source_object")
+			Expect(err).ToNot(HaveOccurred())
+
+			content, err := io.ReadAll(reader)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal("foo"))
+		})
+	})
+
 	Context("Get", func() {
 		It("get blob downloads to a file", func() {
 			storageClient := clientfakes.FakeStorageClient{}
@@ -38,10 +104,10 @@ var _ = Describe("Client", func() {
 			aliBlobstore, err := client.New(&storageClient)
 			Expect(err).ToNot(HaveOccurred())
 
-			aliBlobstore.Get("source_object", "destination/file/path")
+			aliBlobstore.Get(context.Background(), "source_object", "destination/file/path")
 
 			Expect(storageClient.DownloadCallCount()).To(Equal(1))
-			sourceObject, destinationFilePath := storageClient.DownloadArgsForCall(0)
+			_, sourceObject, destinationFilePath := storageClient.DownloadArgsForCall(0)
 
 			Expect(sourceObject).To(Equal("source_object"))
 			Expect(destinationFilePath).To(Equal("destination/file/path"))
@@ -55,26 +121,176 @@ var _ = Describe("Client", func() {
 			aliBlobstore, err := client.New(&storageClient)
 			Expect(err).ToNot(HaveOccurred())
 
-			aliBlobstore.Delete("blob")
+			aliBlobstore.Delete(context.Background(), "blob")
 
 			Expect(storageClient.DeleteCallCount()).To(Equal(1))
-			object := storageClient.DeleteArgsForCall(0)
+			_, object := storageClient.DeleteArgsForCall(0)
 
 			Expect(object).To(Equal("blob"))
 		})
 	})
 
+	Context("DeleteRecursive", func() {
+		It("deletes all blobs below the prefix", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+			storageClient.ListPageReturns(client.ListResult{
+				Objects: []client.ListedObject{{Key: "foo/a"}, {Key: "foo/bar/b"}},
+			}, "", nil)
+			storageClient.DeleteObjectsReturns(client.DeleteResult{Deleted: []string{"foo/a", "foo/bar/b"}}, nil)
+
+			aliBlobstore, _ := client.New(&storageClient)
+			result, err := aliBlobstore.DeleteRecursive(context.Background(), "foo/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Deleted).To(ConsistOf("foo/a", "foo/bar/b"))
+
+			_, prefix, continuationToken := storageClient.ListPageArgsForCall(0)
+			Expect(prefix).To(Equal("foo/"))
+			Expect(continuationToken).To(BeEmpty())
+			_, objects := storageClient.DeleteObjectsArgsForCall(0)
+			Expect(objects).To(Equal([]string{"foo/a", "foo/bar/b"}))
+		})
+
+		It("deletes the listed pages while listing the next ones, up to DeleteConcurrency at once", func() {
+			const pages = 10
+
+			storageClient := clientfakes.FakeStorageClient{}
+			storageClient.ListPageStub = func(_ context.Context, _ string, continuationToken string) (client.ListResult, string, error) {
+				page, _ := strconv.Atoi(continuationToken)
+				nextContinuationToken := ""
+				if page+1 < pages {
+					nextContinuationToken = strconv.Itoa(page + 1)
+				}
+				return client.ListResult{Objects: []client.ListedObject{{Key: fmt.Sprintf("foo/%d", page)}}}, nextContinuationToken, nil
+			}
+
+			// The first deletes wait for each other, so that they are only done once all of them are in flight
+			var mutex sync.Mutex
+			var inFlight, maxInFlight int
+			var allInFlightOnce sync.Once
+			allInFlight := make(chan struct{})
+			storageClient.DeleteObjectsStub = func(_ context.Context, objects []string) (client.DeleteResult, error) {
+				mutex.Lock()
+				inFlight++
+				maxInFlight = max(maxInFlight, inFlight)
+				if inFlight == client.DeleteConcurrency {
+					allInFlightOnce.Do(func() { close(allInFlight) })
+				}
+				mutex.Unlock()
+
+				<-allInFlight
+
+				mutex.Lock()
+				inFlight--
+				mutex.Unlock()
+				return client.DeleteResult{Deleted: objects}, nil
+			}
+
+			aliBlobstore, _ := client.New(&storageClient)
+			result, err := aliBlobstore.DeleteRecursive(context.Background(), "foo/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Deleted).To(HaveLen(pages))
+			Expect(result.Deleted).To(ContainElements("foo/0", "foo/9"))
+			Expect(maxInFlight).To(Equal(client.DeleteConcurrency))
+			Expect(storageClient.ListPageCallCount()).To(Equal(pages))
+		})
+
+		It("stops listing once a page could not be deleted", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+			storageClient.ListPageReturns(client.ListResult{Objects: []client.ListedObject{{Key: "foo/a"}}}, "token", nil)
+			storageClient.DeleteObjectsReturns(client.DeleteResult{}, errors.New("boom"))
+
+			aliBlobstore, _ := client.New(&storageClient)
+			_, err := aliBlobstore.DeleteRecursive(context.Background(), "foo/")
+			Expect(err).To(MatchError("boom"))
+		})
+
+		It("does not send a delete request if there are no blobs below the prefix", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+
+			aliBlobstore, _ := client.New(&storageClient)
+			_, err := aliBlobstore.DeleteRecursive(context.Background(), "foo/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(storageClient.DeleteObjectsCallCount()).To(BeZero())
+		})
+
+		It("refuses to delete the whole bucket for an empty prefix", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+
+			aliBlobstore, _ := client.New(&storageClient)
+			_, err := aliBlobstore.DeleteRecursive(context.Background(), "")
+			Expect(err).To(MatchError(ContainSubstring("empty prefix")))
+			Expect(storageClient.ListPageCallCount()).To(BeZero())
+			Expect(storageClient.DeleteObjectsCallCount()).To(BeZero())
+		})
+
+		It("returns an error along with the blobs which could not be deleted", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+			storageClient.ListPageReturns(client.ListResult{
+				Objects: []client.ListedObject{{Key: "foo/a"}, {Key: "foo/b"}},
+			}, "", nil)
+			storageClient.DeleteObjectsReturns(client.DeleteResult{
+				Deleted: []string{"foo/a"},
+				Failed:  []client.DeleteFailure{{Key: "foo/b", Error: "boom"}},
+			}, nil)
+
+			aliBlobstore, _ := client.New(&storageClient)
+			result, err := aliBlobstore.DeleteRecursive(context.Background(), "foo/")
+			Expect(err).To(MatchError("failed to delete 1 of 2 blobs"))
+			Expect(result.Failed).To(ConsistOf(client.DeleteFailure{Key: "foo/b", Error: "boom"}))
+		})
+	})
+
+	Context("Copy", func() {
+		It("copies the blob server-side", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+
+			aliBlobstore, _ := client.New(&storageClient)
+			err := aliBlobstore.Copy(context.Background(), "source_object", "other-bucket", "destination_object")
+			Expect(err).ToNot(HaveOccurred())
+
+			_, sourceObject, destinationBucketName, destinationObject := storageClient.CopyArgsForCall(0)
+			Expect(sourceObject).To(Equal("source_object"))
+			Expect(destinationBucketName).To(Equal("other-bucket"))
+			Expect(destinationObject).To(Equal("destination_object"))
+			Expect(storageClient.DeleteCallCount()).To(BeZero())
+		})
+	})
+
+	Context("Move", func() {
+		It("deletes the source blob after copying it", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+
+			aliBlobstore, _ := client.New(&storageClient)
+			err := aliBlobstore.Move(context.Background(), "source_object", "", "destination_object")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(storageClient.CopyCallCount()).To(Equal(1))
+			_, object := storageClient.DeleteArgsForCall(0)
+			Expect(object).To(Equal("source_object"))
+		})
+
+		It("keeps the source blob if copying fails", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+			storageClient.CopyReturns(errors.New("boom"))
+
+			aliBlobstore, _ := client.New(&storageClient)
+			err := aliBlobstore.Move(context.Background(), "source_object", "", "destination_object")
+			Expect(err).To(MatchError("boom"))
+			Expect(storageClient.DeleteCallCount()).To(BeZero())
+		})
+	})
+
 	Context("Exists", func() {
 		It("returns blob.Existing on success", func() {
 			storageClient := clientfakes.FakeStorageClient{}
 			storageClient.ExistsReturns(true, nil)
 
 			aliBlobstore, _ := client.New(&storageClient)
-			existsState, err := aliBlobstore.Exists("blob")
+			existsState, err := aliBlobstore.Exists(context.Background(), "blob")
 			Expect(existsState == true).To(BeTrue())
 			Expect(err).ToNot(HaveOccurred())
 
-			object := storageClient.ExistsArgsForCall(0)
+			_, object := storageClient.ExistsArgsForCall(0)
 			Expect(object).To(Equal("blob"))
 		})
 
@@ -83,11 +299,11 @@ var _ = Describe("Client", func() {
 			storageClient.ExistsReturns(false, nil)
 
 			aliBlobstore, _ := client.New(&storageClient)
-			existsState, err := aliBlobstore.Exists("blob")
+			existsState, err := aliBlobstore.Exists(context.Background(), "blob")
 			Expect(existsState == false).To(BeTrue())
 			Expect(err).ToNot(HaveOccurred())
 
-			object := storageClient.ExistsArgsForCall(0)
+			_, object := storageClient.ExistsArgsForCall(0)
 			Expect(object).To(Equal("blob"))
 		})
 
@@ -96,54 +312,180 @@ var _ = Describe("Client", func() {
 			storageClient.ExistsReturns(false, errors.New("boom"))
 
 			aliBlobstore, _ := client.New(&storageClient)
-			existsState, err := aliBlobstore.Exists("blob")
+			existsState, err := aliBlobstore.Exists(context.Background(), "blob")
 			Expect(existsState == false).To(BeTrue())
 			Expect(err).To(HaveOccurred())
 
-			object := storageClient.ExistsArgsForCall(0)
+			_, object := storageClient.ExistsArgsForCall(0)
+			Expect(object).To(Equal("blob"))
+		})
+	})
+
+	Context("Stat", func() {
+		It("returns the properties of the blob", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+			storageClient.HeadReturns(client.ObjectProperties{Size: 3, ServerSideEncryption: "KMS"}, nil)
+
+			aliBlobstore, _ := client.New(&storageClient)
+			properties, err := aliBlobstore.Stat(context.Background(), "blob")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(properties.Size).To(Equal(int64(3)))
+			Expect(properties.ServerSideEncryption).To(Equal("KMS"))
+
+			_, object := storageClient.HeadArgsForCall(0)
 			Expect(object).To(Equal("blob"))
 		})
 	})
 
+	Context("List", func() {
+		It("returns the blobs and common prefixes below the prefix", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+			storageClient.ListReturns(client.ListResult{
+				Objects:        []client.ListedObject{{Key: "foo/blob", Size: 3}},
+				CommonPrefixes: []string{"foo/bar/"},
+			}, nil)
+
+			aliBlobstore, _ := client.New(&storageClient)
+			result, err := aliBlobstore.List(context.Background(), "foo/", "/", 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Objects).To(ConsistOf(client.ListedObject{Key: "foo/blob", Size: 3}))
+			Expect(result.CommonPrefixes).To(ConsistOf("foo/bar/"))
+
+			_, prefix, delimiter, maxKeys := storageClient.ListArgsForCall(0)
+			Expect(prefix).To(Equal("foo/"))
+			Expect(delimiter).To(Equal("/"))
+			Expect(maxKeys).To(Equal(10))
+		})
+	})
+
 	Context("signed url", func() {
 		It("returns a signed url for action 'get'", func() {
 			storageClient := clientfakes.FakeStorageClient{}
-			storageClient.SignedUrlGetReturns("https://the-signed-url", nil)
+			storageClient.SignURLReturns(client.SignedURL{URL: "https://the-signed-url"}, nil)
 
 			aliBlobstore, _ := client.New(&storageClient)
-			url, err := aliBlobstore.Sign("blob", "get", 100)
+			url, err := aliBlobstore.Sign(context.Background(), "blob", "get", 100)
 			Expect(url == "https://the-signed-url").To(BeTrue())
 			Expect(err).ToNot(HaveOccurred())
 
-			object, expiration := storageClient.SignedUrlGetArgsForCall(0)
+			_, object, method, expiration, _ := storageClient.SignURLArgsForCall(0)
 			Expect(object).To(Equal("blob"))
+			Expect(method).To(Equal("GET"))
 			Expect(int(expiration)).To(Equal(100))
 		})
 
 		It("returns a signed url for action 'put'", func() {
 			storageClient := clientfakes.FakeStorageClient{}
-			storageClient.SignedUrlPutReturns("https://the-signed-url", nil)
+			storageClient.SignURLReturns(client.SignedURL{URL: "https://the-signed-url"}, nil)
 
 			aliBlobstore, _ := client.New(&storageClient)
-			url, err := aliBlobstore.Sign("blob", "put", 100)
+			url, err := aliBlobstore.Sign(context.Background(), "blob", "put", 100)
 			Expect(url == "https://the-signed-url").To(BeTrue())
 			Expect(err).ToNot(HaveOccurred())
 
-			object, expiration := storageClient.SignedUrlPutArgsForCall(0)
+			_, object, method, expiration, _ := storageClient.SignURLArgsForCall(0)
 			Expect(object).To(Equal("blob"))
+			Expect(method).To(Equal("PUT"))
 			Expect(int(expiration)).To(Equal(100))
 		})
 
+		It("passes the options of head and delete urls", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+			aliBlobstore, _ := client.New(&storageClient)
+
+			_, err := aliBlobstore.SignURL(context.Background(), "blob", "head", 100, client.SignOptions{ResponseContentDisposition: "attachment"})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = aliBlobstore.SignURL(context.Background(), "blob", "DELETE", 100, client.SignOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, _, method, _, options := storageClient.SignURLArgsForCall(0)
+			Expect(method).To(Equal("HEAD"))
+			Expect(options.ResponseContentDisposition).To(Equal("attachment"))
+			_, _, method, _, _ = storageClient.SignURLArgsForCall(1)
+			Expect(method).To(Equal("DELETE"))
+		})
+
 		It("fails on unknown action", func() {
 			storageClient := clientfakes.FakeStorageClient{}
-			storageClient.SignedUrlGetReturns("", errors.New("boom"))
+			storageClient.SignURLReturns(client.SignedURL{}, errors.New("boom"))
 
 			aliBlobstore, _ := client.New(&storageClient)
-			url, err := aliBlobstore.Sign("blob", "unknown", 100)
+			url, err := aliBlobstore.Sign(context.Background(), "blob", "unknown", 100)
 			Expect(url).To(Equal(""))
 			Expect(err).To(HaveOccurred())
 
-			Expect(storageClient.SignedUrlGetCallCount()).To(Equal(0))
+			Expect(storageClient.SignURLCallCount()).To(Equal(0))
+		})
+
+		It("fails on options which do not apply to the action", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+			aliBlobstore, _ := client.New(&storageClient)
+
+			_, err := aliBlobstore.SignURL(context.Background(), "blob", "get", 100, client.SignOptions{ContentMD5: "rL0Y20zC+Fzt72VPzMSk2A=="})
+			Expect(err).To(MatchError("content type and content MD5 only apply to signed PUT urls"))
+
+			_, err = aliBlobstore.SignURL(context.Background(), "blob", "put", 100, client.SignOptions{ResponseContentDisposition: "attachment"})
+			Expect(err).To(MatchError("response content disposition only applies to signed GET and HEAD urls"))
+
+			_, err = aliBlobstore.SignURL(context.Background(), "blob", "delete", 100, client.SignOptions{TrafficLimit: client.MinTrafficLimit})
+			Expect(err).To(MatchError("traffic limit only applies to signed GET and PUT urls"))
+
+			_, err = aliBlobstore.SignURL(context.Background(), "blob", "get", 100, client.SignOptions{TrafficLimit: 1024})
+			Expect(err).To(MatchError("traffic limit 1024 is not between 819200 and 838860800 bit/s"))
+
+			Expect(storageClient.SignURLCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("signed post policy", func() {
+		It("passes the conditions of the policy", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+			storageClient.SignPostPolicyReturns(client.SignedPostPolicy{URL: "https://the-bucket-url"}, nil)
+
+			aliBlobstore, _ := client.New(&storageClient)
+			policy, err := aliBlobstore.SignPost(context.Background(), 100, client.PostPolicyOptions{KeyPrefix: "uploads/", MaxContentLength: 1024})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(policy.URL).To(Equal("https://the-bucket-url"))
+
+			_, expiration, options := storageClient.SignPostPolicyArgsForCall(0)
+			Expect(int(expiration)).To(Equal(100))
+			Expect(options).To(Equal(client.PostPolicyOptions{KeyPrefix: "uploads/", MaxContentLength: 1024}))
+		})
+
+		It("fails on an invalid content length range", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+			aliBlobstore, _ := client.New(&storageClient)
+
+			_, err := aliBlobstore.SignPost(context.Background(), 100, client.PostPolicyOptions{MinContentLength: 10, MaxContentLength: 5})
+			Expect(err).To(MatchError("minimum content length 10 must not be greater than maximum content length 5"))
+
+			_, err = aliBlobstore.SignPost(context.Background(), 100, client.PostPolicyOptions{MaxContentLength: client.MaxPostObjectSize + 1})
+			Expect(err).To(MatchError(ContainSubstring("exceeds the 5368709120 bytes of PostObject uploads")))
+
+			Expect(storageClient.SignPostPolicyCallCount()).To(Equal(0))
+		})
+
+		It("fails without a key prefix unless any key is allowed", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+			aliBlobstore, _ := client.New(&storageClient)
+
+			_, err := aliBlobstore.SignPost(context.Background(), 100, client.PostPolicyOptions{})
+			Expect(err).To(MatchError("a key prefix is required unless uploads to any key are allowed explicitly"))
+			Expect(storageClient.SignPostPolicyCallCount()).To(Equal(0))
+
+			_, err = aliBlobstore.SignPost(context.Background(), 100, client.PostPolicyOptions{AllowAnyKey: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(storageClient.SignPostPolicyCallCount()).To(Equal(1))
+		})
+
+		It("fails for an expiration which is not positive", func() {
+			storageClient := clientfakes.FakeStorageClient{}
+			aliBlobstore, _ := client.New(&storageClient)
+
+			_, err := aliBlobstore.SignPost(context.Background(), 0, client.PostPolicyOptions{KeyPrefix: "uploads/"})
+			Expect(err).To(MatchError("expiration of 0 seconds must be positive"))
+
+			Expect(storageClient.SignPostPolicyCallCount()).To(Equal(0))
 		})
 	})
 })
@@ -2,16 +2,33 @@
 package clientfakes
 
 import (
+	"context"
+	"io"
 	"sync"
 
 	"github.com/cloudfoundry/bosh-ali-storage-cli/client"
 )
 
 type FakeStorageClient struct {
-	DeleteStub        func(string) error
+	CopyStub        func(context.Context, string, string, string) error
+	copyMutex       sync.RWMutex
+	copyArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+		arg4 string
+	}
+	copyReturns struct {
+		result1 error
+	}
+	copyReturnsOnCall map[int]struct {
+		result1 error
+	}
+	DeleteStub        func(context.Context, string) error
 	deleteMutex       sync.RWMutex
 	deleteArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
+		arg2 string
 	}
 	deleteReturns struct {
 		result1 error
@@ -19,11 +36,26 @@ type FakeStorageClient struct {
 	deleteReturnsOnCall map[int]struct {
 		result1 error
 	}
-	DownloadStub        func(string, string) error
+	DeleteObjectsStub        func(context.Context, []string) (client.DeleteResult, error)
+	deleteObjectsMutex       sync.RWMutex
+	deleteObjectsArgsForCall []struct {
+		arg1 context.Context
+		arg2 []string
+	}
+	deleteObjectsReturns struct {
+		result1 client.DeleteResult
+		result2 error
+	}
+	deleteObjectsReturnsOnCall map[int]struct {
+		result1 client.DeleteResult
+		result2 error
+	}
+	DownloadStub        func(context.Context, string, string) error
 	downloadMutex       sync.RWMutex
 	downloadArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
+		arg3 string
 	}
 	downloadReturns struct {
 		result1 error
@@ -31,10 +63,11 @@ type FakeStorageClient struct {
 	downloadReturnsOnCall map[int]struct {
 		result1 error
 	}
-	ExistsStub        func(string) (bool, error)
+	ExistsStub        func(context.Context, string) (bool, error)
 	existsMutex       sync.RWMutex
 	existsArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
+		arg2 string
 	}
 	existsReturns struct {
 		result1 bool
@@ -44,40 +77,107 @@ type FakeStorageClient struct {
 		result1 bool
 		result2 error
 	}
-	SignedUrlGetStub        func(string, int64) (string, error)
-	signedUrlGetMutex       sync.RWMutex
-	signedUrlGetArgsForCall []struct {
-		arg1 string
-		arg2 int64
+	HeadStub        func(context.Context, string) (client.ObjectProperties, error)
+	headMutex       sync.RWMutex
+	headArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
 	}
-	signedUrlGetReturns struct {
-		result1 string
+	headReturns struct {
+		result1 client.ObjectProperties
 		result2 error
 	}
-	signedUrlGetReturnsOnCall map[int]struct {
-		result1 string
+	headReturnsOnCall map[int]struct {
+		result1 client.ObjectProperties
+		result2 error
+	}
+	ListStub        func(context.Context, string, string, int) (client.ListResult, error)
+	listMutex       sync.RWMutex
+	listArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+		arg4 int
+	}
+	listReturns struct {
+		result1 client.ListResult
+		result2 error
+	}
+	listReturnsOnCall map[int]struct {
+		result1 client.ListResult
+		result2 error
+	}
+	ListPageStub        func(context.Context, string, string) (client.ListResult, string, error)
+	listPageMutex       sync.RWMutex
+	listPageArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}
+	listPageReturns struct {
+		result1 client.ListResult
+		result2 string
+		result3 error
+	}
+	listPageReturnsOnCall map[int]struct {
+		result1 client.ListResult
+		result2 string
+		result3 error
+	}
+	OpenStreamStub        func(context.Context, string, client.GetOptions) (io.ReadCloser, error)
+	openStreamMutex       sync.RWMutex
+	openStreamArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 client.GetOptions
+	}
+	openStreamReturns struct {
+		result1 io.ReadCloser
 		result2 error
 	}
-	SignedUrlPutStub        func(string, int64) (string, error)
-	signedUrlPutMutex       sync.RWMutex
-	signedUrlPutArgsForCall []struct {
-		arg1 string
+	openStreamReturnsOnCall map[int]struct {
+		result1 io.ReadCloser
+		result2 error
+	}
+	SignPostPolicyStub        func(context.Context, int64, client.PostPolicyOptions) (client.SignedPostPolicy, error)
+	signPostPolicyMutex       sync.RWMutex
+	signPostPolicyArgsForCall []struct {
+		arg1 context.Context
 		arg2 int64
+		arg3 client.PostPolicyOptions
+	}
+	signPostPolicyReturns struct {
+		result1 client.SignedPostPolicy
+		result2 error
+	}
+	signPostPolicyReturnsOnCall map[int]struct {
+		result1 client.SignedPostPolicy
+		result2 error
+	}
+	SignURLStub        func(context.Context, string, string, int64, client.SignOptions) (client.SignedURL, error)
+	signURLMutex       sync.RWMutex
+	signURLArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+		arg4 int64
+		arg5 client.SignOptions
 	}
-	signedUrlPutReturns struct {
-		result1 string
+	signURLReturns struct {
+		result1 client.SignedURL
 		result2 error
 	}
-	signedUrlPutReturnsOnCall map[int]struct {
-		result1 string
+	signURLReturnsOnCall map[int]struct {
+		result1 client.SignedURL
 		result2 error
 	}
-	UploadStub        func(string, string, string) error
+	UploadStub        func(context.Context, string, string, string) error
 	uploadMutex       sync.RWMutex
 	uploadArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
 		arg3 string
+		arg4 string
 	}
 	uploadReturns struct {
 		result1 error
@@ -85,22 +185,102 @@ type FakeStorageClient struct {
 	uploadReturnsOnCall map[int]struct {
 		result1 error
 	}
+	UploadStreamStub        func(context.Context, io.Reader, int64, string, client.PutOptions) error
+	uploadStreamMutex       sync.RWMutex
+	uploadStreamArgsForCall []struct {
+		arg1 context.Context
+		arg2 io.Reader
+		arg3 int64
+		arg4 string
+		arg5 client.PutOptions
+	}
+	uploadStreamReturns struct {
+		result1 error
+	}
+	uploadStreamReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeStorageClient) Delete(arg1 string) error {
+func (fake *FakeStorageClient) Copy(arg1 context.Context, arg2 string, arg3 string, arg4 string) error {
+	fake.copyMutex.Lock()
+	ret, specificReturn := fake.copyReturnsOnCall[len(fake.copyArgsForCall)]
+	fake.copyArgsForCall = append(fake.copyArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.CopyStub
+	fakeReturns := fake.copyReturns
+	fake.recordInvocation("Copy", []interface{}{arg1, arg2, arg3, arg4})
+	fake.copyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeStorageClient) CopyCallCount() int {
+	fake.copyMutex.RLock()
+	defer fake.copyMutex.RUnlock()
+	return len(fake.copyArgsForCall)
+}
+
+func (fake *FakeStorageClient) CopyCalls(stub func(context.Context, string, string, string) error) {
+	fake.copyMutex.Lock()
+	defer fake.copyMutex.Unlock()
+	fake.CopyStub = stub
+}
+
+func (fake *FakeStorageClient) CopyArgsForCall(i int) (context.Context, string, string, string) {
+	fake.copyMutex.RLock()
+	defer fake.copyMutex.RUnlock()
+	argsForCall := fake.copyArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeStorageClient) CopyReturns(result1 error) {
+	fake.copyMutex.Lock()
+	defer fake.copyMutex.Unlock()
+	fake.CopyStub = nil
+	fake.copyReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorageClient) CopyReturnsOnCall(i int, result1 error) {
+	fake.copyMutex.Lock()
+	defer fake.copyMutex.Unlock()
+	fake.CopyStub = nil
+	if fake.copyReturnsOnCall == nil {
+		fake.copyReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.copyReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorageClient) Delete(arg1 context.Context, arg2 string) error {
 	fake.deleteMutex.Lock()
 	ret, specificReturn := fake.deleteReturnsOnCall[len(fake.deleteArgsForCall)]
 	fake.deleteArgsForCall = append(fake.deleteArgsForCall, struct {
-		arg1 string
-	}{arg1})
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
 	stub := fake.DeleteStub
 	fakeReturns := fake.deleteReturns
-	fake.recordInvocation("Delete", []interface{}{arg1})
+	fake.recordInvocation("Delete", []interface{}{arg1, arg2})
 	fake.deleteMutex.Unlock()
 	if stub != nil {
-		return stub(arg1)
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
@@ -114,17 +294,17 @@ func (fake *FakeStorageClient) DeleteCallCount() int {
 	return len(fake.deleteArgsForCall)
 }
 
-func (fake *FakeStorageClient) DeleteCalls(stub func(string) error) {
+func (fake *FakeStorageClient) DeleteCalls(stub func(context.Context, string) error) {
 	fake.deleteMutex.Lock()
 	defer fake.deleteMutex.Unlock()
 	fake.DeleteStub = stub
 }
 
-func (fake *FakeStorageClient) DeleteArgsForCall(i int) string {
+func (fake *FakeStorageClient) DeleteArgsForCall(i int) (context.Context, string) {
 	fake.deleteMutex.RLock()
 	defer fake.deleteMutex.RUnlock()
 	argsForCall := fake.deleteArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeStorageClient) DeleteReturns(result1 error) {
@@ -150,19 +330,90 @@ func (fake *FakeStorageClient) DeleteReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeStorageClient) Download(arg1 string, arg2 string) error {
+func (fake *FakeStorageClient) DeleteObjects(arg1 context.Context, arg2 []string) (client.DeleteResult, error) {
+	var arg2Copy []string
+	if arg2 != nil {
+		arg2Copy = make([]string, len(arg2))
+		copy(arg2Copy, arg2)
+	}
+	fake.deleteObjectsMutex.Lock()
+	ret, specificReturn := fake.deleteObjectsReturnsOnCall[len(fake.deleteObjectsArgsForCall)]
+	fake.deleteObjectsArgsForCall = append(fake.deleteObjectsArgsForCall, struct {
+		arg1 context.Context
+		arg2 []string
+	}{arg1, arg2Copy})
+	stub := fake.DeleteObjectsStub
+	fakeReturns := fake.deleteObjectsReturns
+	fake.recordInvocation("DeleteObjects", []interface{}{arg1, arg2Copy})
+	fake.deleteObjectsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeStorageClient) DeleteObjectsCallCount() int {
+	fake.deleteObjectsMutex.RLock()
+	defer fake.deleteObjectsMutex.RUnlock()
+	return len(fake.deleteObjectsArgsForCall)
+}
+
+func (fake *FakeStorageClient) DeleteObjectsCalls(stub func(context.Context, []string) (client.DeleteResult, error)) {
+	fake.deleteObjectsMutex.Lock()
+	defer fake.deleteObjectsMutex.Unlock()
+	fake.DeleteObjectsStub = stub
+}
+
+func (fake *FakeStorageClient) DeleteObjectsArgsForCall(i int) (context.Context, []string) {
+	fake.deleteObjectsMutex.RLock()
+	defer fake.deleteObjectsMutex.RUnlock()
+	argsForCall := fake.deleteObjectsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeStorageClient) DeleteObjectsReturns(result1 client.DeleteResult, result2 error) {
+	fake.deleteObjectsMutex.Lock()
+	defer fake.deleteObjectsMutex.Unlock()
+	fake.DeleteObjectsStub = nil
+	fake.deleteObjectsReturns = struct {
+		result1 client.DeleteResult
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorageClient) DeleteObjectsReturnsOnCall(i int, result1 client.DeleteResult, result2 error) {
+	fake.deleteObjectsMutex.Lock()
+	defer fake.deleteObjectsMutex.Unlock()
+	fake.DeleteObjectsStub = nil
+	if fake.deleteObjectsReturnsOnCall == nil {
+		fake.deleteObjectsReturnsOnCall = make(map[int]struct {
+			result1 client.DeleteResult
+			result2 error
+		})
+	}
+	fake.deleteObjectsReturnsOnCall[i] = struct {
+		result1 client.DeleteResult
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorageClient) Download(arg1 context.Context, arg2 string, arg3 string) error {
 	fake.downloadMutex.Lock()
 	ret, specificReturn := fake.downloadReturnsOnCall[len(fake.downloadArgsForCall)]
 	fake.downloadArgsForCall = append(fake.downloadArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-	}{arg1, arg2})
+		arg3 string
+	}{arg1, arg2, arg3})
 	stub := fake.DownloadStub
 	fakeReturns := fake.downloadReturns
-	fake.recordInvocation("Download", []interface{}{arg1, arg2})
+	fake.recordInvocation("Download", []interface{}{arg1, arg2, arg3})
 	fake.downloadMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
@@ -176,17 +427,17 @@ func (fake *FakeStorageClient) DownloadCallCount() int {
 	return len(fake.downloadArgsForCall)
 }
 
-func (fake *FakeStorageClient) DownloadCalls(stub func(string, string) error) {
+func (fake *FakeStorageClient) DownloadCalls(stub func(context.Context, string, string) error) {
 	fake.downloadMutex.Lock()
 	defer fake.downloadMutex.Unlock()
 	fake.DownloadStub = stub
 }
 
-func (fake *FakeStorageClient) DownloadArgsForCall(i int) (string, string) {
+func (fake *FakeStorageClient) DownloadArgsForCall(i int) (context.Context, string, string) {
 	fake.downloadMutex.RLock()
 	defer fake.downloadMutex.RUnlock()
 	argsForCall := fake.downloadArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeStorageClient) DownloadReturns(result1 error) {
@@ -212,18 +463,19 @@ func (fake *FakeStorageClient) DownloadReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeStorageClient) Exists(arg1 string) (bool, error) {
+func (fake *FakeStorageClient) Exists(arg1 context.Context, arg2 string) (bool, error) {
 	fake.existsMutex.Lock()
 	ret, specificReturn := fake.existsReturnsOnCall[len(fake.existsArgsForCall)]
 	fake.existsArgsForCall = append(fake.existsArgsForCall, struct {
-		arg1 string
-	}{arg1})
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
 	stub := fake.ExistsStub
 	fakeReturns := fake.existsReturns
-	fake.recordInvocation("Exists", []interface{}{arg1})
+	fake.recordInvocation("Exists", []interface{}{arg1, arg2})
 	fake.existsMutex.Unlock()
 	if stub != nil {
-		return stub(arg1)
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -237,17 +489,17 @@ func (fake *FakeStorageClient) ExistsCallCount() int {
 	return len(fake.existsArgsForCall)
 }
 
-func (fake *FakeStorageClient) ExistsCalls(stub func(string) (bool, error)) {
+func (fake *FakeStorageClient) ExistsCalls(stub func(context.Context, string) (bool, error)) {
 	fake.existsMutex.Lock()
 	defer fake.existsMutex.Unlock()
 	fake.ExistsStub = stub
 }
 
-func (fake *FakeStorageClient) ExistsArgsForCall(i int) string {
+func (fake *FakeStorageClient) ExistsArgsForCall(i int) (context.Context, string) {
 	fake.existsMutex.RLock()
 	defer fake.existsMutex.RUnlock()
 	argsForCall := fake.existsArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeStorageClient) ExistsReturns(result1 bool, result2 error) {
@@ -276,17 +528,17 @@ func (fake *FakeStorageClient) ExistsReturnsOnCall(i int, result1 bool, result2
 	}{result1, result2}
 }
 
-func (fake *FakeStorageClient) SignedUrlGet(arg1 string, arg2 int64) (string, error) {
-	fake.signedUrlGetMutex.Lock()
-	ret, specificReturn := fake.signedUrlGetReturnsOnCall[len(fake.signedUrlGetArgsForCall)]
-	fake.signedUrlGetArgsForCall = append(fake.signedUrlGetArgsForCall, struct {
-		arg1 string
-		arg2 int64
+func (fake *FakeStorageClient) Head(arg1 context.Context, arg2 string) (client.ObjectProperties, error) {
+	fake.headMutex.Lock()
+	ret, specificReturn := fake.headReturnsOnCall[len(fake.headArgsForCall)]
+	fake.headArgsForCall = append(fake.headArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
 	}{arg1, arg2})
-	stub := fake.SignedUrlGetStub
-	fakeReturns := fake.signedUrlGetReturns
-	fake.recordInvocation("SignedUrlGet", []interface{}{arg1, arg2})
-	fake.signedUrlGetMutex.Unlock()
+	stub := fake.HeadStub
+	fakeReturns := fake.headReturns
+	fake.recordInvocation("Head", []interface{}{arg1, arg2})
+	fake.headMutex.Unlock()
 	if stub != nil {
 		return stub(arg1, arg2)
 	}
@@ -296,64 +548,267 @@ func (fake *FakeStorageClient) SignedUrlGet(arg1 string, arg2 int64) (string, er
 	return fakeReturns.result1, fakeReturns.result2
 }
 
-func (fake *FakeStorageClient) SignedUrlGetCallCount() int {
-	fake.signedUrlGetMutex.RLock()
-	defer fake.signedUrlGetMutex.RUnlock()
-	return len(fake.signedUrlGetArgsForCall)
+func (fake *FakeStorageClient) HeadCallCount() int {
+	fake.headMutex.RLock()
+	defer fake.headMutex.RUnlock()
+	return len(fake.headArgsForCall)
 }
 
-func (fake *FakeStorageClient) SignedUrlGetCalls(stub func(string, int64) (string, error)) {
-	fake.signedUrlGetMutex.Lock()
-	defer fake.signedUrlGetMutex.Unlock()
-	fake.SignedUrlGetStub = stub
+func (fake *FakeStorageClient) HeadCalls(stub func(context.Context, string) (client.ObjectProperties, error)) {
+	fake.headMutex.Lock()
+	defer fake.headMutex.Unlock()
+	fake.HeadStub = stub
 }
 
-func (fake *FakeStorageClient) SignedUrlGetArgsForCall(i int) (string, int64) {
-	fake.signedUrlGetMutex.RLock()
-	defer fake.signedUrlGetMutex.RUnlock()
-	argsForCall := fake.signedUrlGetArgsForCall[i]
+func (fake *FakeStorageClient) HeadArgsForCall(i int) (context.Context, string) {
+	fake.headMutex.RLock()
+	defer fake.headMutex.RUnlock()
+	argsForCall := fake.headArgsForCall[i]
 	return argsForCall.arg1, argsForCall.arg2
 }
 
-func (fake *FakeStorageClient) SignedUrlGetReturns(result1 string, result2 error) {
-	fake.signedUrlGetMutex.Lock()
-	defer fake.signedUrlGetMutex.Unlock()
-	fake.SignedUrlGetStub = nil
-	fake.signedUrlGetReturns = struct {
-		result1 string
+func (fake *FakeStorageClient) HeadReturns(result1 client.ObjectProperties, result2 error) {
+	fake.headMutex.Lock()
+	defer fake.headMutex.Unlock()
+	fake.HeadStub = nil
+	fake.headReturns = struct {
+		result1 client.ObjectProperties
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeStorageClient) SignedUrlGetReturnsOnCall(i int, result1 string, result2 error) {
-	fake.signedUrlGetMutex.Lock()
-	defer fake.signedUrlGetMutex.Unlock()
-	fake.SignedUrlGetStub = nil
-	if fake.signedUrlGetReturnsOnCall == nil {
-		fake.signedUrlGetReturnsOnCall = make(map[int]struct {
-			result1 string
+func (fake *FakeStorageClient) HeadReturnsOnCall(i int, result1 client.ObjectProperties, result2 error) {
+	fake.headMutex.Lock()
+	defer fake.headMutex.Unlock()
+	fake.HeadStub = nil
+	if fake.headReturnsOnCall == nil {
+		fake.headReturnsOnCall = make(map[int]struct {
+			result1 client.ObjectProperties
 			result2 error
 		})
 	}
-	fake.signedUrlGetReturnsOnCall[i] = struct {
-		result1 string
+	fake.headReturnsOnCall[i] = struct {
+		result1 client.ObjectProperties
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeStorageClient) SignedUrlPut(arg1 string, arg2 int64) (string, error) {
-	fake.signedUrlPutMutex.Lock()
-	ret, specificReturn := fake.signedUrlPutReturnsOnCall[len(fake.signedUrlPutArgsForCall)]
-	fake.signedUrlPutArgsForCall = append(fake.signedUrlPutArgsForCall, struct {
-		arg1 string
+func (fake *FakeStorageClient) List(arg1 context.Context, arg2 string, arg3 string, arg4 int) (client.ListResult, error) {
+	fake.listMutex.Lock()
+	ret, specificReturn := fake.listReturnsOnCall[len(fake.listArgsForCall)]
+	fake.listArgsForCall = append(fake.listArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+		arg4 int
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.ListStub
+	fakeReturns := fake.listReturns
+	fake.recordInvocation("List", []interface{}{arg1, arg2, arg3, arg4})
+	fake.listMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeStorageClient) ListCallCount() int {
+	fake.listMutex.RLock()
+	defer fake.listMutex.RUnlock()
+	return len(fake.listArgsForCall)
+}
+
+func (fake *FakeStorageClient) ListCalls(stub func(context.Context, string, string, int) (client.ListResult, error)) {
+	fake.listMutex.Lock()
+	defer fake.listMutex.Unlock()
+	fake.ListStub = stub
+}
+
+func (fake *FakeStorageClient) ListArgsForCall(i int) (context.Context, string, string, int) {
+	fake.listMutex.RLock()
+	defer fake.listMutex.RUnlock()
+	argsForCall := fake.listArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeStorageClient) ListReturns(result1 client.ListResult, result2 error) {
+	fake.listMutex.Lock()
+	defer fake.listMutex.Unlock()
+	fake.ListStub = nil
+	fake.listReturns = struct {
+		result1 client.ListResult
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorageClient) ListReturnsOnCall(i int, result1 client.ListResult, result2 error) {
+	fake.listMutex.Lock()
+	defer fake.listMutex.Unlock()
+	fake.ListStub = nil
+	if fake.listReturnsOnCall == nil {
+		fake.listReturnsOnCall = make(map[int]struct {
+			result1 client.ListResult
+			result2 error
+		})
+	}
+	fake.listReturnsOnCall[i] = struct {
+		result1 client.ListResult
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorageClient) ListPage(arg1 context.Context, arg2 string, arg3 string) (client.ListResult, string, error) {
+	fake.listPageMutex.Lock()
+	ret, specificReturn := fake.listPageReturnsOnCall[len(fake.listPageArgsForCall)]
+	fake.listPageArgsForCall = append(fake.listPageArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.ListPageStub
+	fakeReturns := fake.listPageReturns
+	fake.recordInvocation("ListPage", []interface{}{arg1, arg2, arg3})
+	fake.listPageMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2, ret.result3
+	}
+	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
+}
+
+func (fake *FakeStorageClient) ListPageCallCount() int {
+	fake.listPageMutex.RLock()
+	defer fake.listPageMutex.RUnlock()
+	return len(fake.listPageArgsForCall)
+}
+
+func (fake *FakeStorageClient) ListPageCalls(stub func(context.Context, string, string) (client.ListResult, string, error)) {
+	fake.listPageMutex.Lock()
+	defer fake.listPageMutex.Unlock()
+	fake.ListPageStub = stub
+}
+
+func (fake *FakeStorageClient) ListPageArgsForCall(i int) (context.Context, string, string) {
+	fake.listPageMutex.RLock()
+	defer fake.listPageMutex.RUnlock()
+	argsForCall := fake.listPageArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeStorageClient) ListPageReturns(result1 client.ListResult, result2 string, result3 error) {
+	fake.listPageMutex.Lock()
+	defer fake.listPageMutex.Unlock()
+	fake.ListPageStub = nil
+	fake.listPageReturns = struct {
+		result1 client.ListResult
+		result2 string
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeStorageClient) ListPageReturnsOnCall(i int, result1 client.ListResult, result2 string, result3 error) {
+	fake.listPageMutex.Lock()
+	defer fake.listPageMutex.Unlock()
+	fake.ListPageStub = nil
+	if fake.listPageReturnsOnCall == nil {
+		fake.listPageReturnsOnCall = make(map[int]struct {
+			result1 client.ListResult
+			result2 string
+			result3 error
+		})
+	}
+	fake.listPageReturnsOnCall[i] = struct {
+		result1 client.ListResult
+		result2 string
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeStorageClient) OpenStream(arg1 context.Context, arg2 string, arg3 client.GetOptions) (io.ReadCloser, error) {
+	fake.openStreamMutex.Lock()
+	ret, specificReturn := fake.openStreamReturnsOnCall[len(fake.openStreamArgsForCall)]
+	fake.openStreamArgsForCall = append(fake.openStreamArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 client.GetOptions
+	}{arg1, arg2, arg3})
+	stub := fake.OpenStreamStub
+	fakeReturns := fake.openStreamReturns
+	fake.recordInvocation("OpenStream", []interface{}{arg1, arg2, arg3})
+	fake.openStreamMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeStorageClient) OpenStreamCallCount() int {
+	fake.openStreamMutex.RLock()
+	defer fake.openStreamMutex.RUnlock()
+	return len(fake.openStreamArgsForCall)
+}
+
+func (fake *FakeStorageClient) OpenStreamCalls(stub func(context.Context, string, client.GetOptions) (io.ReadCloser, error)) {
+	fake.openStreamMutex.Lock()
+	defer fake.openStreamMutex.Unlock()
+	fake.OpenStreamStub = stub
+}
+
+func (fake *FakeStorageClient) OpenStreamArgsForCall(i int) (context.Context, string, client.GetOptions) {
+	fake.openStreamMutex.RLock()
+	defer fake.openStreamMutex.RUnlock()
+	argsForCall := fake.openStreamArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeStorageClient) OpenStreamReturns(result1 io.ReadCloser, result2 error) {
+	fake.openStreamMutex.Lock()
+	defer fake.openStreamMutex.Unlock()
+	fake.OpenStreamStub = nil
+	fake.openStreamReturns = struct {
+		result1 io.ReadCloser
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorageClient) OpenStreamReturnsOnCall(i int, result1 io.ReadCloser, result2 error) {
+	fake.openStreamMutex.Lock()
+	defer fake.openStreamMutex.Unlock()
+	fake.OpenStreamStub = nil
+	if fake.openStreamReturnsOnCall == nil {
+		fake.openStreamReturnsOnCall = make(map[int]struct {
+			result1 io.ReadCloser
+			result2 error
+		})
+	}
+	fake.openStreamReturnsOnCall[i] = struct {
+		result1 io.ReadCloser
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorageClient) SignPostPolicy(arg1 context.Context, arg2 int64, arg3 client.PostPolicyOptions) (client.SignedPostPolicy, error) {
+	fake.signPostPolicyMutex.Lock()
+	ret, specificReturn := fake.signPostPolicyReturnsOnCall[len(fake.signPostPolicyArgsForCall)]
+	fake.signPostPolicyArgsForCall = append(fake.signPostPolicyArgsForCall, struct {
+		arg1 context.Context
 		arg2 int64
-	}{arg1, arg2})
-	stub := fake.SignedUrlPutStub
-	fakeReturns := fake.signedUrlPutReturns
-	fake.recordInvocation("SignedUrlPut", []interface{}{arg1, arg2})
-	fake.signedUrlPutMutex.Unlock()
+		arg3 client.PostPolicyOptions
+	}{arg1, arg2, arg3})
+	stub := fake.SignPostPolicyStub
+	fakeReturns := fake.signPostPolicyReturns
+	fake.recordInvocation("SignPostPolicy", []interface{}{arg1, arg2, arg3})
+	fake.signPostPolicyMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -361,65 +816,134 @@ func (fake *FakeStorageClient) SignedUrlPut(arg1 string, arg2 int64) (string, er
 	return fakeReturns.result1, fakeReturns.result2
 }
 
-func (fake *FakeStorageClient) SignedUrlPutCallCount() int {
-	fake.signedUrlPutMutex.RLock()
-	defer fake.signedUrlPutMutex.RUnlock()
-	return len(fake.signedUrlPutArgsForCall)
+func (fake *FakeStorageClient) SignPostPolicyCallCount() int {
+	fake.signPostPolicyMutex.RLock()
+	defer fake.signPostPolicyMutex.RUnlock()
+	return len(fake.signPostPolicyArgsForCall)
 }
 
-func (fake *FakeStorageClient) SignedUrlPutCalls(stub func(string, int64) (string, error)) {
-	fake.signedUrlPutMutex.Lock()
-	defer fake.signedUrlPutMutex.Unlock()
-	fake.SignedUrlPutStub = stub
+func (fake *FakeStorageClient) SignPostPolicyCalls(stub func(context.Context, int64, client.PostPolicyOptions) (client.SignedPostPolicy, error)) {
+	fake.signPostPolicyMutex.Lock()
+	defer fake.signPostPolicyMutex.Unlock()
+	fake.SignPostPolicyStub = stub
 }
 
-func (fake *FakeStorageClient) SignedUrlPutArgsForCall(i int) (string, int64) {
-	fake.signedUrlPutMutex.RLock()
-	defer fake.signedUrlPutMutex.RUnlock()
-	argsForCall := fake.signedUrlPutArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+func (fake *FakeStorageClient) SignPostPolicyArgsForCall(i int) (context.Context, int64, client.PostPolicyOptions) {
+	fake.signPostPolicyMutex.RLock()
+	defer fake.signPostPolicyMutex.RUnlock()
+	argsForCall := fake.signPostPolicyArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeStorageClient) SignPostPolicyReturns(result1 client.SignedPostPolicy, result2 error) {
+	fake.signPostPolicyMutex.Lock()
+	defer fake.signPostPolicyMutex.Unlock()
+	fake.SignPostPolicyStub = nil
+	fake.signPostPolicyReturns = struct {
+		result1 client.SignedPostPolicy
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorageClient) SignPostPolicyReturnsOnCall(i int, result1 client.SignedPostPolicy, result2 error) {
+	fake.signPostPolicyMutex.Lock()
+	defer fake.signPostPolicyMutex.Unlock()
+	fake.SignPostPolicyStub = nil
+	if fake.signPostPolicyReturnsOnCall == nil {
+		fake.signPostPolicyReturnsOnCall = make(map[int]struct {
+			result1 client.SignedPostPolicy
+			result2 error
+		})
+	}
+	fake.signPostPolicyReturnsOnCall[i] = struct {
+		result1 client.SignedPostPolicy
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStorageClient) SignURL(arg1 context.Context, arg2 string, arg3 string, arg4 int64, arg5 client.SignOptions) (client.SignedURL, error) {
+	fake.signURLMutex.Lock()
+	ret, specificReturn := fake.signURLReturnsOnCall[len(fake.signURLArgsForCall)]
+	fake.signURLArgsForCall = append(fake.signURLArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+		arg4 int64
+		arg5 client.SignOptions
+	}{arg1, arg2, arg3, arg4, arg5})
+	stub := fake.SignURLStub
+	fakeReturns := fake.signURLReturns
+	fake.recordInvocation("SignURL", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.signURLMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeStorageClient) SignURLCallCount() int {
+	fake.signURLMutex.RLock()
+	defer fake.signURLMutex.RUnlock()
+	return len(fake.signURLArgsForCall)
 }
 
-func (fake *FakeStorageClient) SignedUrlPutReturns(result1 string, result2 error) {
-	fake.signedUrlPutMutex.Lock()
-	defer fake.signedUrlPutMutex.Unlock()
-	fake.SignedUrlPutStub = nil
-	fake.signedUrlPutReturns = struct {
-		result1 string
+func (fake *FakeStorageClient) SignURLCalls(stub func(context.Context, string, string, int64, client.SignOptions) (client.SignedURL, error)) {
+	fake.signURLMutex.Lock()
+	defer fake.signURLMutex.Unlock()
+	fake.SignURLStub = stub
+}
+
+func (fake *FakeStorageClient) SignURLArgsForCall(i int) (context.Context, string, string, int64, client.SignOptions) {
+	fake.signURLMutex.RLock()
+	defer fake.signURLMutex.RUnlock()
+	argsForCall := fake.signURLArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeStorageClient) SignURLReturns(result1 client.SignedURL, result2 error) {
+	fake.signURLMutex.Lock()
+	defer fake.signURLMutex.Unlock()
+	fake.SignURLStub = nil
+	fake.signURLReturns = struct {
+		result1 client.SignedURL
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeStorageClient) SignedUrlPutReturnsOnCall(i int, result1 string, result2 error) {
-	fake.signedUrlPutMutex.Lock()
-	defer fake.signedUrlPutMutex.Unlock()
-	fake.SignedUrlPutStub = nil
-	if fake.signedUrlPutReturnsOnCall == nil {
-		fake.signedUrlPutReturnsOnCall = make(map[int]struct {
-			result1 string
+func (fake *FakeStorageClient) SignURLReturnsOnCall(i int, result1 client.SignedURL, result2 error) {
+	fake.signURLMutex.Lock()
+	defer fake.signURLMutex.Unlock()
+	fake.SignURLStub = nil
+	if fake.signURLReturnsOnCall == nil {
+		fake.signURLReturnsOnCall = make(map[int]struct {
+			result1 client.SignedURL
 			result2 error
 		})
 	}
-	fake.signedUrlPutReturnsOnCall[i] = struct {
-		result1 string
+	fake.signURLReturnsOnCall[i] = struct {
+		result1 client.SignedURL
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeStorageClient) Upload(arg1 string, arg2 string, arg3 string) error {
+func (fake *FakeStorageClient) Upload(arg1 context.Context, arg2 string, arg3 string, arg4 string) error {
 	fake.uploadMutex.Lock()
 	ret, specificReturn := fake.uploadReturnsOnCall[len(fake.uploadArgsForCall)]
 	fake.uploadArgsForCall = append(fake.uploadArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
 		arg3 string
-	}{arg1, arg2, arg3})
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
 	stub := fake.UploadStub
 	fakeReturns := fake.uploadReturns
-	fake.recordInvocation("Upload", []interface{}{arg1, arg2, arg3})
+	fake.recordInvocation("Upload", []interface{}{arg1, arg2, arg3, arg4})
 	fake.uploadMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3)
+		return stub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1
@@ -433,17 +957,17 @@ func (fake *FakeStorageClient) UploadCallCount() int {
 	return len(fake.uploadArgsForCall)
 }
 
-func (fake *FakeStorageClient) UploadCalls(stub func(string, string, string) error) {
+func (fake *FakeStorageClient) UploadCalls(stub func(context.Context, string, string, string) error) {
 	fake.uploadMutex.Lock()
 	defer fake.uploadMutex.Unlock()
 	fake.UploadStub = stub
 }
 
-func (fake *FakeStorageClient) UploadArgsForCall(i int) (string, string, string) {
+func (fake *FakeStorageClient) UploadArgsForCall(i int) (context.Context, string, string, string) {
 	fake.uploadMutex.RLock()
 	defer fake.uploadMutex.RUnlock()
 	argsForCall := fake.uploadArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeStorageClient) UploadReturns(result1 error) {
@@ -469,21 +993,100 @@ func (fake *FakeStorageClient) UploadReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeStorageClient) UploadStream(arg1 context.Context, arg2 io.Reader, arg3 int64, arg4 string, arg5 client.PutOptions) error {
+	fake.uploadStreamMutex.Lock()
+	ret, specificReturn := fake.uploadStreamReturnsOnCall[len(fake.uploadStreamArgsForCall)]
+	fake.uploadStreamArgsForCall = append(fake.uploadStreamArgsForCall, struct {
+		arg1 context.Context
+		arg2 io.Reader
+		arg3 int64
+		arg4 string
+		arg5 client.PutOptions
+	}{arg1, arg2, arg3, arg4, arg5})
+	stub := fake.UploadStreamStub
+	fakeReturns := fake.uploadStreamReturns
+	fake.recordInvocation("UploadStream", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.uploadStreamMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeStorageClient) UploadStreamCallCount() int {
+	fake.uploadStreamMutex.RLock()
+	defer fake.uploadStreamMutex.RUnlock()
+	return len(fake.uploadStreamArgsForCall)
+}
+
+func (fake *FakeStorageClient) UploadStreamCalls(stub func(context.Context, io.Reader, int64, string, client.PutOptions) error) {
+	fake.uploadStreamMutex.Lock()
+	defer fake.uploadStreamMutex.Unlock()
+	fake.UploadStreamStub = stub
+}
+
+func (fake *FakeStorageClient) UploadStreamArgsForCall(i int) (context.Context, io.Reader, int64, string, client.PutOptions) {
+	fake.uploadStreamMutex.RLock()
+	defer fake.uploadStreamMutex.RUnlock()
+	argsForCall := fake.uploadStreamArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeStorageClient) UploadStreamReturns(result1 error) {
+	fake.uploadStreamMutex.Lock()
+	defer fake.uploadStreamMutex.Unlock()
+	fake.UploadStreamStub = nil
+	fake.uploadStreamReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeStorageClient) UploadStreamReturnsOnCall(i int, result1 error) {
+	fake.uploadStreamMutex.Lock()
+	defer fake.uploadStreamMutex.Unlock()
+	fake.UploadStreamStub = nil
+	if fake.uploadStreamReturnsOnCall == nil {
+		fake.uploadStreamReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.uploadStreamReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeStorageClient) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.copyMutex.RLock()
+	defer fake.copyMutex.RUnlock()
 	fake.deleteMutex.RLock()
 	defer fake.deleteMutex.RUnlock()
+	fake.deleteObjectsMutex.RLock()
+	defer fake.deleteObjectsMutex.RUnlock()
 	fake.downloadMutex.RLock()
 	defer fake.downloadMutex.RUnlock()
 	fake.existsMutex.RLock()
 	defer fake.existsMutex.RUnlock()
-	fake.signedUrlGetMutex.RLock()
-	defer fake.signedUrlGetMutex.RUnlock()
-	fake.signedUrlPutMutex.RLock()
-	defer fake.signedUrlPutMutex.RUnlock()
+	fake.headMutex.RLock()
+	defer fake.headMutex.RUnlock()
+	fake.listMutex.RLock()
+	defer fake.listMutex.RUnlock()
+	fake.listPageMutex.RLock()
+	defer fake.listPageMutex.RUnlock()
+	fake.openStreamMutex.RLock()
+	defer fake.openStreamMutex.RUnlock()
+	fake.signPostPolicyMutex.RLock()
+	defer fake.signPostPolicyMutex.RUnlock()
+	fake.signURLMutex.RLock()
+	defer fake.signURLMutex.RUnlock()
 	fake.uploadMutex.RLock()
 	defer fake.uploadMutex.RUnlock()
+	fake.uploadStreamMutex.RLock()
+	defer fake.uploadStreamMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
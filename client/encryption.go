@@ -0,0 +1,365 @@
+package client
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// The object metadata of client-side encrypted objects borrows the names of the OSS SDK encryption client, but not
+// its format: the material description holds the fingerprint of the master key and AES master keys wrap with AES-GCM,
+// so objects are not interchangeable with the SDK encryption client
+const (
+	metaEncryptionKey                   = "client-side-encryption-key"
+	metaEncryptionStart                 = "client-side-encryption-start"
+	metaEncryptionCekAlg                = "client-side-encryption-cek-alg"
+	metaEncryptionWrapAlg               = "client-side-encryption-wrap-alg"
+	metaEncryptionMatDesc               = "client-side-encryption-matdesc"
+	metaEncryptionUnencryptedContentLen = "client-side-encryption-unencrypted-content-length"
+	metaEncryptionUnencryptedContentMD5 = "client-side-encryption-unencrypted-content-md5"
+
+	contentEncryptionAlgorithm = "AES/CTR/NoPadding"
+	rsaWrapAlgorithm           = "RSA/NONE/PKCS1Padding"
+	aesWrapAlgorithm           = "AES/GCM/NoPadding"
+)
+
+// ErrEncryptionKeyRequired is returned when downloading a client-side encrypted object without a master key.
+var ErrEncryptionKeyRequired = errors.New("blob is encrypted client-side, but no client_side_encryption_key_file is configured")
+
+// MasterKey wraps and unwraps the data keys with which the content of client-side encrypted objects is encrypted.
+type MasterKey interface {
+	Wrap(plaintext []byte) ([]byte, error)
+	Unwrap(ciphertext []byte) ([]byte, error)
+	// WrapAlgorithm names the algorithm used to wrap data keys
+	WrapAlgorithm() string
+	// Fingerprint identifies the master key without revealing it
+	Fingerprint() string
+}
+
+// LoadMasterKey reads an RSA key in PEM format or a base64 encoded 256 bit AES key from keyFilePath.
+// A file containing only an RSA public key can be used to encrypt, but not to decrypt objects.
+func LoadMasterKey(keyFilePath string) (MasterKey, error) {
+	contents, err := os.ReadFile(keyFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("reading master key: %w", err)
+	}
+
+	if block, _ := pem.Decode(contents); block != nil {
+		return parseRSAMasterKey(block)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(contents)))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("master key file %s contains neither a PEM encoded RSA key nor a base64 encoded 256 bit key", keyFilePath)
+	}
+
+	return aesMasterKey{key: key}, nil
+}
+
+func parseRSAMasterKey(block *pem.Block) (MasterKey, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing RSA master key: %w", err)
+		}
+		return rsaMasterKey{publicKey: &privateKey.PublicKey, privateKey: privateKey}, nil
+
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing RSA master key: %w", err)
+		}
+		privateKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("master key is not an RSA key")
+		}
+		return rsaMasterKey{publicKey: &privateKey.PublicKey, privateKey: privateKey}, nil
+
+	case "RSA PUBLIC KEY":
+		publicKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing RSA master key: %w", err)
+		}
+		return rsaMasterKey{publicKey: publicKey}, nil
+
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing RSA master key: %w", err)
+		}
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("master key is not an RSA key")
+		}
+		return rsaMasterKey{publicKey: publicKey}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported PEM block '%s' in master key file", block.Type)
+	}
+}
+
+type rsaMasterKey struct {
+	publicKey  *rsa.PublicKey
+	privateKey *rsa.PrivateKey
+}
+
+func (k rsaMasterKey) Wrap(plaintext []byte) ([]byte, error) {
+	return rsa.EncryptPKCS1v15(rand.Reader, k.publicKey, plaintext)
+}
+
+func (k rsaMasterKey) Unwrap(ciphertext []byte) ([]byte, error) {
+	if k.privateKey == nil {
+		return nil, errors.New("the master key file contains only a public key, which cannot decrypt blobs")
+	}
+	return rsa.DecryptPKCS1v15(rand.Reader, k.privateKey, ciphertext)
+}
+
+func (k rsaMasterKey) WrapAlgorithm() string {
+	return rsaWrapAlgorithm
+}
+
+func (k rsaMasterKey) Fingerprint() string {
+	publicKey, _ := x509.MarshalPKIXPublicKey(k.publicKey)
+	return fingerprint(publicKey)
+}
+
+type aesMasterKey struct {
+	key []byte
+}
+
+func (k aesMasterKey) Wrap(plaintext []byte) ([]byte, error) {
+	gcm, err := k.gcm()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (k aesMasterKey) Unwrap(ciphertext []byte) ([]byte, error) {
+	gcm, err := k.gcm()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("wrapped key is too short")
+	}
+
+	return gcm.Open(nil, ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():], nil)
+}
+
+func (k aesMasterKey) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (k aesMasterKey) WrapAlgorithm() string {
+	return aesWrapAlgorithm
+}
+
+func (k aesMasterKey) Fingerprint() string {
+	return fingerprint(k.key)
+}
+
+func fingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:16])
+}
+
+type materialDescription struct {
+	Fingerprint string `json:"fingerprint"`
+}
+
+// envelope holds the random data key and IV with which the content of a single object is encrypted
+type envelope struct {
+	dataKey []byte
+	iv      []byte
+}
+
+func newEnvelope() (envelope, error) {
+	e := envelope{dataKey: make([]byte, 32), iv: make([]byte, aes.BlockSize)}
+
+	_, err := rand.Read(e.dataKey)
+	if err != nil {
+		return envelope{}, err
+	}
+
+	_, err = rand.Read(e.iv)
+	if err != nil {
+		return envelope{}, err
+	}
+
+	return e, nil
+}
+
+// metadataOptions returns the object metadata storing the envelope, wrapped with masterKey,
+// and the properties of the unencrypted content. An empty plaintextMD5 or a negative plaintextSize
+// are left out, as they are unknown for streamed multipart uploads.
+func (e envelope) metadataOptions(masterKey MasterKey, plaintextMD5 string, plaintextSize int64) ([]oss.Option, error) {
+	wrappedKey, err := masterKey.Wrap(e.dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("wrapping data key: %w", err)
+	}
+
+	wrappedIV, err := masterKey.Wrap(e.iv)
+	if err != nil {
+		return nil, fmt.Errorf("wrapping data key: %w", err)
+	}
+
+	matDesc, err := json.Marshal(materialDescription{Fingerprint: masterKey.Fingerprint()})
+	if err != nil {
+		return nil, err
+	}
+
+	options := []oss.Option{
+		oss.Meta(metaEncryptionKey, base64.StdEncoding.EncodeToString(wrappedKey)),
+		oss.Meta(metaEncryptionStart, base64.StdEncoding.EncodeToString(wrappedIV)),
+		oss.Meta(metaEncryptionCekAlg, contentEncryptionAlgorithm),
+		oss.Meta(metaEncryptionWrapAlg, masterKey.WrapAlgorithm()),
+		oss.Meta(metaEncryptionMatDesc, string(matDesc)),
+	}
+	if plaintextSize >= 0 {
+		options = append(options, oss.Meta(metaEncryptionUnencryptedContentLen, strconv.FormatInt(plaintextSize, 10)))
+	}
+	if plaintextMD5 != "" {
+		options = append(options, oss.Meta(metaEncryptionUnencryptedContentMD5, plaintextMD5))
+	}
+
+	return options, nil
+}
+
+// isClientSideEncrypted reports whether the object headers contain an envelope.
+func isClientSideEncrypted(objectHeader http.Header) bool {
+	return objectHeader.Get(oss.HTTPHeaderOssMetaPrefix+metaEncryptionKey) != ""
+}
+
+// openEnvelope unwraps the envelope stored in the object headers with masterKey.
+func openEnvelope(objectHeader http.Header, masterKey MasterKey) (envelope, error) {
+	meta := func(name string) string {
+		return objectHeader.Get(oss.HTTPHeaderOssMetaPrefix + name)
+	}
+
+	if masterKey == nil {
+		return envelope{}, ErrEncryptionKeyRequired
+	}
+
+	if algorithm := meta(metaEncryptionCekAlg); algorithm != contentEncryptionAlgorithm {
+		return envelope{}, fmt.Errorf("unsupported content encryption algorithm '%s'", algorithm)
+	}
+
+	if algorithm := meta(metaEncryptionWrapAlg); algorithm != masterKey.WrapAlgorithm() {
+		return envelope{}, fmt.Errorf("blob data key is wrapped with '%s', but the master key uses '%s'", algorithm, masterKey.WrapAlgorithm())
+	}
+
+	matDesc := materialDescription{}
+	if json.Unmarshal([]byte(meta(metaEncryptionMatDesc)), &matDesc) == nil && matDesc.Fingerprint != "" &&
+		matDesc.Fingerprint != masterKey.Fingerprint() {
+		return envelope{}, fmt.Errorf("blob is encrypted with master key %s, but the configured master key is %s", matDesc.Fingerprint, masterKey.Fingerprint())
+	}
+
+	unwrap := func(name string) ([]byte, error) {
+		wrapped, err := base64.StdEncoding.DecodeString(meta(name))
+		if err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", name, err)
+		}
+
+		unwrapped, err := masterKey.Unwrap(wrapped)
+		if err != nil {
+			return nil, fmt.Errorf("unwrapping %s: %w", name, err)
+		}
+		return unwrapped, nil
+	}
+
+	dataKey, err := unwrap(metaEncryptionKey)
+	if err != nil {
+		return envelope{}, err
+	}
+
+	iv, err := unwrap(metaEncryptionStart)
+	if err != nil {
+		return envelope{}, err
+	}
+
+	return envelope{dataKey: dataKey, iv: iv}, nil
+}
+
+// streamReader returns a reader AES-CTR en- or decrypting the content of source.
+func (e envelope) streamReader(source io.Reader) (io.Reader, error) {
+	block, err := aes.NewCipher(e.dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.StreamReader{S: cipher.NewCTR(block, e.iv), R: source}, nil
+}
+
+// transformFile writes the AES-CTR en- or decrypted content of sourceFilePath to destinationFilePath
+// and returns the base64 encoded MD5 of the written content.
+func (e envelope) transformFile(sourceFilePath string, destinationFilePath string) (string, error) {
+	source, err := os.Open(sourceFilePath)
+	if err != nil {
+		return "", err
+	}
+	defer source.Close()
+
+	reader, err := e.streamReader(source)
+	if err != nil {
+		return "", err
+	}
+
+	destination, err := os.OpenFile(destinationFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer destination.Close()
+
+	hash := md5.New()
+	_, err = io.Copy(io.MultiWriter(destination, hash), reader)
+	if err != nil {
+		return "", err
+	}
+
+	err = destination.Close()
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil)), nil
+}
+
+// verifyDecryptedMD5 compares the base64 encoded MD5 of decrypted content with the MD5 recorded on upload, if any.
+func verifyDecryptedMD5(objectHeader http.Header, actualMD5 string) error {
+	expectedMD5 := objectHeader.Get(oss.HTTPHeaderOssMetaPrefix + metaEncryptionUnencryptedContentMD5)
+	if expectedMD5 != "" && expectedMD5 != actualMD5 {
+		return fmt.Errorf("%w: expected decrypted content MD5 %s, got %s", ErrChecksumMismatch, expectedMD5, actualMD5)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package client_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/bosh-ali-storage-cli/client"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LoadMasterKey", func() {
+	var keyDir string
+
+	writeKeyFile := func(contents []byte) string {
+		keyFilePath := filepath.Join(keyDir, "master.key")
+		Expect(os.WriteFile(keyFilePath, contents, 0600)).To(Succeed())
+		return keyFilePath
+	}
+
+	BeforeEach(func() {
+		keyDir = GinkgoT().TempDir()
+	})
+
+	Context("with a base64 encoded 256 bit key", func() {
+		It("wraps and unwraps data keys with AES-GCM", func() {
+			key := make([]byte, 32)
+			_, err := rand.Read(key)
+			Expect(err).ToNot(HaveOccurred())
+
+			masterKey, err := client.LoadMasterKey(writeKeyFile([]byte(base64.StdEncoding.EncodeToString(key) + "\n")))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(masterKey.WrapAlgorithm()).To(Equal("AES/GCM/NoPadding"))
+
+			wrapped, err := masterKey.Wrap([]byte("data key"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(wrapped).ToNot(ContainSubstring("data key"))
+
+			unwrapped, err := masterKey.Unwrap(wrapped)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(unwrapped)).To(Equal("data key"))
+		})
+	})
+
+	Context("with an RSA key", func() {
+		var privateKey *rsa.PrivateKey
+
+		BeforeEach(func() {
+			var err error
+			privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("wraps and unwraps data keys with the private key", func() {
+			masterKey, err := client.LoadMasterKey(writeKeyFile(pem.EncodeToMemory(&pem.Block{
+				Type:  "RSA PRIVATE KEY",
+				Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+			})))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(masterKey.WrapAlgorithm()).To(Equal("RSA/NONE/PKCS1Padding"))
+
+			wrapped, err := masterKey.Wrap([]byte("data key"))
+			Expect(err).ToNot(HaveOccurred())
+
+			unwrapped, err := masterKey.Unwrap(wrapped)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(unwrapped)).To(Equal("data key"))
+		})
+
+		It("only wraps data keys with the public key", func() {
+			publicKey, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			masterKey, err := client.LoadMasterKey(writeKeyFile(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})))
+			Expect(err).ToNot(HaveOccurred())
+
+			wrapped, err := masterKey.Wrap([]byte("data key"))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = masterKey.Unwrap(wrapped)
+			Expect(err).To(MatchError(ContainSubstring("only a public key")))
+		})
+
+		It("identifies the private and public key by the same fingerprint", func() {
+			privateMasterKey, err := client.LoadMasterKey(writeKeyFile(pem.EncodeToMemory(&pem.Block{
+				Type:  "RSA PRIVATE KEY",
+				Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+			})))
+			Expect(err).ToNot(HaveOccurred())
+
+			publicMasterKey, err := client.LoadMasterKey(writeKeyFile(pem.EncodeToMemory(&pem.Block{
+				Type:  "RSA PUBLIC KEY",
+				Bytes: x509.MarshalPKCS1PublicKey(&privateKey.PublicKey),
+			})))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(privateMasterKey.Fingerprint()).To(Equal(publicMasterKey.Fingerprint()))
+		})
+	})
+
+	It("fails for a key of the wrong size", func() {
+		_, err := client.LoadMasterKey(writeKeyFile([]byte(base64.StdEncoding.EncodeToString([]byte("too short")))))
+		Expect(err).To(MatchError(ContainSubstring("neither a PEM encoded RSA key nor a base64 encoded 256 bit key")))
+	})
+
+	It("fails for a missing key file", func() {
+		_, err := client.LoadMasterKey(filepath.Join(keyDir, "missing.key"))
+		Expect(err).To(MatchError(ContainSubstring("reading master key")))
+	})
+
+	It("fails creating a storage client with an unusable key file", func() {
+		_, err := client.NewStorageClient(config.AliStorageConfig{
+			AccessKeyID:                 "foo_access_key_id",
+			AccessKeySecret:             "foo_access_key_secret",
+			Endpoint:                    "oss-cn-hangzhou.aliyuncs.com",
+			BucketName:                  "foo-bucket-name",
+			ClientSideEncryptionKeyFile: writeKeyFile([]byte("not a key")),
+		})
+		Expect(err).To(MatchError(ContainSubstring("loading client-side encryption key")))
+	})
+})
@@ -0,0 +1,12 @@
+package client
+
+import "time"
+
+// SetTimeNow makes URLs be signed at the time returned by now until restore is called.
+func SetTimeNow(now func() time.Time) (restore func()) {
+	timeNow = now
+	return func() { timeNow = time.Now }
+}
+
+// DeleteConcurrency is the number of batch deletes sent in parallel.
+const DeleteConcurrency = deleteConcurrency
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// Keys of the attributes of the structured log records, which are written to the default slog logger
+const (
+	logKeyOperation = "operation"
+	logKeyBucket    = "bucket"
+	logKeyKey       = "key"
+	logKeyBytes     = "bytes"
+	logKeyDuration  = "duration"
+	logKeyRequestID = "request_id"
+	logKeyError     = "error"
+)
+
+// operationKey is the context key of the operationLog of an operation
+type operationKey struct{}
+
+// requestIDRecorder keeps the ID of the last OSS response received for an operation, so that
+// the operation can be looked up in the OSS access logs or reported to the OSS support.
+type requestIDRecorder struct {
+	mutex     sync.Mutex
+	requestID string
+}
+
+func (r *requestIDRecorder) record(requestID string) {
+	if requestID == "" {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.requestID = requestID
+}
+
+func (r *requestIDRecorder) last() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.requestID
+}
+
+// recordRequestID records the request ID of an OSS response for the operation of ctx, if any.
+func recordRequestID(ctx context.Context, requestID string) {
+	operation, ok := ctx.Value(operationKey{}).(*operationLog)
+	if ok {
+		operation.requestIDs.record(requestID)
+	}
+}
+
+// operationLogger returns the logger of the operation of ctx, which adds the operation, bucket and key to every record.
+func operationLogger(ctx context.Context) *slog.Logger {
+	operation, ok := ctx.Value(operationKey{}).(*operationLog)
+	if ok {
+		return operation.logger
+	}
+	return slog.Default()
+}
+
+// operationLog logs the outcome of a single operation of the storage client.
+type operationLog struct {
+	logger     *slog.Logger
+	start      time.Time
+	requestIDs *requestIDRecorder
+}
+
+// startOperation returns the log of an operation on key and the context carrying it, which records the request IDs
+// of the requests of the operation.
+func (dsc DefaultStorageClient) startOperation(ctx context.Context, operation string, key string) (context.Context, *operationLog) {
+	attrs := []any{slog.String(logKeyOperation, operation), slog.String(logKeyBucket, dsc.storageConfig.BucketName)}
+	if key != "" {
+		attrs = append(attrs, slog.String(logKeyKey, key))
+	}
+	logger := slog.Default().With(attrs...)
+	logger.Debug("operation started")
+
+	log := &operationLog{logger: logger, start: time.Now(), requestIDs: &requestIDRecorder{}}
+	return context.WithValue(ctx, operationKey{}, log), log
+}
+
+// finish logs the duration and outcome of the operation, with the request ID of the failed request for OSS errors.
+func (o *operationLog) finish(err error, attrs ...any) {
+	attrs = append(attrs, slog.Duration(logKeyDuration, time.Since(o.start)))
+
+	requestID := o.requestIDs.last()
+	var serviceError oss.ServiceError
+	if errors.As(err, &serviceError) && serviceError.RequestID != "" {
+		requestID = serviceError.RequestID
+	}
+	if requestID != "" {
+		attrs = append(attrs, slog.String(logKeyRequestID, requestID))
+	}
+
+	// A failed attempt may still be retried, the final error is reported by the caller
+	if err != nil {
+		o.logger.Warn("operation failed", append(attrs, slog.String(logKeyError, err.Error()))...)
+		return
+	}
+	o.logger.Info("operation completed", attrs...)
+}
@@ -0,0 +1,184 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
+)
+
+const (
+	// MaxPostObjectSize is the largest content of a PostObject upload, 5 GB
+	MaxPostObjectSize = 5 * 1024 * 1024 * 1024
+
+	// postPolicyTimeFormat is the format of the expiration of PostObject policies
+	postPolicyTimeFormat = "2006-01-02T15:04:05.000Z"
+
+	// postKeyFileName is replaced by OSS with the file name of the file field of a PostObject upload
+	postKeyFileName = "${filename}"
+)
+
+// PostPolicyOptions are the conditions of a signed PostObject policy, which a form upload has to satisfy.
+type PostPolicyOptions struct {
+	// KeyPrefix is the prefix the key of the uploaded object has to start with
+	KeyPrefix string
+	// AllowAnyKey allows an empty KeyPrefix, which authorizes uploads to any key and so overwriting any object
+	AllowAnyKey bool
+	// MinContentLength and MaxContentLength limit the size of the uploaded content in bytes, if either is positive.
+	// MaxContentLength defaults to MaxPostObjectSize.
+	MinContentLength int64
+	MaxContentLength int64
+	// ContentType is the Content-Type the upload has to be sent with, or empty for any type
+	ContentType string
+}
+
+// Validate checks that the content length range of the options is within the limits of PostObject uploads, and that
+// the keys are restricted to a prefix unless any key is allowed explicitly.
+func (o PostPolicyOptions) Validate() error {
+	switch {
+	case o.MinContentLength < 0 || o.MaxContentLength < 0:
+		return fmt.Errorf("content length range %d to %d must not be negative", o.MinContentLength, o.MaxContentLength)
+	case o.MaxContentLength > MaxPostObjectSize:
+		return fmt.Errorf("maximum content length %d exceeds the %d bytes of PostObject uploads", o.MaxContentLength, MaxPostObjectSize)
+	case o.MaxContentLength > 0 && o.MinContentLength > o.MaxContentLength:
+		return fmt.Errorf("minimum content length %d must not be greater than maximum content length %d", o.MinContentLength, o.MaxContentLength)
+	case o.KeyPrefix == "" && !o.AllowAnyKey:
+		return fmt.Errorf("a key prefix is required unless uploads to any key are allowed explicitly")
+	}
+	return nil
+}
+
+// validatePostExpiration checks that a PostObject policy expires in the future.
+func validatePostExpiration(expiredInSec int64) error {
+	if expiredInSec <= 0 {
+		return fmt.Errorf("expiration of %d seconds must be positive", expiredInSec)
+	}
+	return nil
+}
+
+// SignedPostPolicy is a signed PostObject policy with the URL of the bucket and the fields of the upload form.
+type SignedPostPolicy struct {
+	URL        string    `json:"url"`
+	Expiration time.Time `json:"expiration"`
+	// Fields are the form fields to send before the file field. The key field is the key prefix followed by
+	// ${filename}, which OSS replaces with the file name of the file field, but it can be set to any key starting
+	// with the key prefix.
+	Fields map[string]string `json:"fields"`
+}
+
+// postPolicy is the policy document of PostObject uploads. Each condition is either an object with the exact value
+// of a field, or an array of an operator, the $-prefixed field and the value, or the content-length-range.
+type postPolicy struct {
+	Expiration string `json:"expiration"`
+	Conditions []any  `json:"conditions"`
+}
+
+// SignPostPolicy returns a signed policy authorizing PostObject uploads to the configured bucket until it expires.
+// Uploads have to send the fields of the configured server-side encryption, which are part of the policy.
+func (dsc DefaultStorageClient) SignPostPolicy(
+	ctx context.Context,
+	expiredInSec int64,
+	options PostPolicyOptions,
+) (signedPolicy SignedPostPolicy, err error) {
+	_, operation := dsc.startOperation(ctx, "sign_post", options.KeyPrefix)
+	defer func() { operation.finish(err, slog.Int64("expires_in_seconds", expiredInSec)) }()
+
+	err = options.Validate()
+	if err != nil {
+		return SignedPostPolicy{}, err
+	}
+	err = validatePostExpiration(expiredInSec)
+	if err != nil {
+		return SignedPostPolicy{}, err
+	}
+
+	bucketURL, err := dsc.objectURL("")
+	if err != nil {
+		return SignedPostPolicy{}, err
+	}
+
+	now := time.Now().UTC()
+	signedPolicy = SignedPostPolicy{
+		URL:        bucketURL,
+		Expiration: now.Add(time.Duration(expiredInSec) * time.Second).Truncate(time.Millisecond),
+		Fields:     map[string]string{"key": options.KeyPrefix + postKeyFileName},
+	}
+
+	policy := postPolicy{
+		Expiration: signedPolicy.Expiration.Format(postPolicyTimeFormat),
+		Conditions: []any{
+			map[string]string{"bucket": dsc.storageConfig.BucketName},
+			[]any{"starts-with", "$key", options.KeyPrefix},
+		},
+	}
+	if options.MinContentLength > 0 || options.MaxContentLength > 0 {
+		maxContentLength := options.MaxContentLength
+		if maxContentLength == 0 {
+			maxContentLength = MaxPostObjectSize
+		}
+		policy.Conditions = append(policy.Conditions, []any{"content-length-range", options.MinContentLength, maxContentLength})
+	}
+
+	// The remaining fields are sent as they are and have to match the policy exactly
+	policyFields := map[string]string{}
+	if options.ContentType != "" {
+		policyFields[oss.HTTPHeaderContentType] = options.ContentType
+	}
+	if dsc.storageConfig.ServerSideEncryption != "" {
+		policyFields[oss.HTTPHeaderOssServerSideEncryption] = dsc.storageConfig.ServerSideEncryption
+	}
+	if dsc.storageConfig.SSEKMSKeyID != "" {
+		policyFields[oss.HTTPHeaderOssServerSideEncryptionKeyID] = dsc.storageConfig.SSEKMSKeyID
+	}
+
+	credentials := dsc.credentialsProvider.GetCredentials()
+	if token := credentials.GetSecurityToken(); token != "" {
+		policyFields["x-oss-security-token"] = token
+	}
+
+	v4 := dsc.storageConfig.AuthVersion == config.AuthVersionV4
+	if v4 {
+		policyFields["x-oss-signature-version"] = v4Algorithm
+		policyFields["x-oss-credential"] = credentials.GetAccessKeyID() + "/" + now.Format(v4DayFormat) + "/" +
+			dsc.storageConfig.Region + "/" + v4Product + "/" + v4Terminator
+		policyFields["x-oss-date"] = now.Format(v4DateFormat)
+	}
+
+	names := make([]string, 0, len(policyFields))
+	for name := range policyFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		policy.Conditions = append(policy.Conditions, []any{"eq", "$" + name, policyFields[name]})
+		signedPolicy.Fields[name] = policyFields[name]
+	}
+
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return SignedPostPolicy{}, fmt.Errorf("encoding policy: %w", err)
+	}
+	encodedPolicy := base64.StdEncoding.EncodeToString(policyJSON)
+	signedPolicy.Fields["policy"] = encodedPolicy
+
+	if v4 {
+		key := v4SigningKey(credentials.GetAccessKeySecret(), now, dsc.storageConfig.Region)
+		signedPolicy.Fields["x-oss-signature"] = hex.EncodeToString(hmacSHA256(key, encodedPolicy))
+		return signedPolicy, nil
+	}
+
+	mac := hmac.New(sha1.New, []byte(credentials.GetAccessKeySecret()))
+	mac.Write([]byte(encodedPolicy))
+	signedPolicy.Fields["OSSAccessKeyId"] = credentials.GetAccessKeyID()
+	signedPolicy.Fields["Signature"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return signedPolicy, nil
+}
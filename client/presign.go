@@ -0,0 +1,298 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
+)
+
+const (
+	v4Algorithm       = "OSS4-HMAC-SHA256"
+	v4DateFormat      = "20060102T150405Z"
+	v4DayFormat       = "20060102"
+	v4Product         = "oss"
+	v4Terminator      = "aliyun_v4_request"
+	v4UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+	// v4MaxExpiredInSec is the longest validity of a URL with a V4 signature, 7 days
+	v4MaxExpiredInSec = 7 * 24 * 60 * 60
+
+	// MinTrafficLimit and MaxTrafficLimit are the bounds of the bandwidth limit of a transfer in bit/s,
+	// 100 KB/s and 100 MB/s
+	MinTrafficLimit = 100 * 1024 * 8
+	MaxTrafficLimit = 100 * 1024 * 1024 * 8
+)
+
+// timeNow returns the time URLs are signed at, it is replaced by the tests to sign at a fixed time
+var timeNow = time.Now
+
+// SignOptions are the optional properties of a request through a signed URL. They are part of the signature,
+// so that the request cannot change them.
+type SignOptions struct {
+	// ContentType and ContentMD5, the base64 encoded MD5 of the content, have to be sent with an upload
+	ContentType string
+	ContentMD5  string
+	// ResponseContentDisposition overrides the Content-Disposition of the response of a download
+	ResponseContentDisposition string
+	// TrafficLimit limits the bandwidth of an upload or download in bit/s, or 0 for no limit
+	TrafficLimit int64
+	// ServerSideEncryption and SSEKMSKeyID override the configured server-side encryption of an upload
+	ServerSideEncryption string
+	SSEKMSKeyID          string
+}
+
+// Validate checks that the options apply to requests with the given HTTP method.
+func (o SignOptions) Validate(method string) error {
+	isUpload := method == http.MethodPut
+	isDownload := method == http.MethodGet || method == http.MethodHead
+
+	switch {
+	case !isUpload && (o.ContentType != "" || o.ContentMD5 != ""):
+		return fmt.Errorf("content type and content MD5 only apply to signed PUT urls")
+	case !isUpload && (o.ServerSideEncryption != "" || o.SSEKMSKeyID != ""):
+		return fmt.Errorf("server-side encryption only applies to signed PUT urls")
+	case !isDownload && o.ResponseContentDisposition != "":
+		return fmt.Errorf("response content disposition only applies to signed GET and HEAD urls")
+	case method != http.MethodPut && method != http.MethodGet && o.TrafficLimit != 0:
+		return fmt.Errorf("traffic limit only applies to signed GET and PUT urls")
+	case o.TrafficLimit != 0 && (o.TrafficLimit < MinTrafficLimit || o.TrafficLimit > MaxTrafficLimit):
+		return fmt.Errorf("traffic limit %d is not between %d and %d bit/s", o.TrafficLimit, MinTrafficLimit, MaxTrafficLimit)
+	}
+	return nil
+}
+
+// SignedURL is a signed URL with the method and the headers of the request it authorizes.
+type SignedURL struct {
+	URL    string `json:"url"`
+	Method string `json:"method"`
+	// Headers are the headers the request has to be sent with, as they are part of the signature
+	Headers map[string]string `json:"headers"`
+}
+
+// SignURL returns a URL authorizing a request with the given HTTP method for object until it expires.
+// Uploads through the URL have to send the headers of the configured server-side encryption, unless overridden.
+func (dsc DefaultStorageClient) SignURL(
+	ctx context.Context,
+	object string,
+	method string,
+	expiredInSec int64,
+	options SignOptions,
+) (signedURL SignedURL, err error) {
+	_, operation := dsc.startOperation(ctx, "sign_"+strings.ToLower(method), object)
+	defer func() { operation.finish(err, slog.Int64("expires_in_seconds", expiredInSec)) }()
+
+	err = options.Validate(method)
+	if err != nil {
+		return SignedURL{}, err
+	}
+
+	request := presignedRequest{method: method, object: object, header: http.Header{}, query: url.Values{}, expiredInSec: expiredInSec}
+	if options.ContentType != "" {
+		request.header.Set(oss.HTTPHeaderContentType, options.ContentType)
+	}
+	if options.ContentMD5 != "" {
+		request.header.Set(oss.HTTPHeaderContentMD5, options.ContentMD5)
+	}
+	if method == http.MethodPut {
+		serverSideEncryption, sseKMSKeyID := dsc.storageConfig.ServerSideEncryption, dsc.storageConfig.SSEKMSKeyID
+		if options.ServerSideEncryption != "" {
+			serverSideEncryption, sseKMSKeyID = options.ServerSideEncryption, options.SSEKMSKeyID
+		}
+		if serverSideEncryption != "" {
+			request.header.Set(oss.HTTPHeaderOssServerSideEncryption, serverSideEncryption)
+		}
+		if sseKMSKeyID != "" {
+			request.header.Set(oss.HTTPHeaderOssServerSideEncryptionKeyID, sseKMSKeyID)
+		}
+	}
+	if options.ResponseContentDisposition != "" {
+		request.query.Set("response-content-disposition", options.ResponseContentDisposition)
+	}
+	if options.TrafficLimit != 0 {
+		request.query.Set("x-oss-traffic-limit", strconv.FormatInt(options.TrafficLimit, 10))
+	}
+
+	signedURL = SignedURL{Method: method, Headers: map[string]string{}}
+	for name := range request.header {
+		signedURL.Headers[name] = request.header.Get(name)
+	}
+
+	if dsc.storageConfig.AuthVersion == config.AuthVersionV4 {
+		signedURL.URL, err = dsc.signURLV4(request)
+		return signedURL, err
+	}
+
+	var ossOptions []oss.Option
+	for name := range request.header {
+		ossOptions = append(ossOptions, oss.SetHeader(name, request.header.Get(name)))
+	}
+	for name := range request.query {
+		ossOptions = append(ossOptions, oss.AddParam(name, request.query.Get(name)))
+	}
+
+	signedURL.URL, err = dsc.bucket.SignURL(object, oss.HTTPMethod(method), expiredInSec, ossOptions...)
+	return signedURL, err
+}
+
+// presignedRequest is a request for an object of the configured bucket, signed in advance for a presigned URL.
+type presignedRequest struct {
+	method string
+	object string
+	// header are the headers the request has to be sent with, which are part of the signature
+	header http.Header
+	// query are the parameters of the URL in addition to the signature, e.g. response header overrides
+	query        url.Values
+	expiredInSec int64
+}
+
+// signURLV4 returns a URL of a request signed with a V4 signature in its query, since the SDK only presigns URLs
+// with V1 signatures.
+func (dsc DefaultStorageClient) signURLV4(request presignedRequest) (string, error) {
+	if request.expiredInSec < 0 || request.expiredInSec > v4MaxExpiredInSec {
+		return "", fmt.Errorf("expiration of %d seconds is not between 0 and %d seconds", request.expiredInSec, v4MaxExpiredInSec)
+	}
+
+	objectURL, err := dsc.objectURL(request.object)
+	if err != nil {
+		return "", err
+	}
+	resource := "/" + dsc.storageConfig.BucketName + "/" + v4Escape(request.object, false)
+
+	credentials := dsc.credentialsProvider.GetCredentials()
+	now := timeNow().UTC()
+	scope := now.Format(v4DayFormat) + "/" + dsc.storageConfig.Region + "/" + v4Product + "/" + v4Terminator
+
+	query := url.Values{}
+	for name, values := range request.query {
+		query[name] = values
+	}
+	query.Set("x-oss-signature-version", v4Algorithm)
+	query.Set("x-oss-credential", credentials.GetAccessKeyID()+"/"+scope)
+	query.Set("x-oss-date", now.Format(v4DateFormat))
+	query.Set("x-oss-expires", strconv.FormatInt(request.expiredInSec, 10))
+	if token := credentials.GetSecurityToken(); token != "" {
+		query.Set("x-oss-security-token", token)
+	}
+
+	canonicalRequest := request.method + "\n" +
+		resource + "\n" +
+		v4CanonicalQuery(query) + "\n" +
+		v4CanonicalHeaders(request.header) + "\n" +
+		"\n" +
+		v4UnsignedPayload
+
+	hashedRequest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := v4Algorithm + "\n" +
+		now.Format(v4DateFormat) + "\n" +
+		scope + "\n" +
+		hex.EncodeToString(hashedRequest[:])
+
+	key := v4SigningKey(credentials.GetAccessKeySecret(), now, dsc.storageConfig.Region)
+	query.Set("x-oss-signature", hex.EncodeToString(hmacSHA256(key, stringToSign)))
+
+	return objectURL + "?" + query.Encode(), nil
+}
+
+// objectURL returns the URL of an object of the configured bucket, or of the bucket itself for an empty object.
+// Like the SDK, endpoints given as IP address are addressed in path style.
+func (dsc DefaultStorageClient) objectURL(object string) (string, error) {
+	scheme, host := "http", dsc.storageConfig.Endpoint
+	if before, after, ok := strings.Cut(host, "://"); ok {
+		scheme, host = before, after
+	}
+	endpointURL, err := url.Parse(scheme + "://" + host)
+	if err != nil {
+		return "", fmt.Errorf("parsing endpoint: %w", err)
+	}
+
+	bucketName := dsc.storageConfig.BucketName
+	if net.ParseIP(endpointURL.Hostname()) != nil {
+		return scheme + "://" + endpointURL.Host + "/" + bucketName + "/" + v4Escape(object, false), nil
+	}
+	return scheme + "://" + bucketName + "." + endpointURL.Host + "/" + v4Escape(object, false), nil
+}
+
+// v4SigningKey returns the key of V4 signatures, derived from the secret for the day of the signature and the region.
+func v4SigningKey(secret string, signedAt time.Time, region string) []byte {
+	key := []byte("aliyun_v4" + secret)
+	for _, part := range []string{signedAt.Format(v4DayFormat), region, v4Product, v4Terminator} {
+		key = hmacSHA256(key, part)
+	}
+	return key
+}
+
+// v4CanonicalQuery returns the escaped query parameters sorted by name, omitting the equals sign of empty values.
+func v4CanonicalQuery(query url.Values) string {
+	escaped := map[string]string{}
+	for name, values := range query {
+		escaped[v4Escape(name, true)] = ""
+		if len(values) > 0 && values[0] != "" {
+			escaped[v4Escape(name, true)] = "=" + v4Escape(values[0], true)
+		}
+	}
+
+	names := make([]string, 0, len(escaped))
+	for name := range escaped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	params := make([]string, 0, len(names))
+	for _, name := range names {
+		params = append(params, name+escaped[name])
+	}
+	return strings.Join(params, "&")
+}
+
+// v4CanonicalHeaders returns the signed headers, the x-oss-* headers, Content-Type and Content-MD5, by their
+// lower-case names in sorted order, each one followed by a newline.
+func v4CanonicalHeaders(header http.Header) string {
+	signed := map[string]string{}
+	for name, values := range header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-oss-") || name == "content-type" || name == "content-md5" {
+			signed[name] = strings.TrimSpace(values[0])
+		}
+	}
+
+	names := make([]string, 0, len(signed))
+	for name := range signed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + signed[name] + "\n"
+	}
+	return canonicalHeaders
+}
+
+// v4Escape escapes a value like url.QueryEscape, but with spaces as %20. Slashes are only escaped in query
+// parameters, not in object keys.
+func v4Escape(value string, escapeSlash bool) string {
+	escaped := strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+	if !escapeSlash {
+		escaped = strings.ReplaceAll(escaped, "%2F", "/")
+	}
+	return escaped
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
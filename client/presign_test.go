@@ -0,0 +1,112 @@
+package client_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/bosh-ali-storage-cli/client"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SignURL with V4 signatures", func() {
+	const (
+		signedAt = "20231217T025437Z"
+		scope    = "20231217/cn-hangzhou/oss/aliyun_v4_request"
+	)
+
+	var (
+		storageClient client.StorageClient
+		restore       func()
+	)
+
+	// v4Signature signs a canonical request written out by hand from the V4 signature documentation of OSS,
+	// independently of the canonicalisation of the client.
+	v4Signature := func(canonicalRequest string) string {
+		hashedRequest := sha256.Sum256([]byte(canonicalRequest))
+		stringToSign := "OSS4-HMAC-SHA256\n" + signedAt + "\n" + scope + "\n" + hex.EncodeToString(hashedRequest[:])
+
+		key := []byte("aliyun_v4sk")
+		for _, data := range append(strings.Split(scope, "/"), stringToSign) {
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte(data))
+			key = mac.Sum(nil)
+		}
+		return hex.EncodeToString(key)
+	}
+
+	BeforeEach(func() {
+		restore = client.SetTimeNow(func() time.Time { return time.Unix(1702781677, 0) })
+
+		var err error
+		storageClient, err = client.NewStorageClient(config.AliStorageConfig{
+			AccessKeyID:     "ak",
+			AccessKeySecret: "sk",
+			Endpoint:        "https://oss-cn-hangzhou.aliyuncs.com",
+			BucketName:      "bucket",
+			AuthVersion:     config.AuthVersionV4,
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		restore()
+	})
+
+	It("signs the escaped key, the query and the headers of an upload", func() {
+		signedURL, err := storageClient.SignURL(context.Background(), "1234+-/123/1.txt", http.MethodPut, 599, client.SignOptions{
+			ContentType:  "text/plain",
+			ContentMD5:   "rL0Y20zC+Fzt72VPzMSk2A==",
+			TrafficLimit: client.MinTrafficLimit,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		canonicalRequest := "PUT\n" +
+			"/bucket/1234%2B-/123/1.txt\n" +
+			"x-oss-credential=ak%2F20231217%2Fcn-hangzhou%2Foss%2Faliyun_v4_request&x-oss-date=20231217T025437Z&x-oss-expires=599&x-oss-signature-version=OSS4-HMAC-SHA256&x-oss-traffic-limit=819200\n" +
+			"content-md5:rL0Y20zC+Fzt72VPzMSk2A==\n" +
+			"content-type:text/plain\n" +
+			"\n" +
+			"\n" +
+			"UNSIGNED-PAYLOAD"
+
+		parsed, err := url.Parse(signedURL.URL)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed.Scheme + "://" + parsed.Host + parsed.EscapedPath()).To(Equal("https://bucket.oss-cn-hangzhou.aliyuncs.com/1234%2B-/123/1.txt"))
+		Expect(parsed.Query()).To(Equal(url.Values{
+			"x-oss-credential":        {"ak/" + scope},
+			"x-oss-date":              {signedAt},
+			"x-oss-expires":           {"599"},
+			"x-oss-signature-version": {"OSS4-HMAC-SHA256"},
+			"x-oss-traffic-limit":     {"819200"},
+			"x-oss-signature":         {v4Signature(canonicalRequest)},
+		}))
+	})
+
+	It("signs query parameters with reserved characters escaped", func() {
+		signedURL, err := storageClient.SignURL(context.Background(), "dir/a b.txt", http.MethodGet, 60, client.SignOptions{
+			ResponseContentDisposition: "attachment; filename=a b.txt",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		canonicalRequest := "GET\n" +
+			"/bucket/dir/a%20b.txt\n" +
+			"response-content-disposition=attachment%3B%20filename%3Da%20b.txt&x-oss-credential=ak%2F20231217%2Fcn-hangzhou%2Foss%2Faliyun_v4_request&x-oss-date=20231217T025437Z&x-oss-expires=60&x-oss-signature-version=OSS4-HMAC-SHA256\n" +
+			"\n" +
+			"\n" +
+			"UNSIGNED-PAYLOAD"
+
+		parsed, err := url.Parse(signedURL.URL)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed.EscapedPath()).To(Equal("/dir/a%20b.txt"))
+		Expect(parsed.Query().Get("response-content-disposition")).To(Equal("attachment; filename=a b.txt"))
+		Expect(parsed.Query().Get("x-oss-signature")).To(Equal(v4Signature(canonicalRequest)))
+	})
+})
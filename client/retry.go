@@ -0,0 +1,299 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
+)
+
+// retryableErrorCodes are the OSS error codes of transient failures, independent of their HTTP status code
+var retryableErrorCodes = map[string]bool{
+	"InternalError":      true,
+	"RequestTimeout":     true,
+	"ServiceUnavailable": true,
+}
+
+// RetryPolicy defines how often, and after which backoff, an operation failing with a transient error is retried.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first one, 1 disables retries
+	MaxAttempts int
+	// BaseBackoff is the backoff before the first retry, doubled for every further retry up to MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Jitter randomizes the backoff, one of config.RetryJitterFull, config.RetryJitterEqual or config.RetryJitterNone
+	Jitter string
+}
+
+// NewRetryPolicy returns the retry policy of the retry_* properties of storageConfig.
+func NewRetryPolicy(storageConfig config.AliStorageConfig) RetryPolicy {
+	storageConfig.ApplyDefaults()
+
+	return RetryPolicy{
+		MaxAttempts: storageConfig.RetryMaxAttempts,
+		BaseBackoff: time.Duration(storageConfig.RetryBaseBackoffMs) * time.Millisecond,
+		MaxBackoff:  time.Duration(storageConfig.RetryMaxBackoffMs) * time.Millisecond,
+		Jitter:      storageConfig.RetryJitter,
+	}
+}
+
+// backoff returns the duration to wait before the given retry, starting with 1.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	backoff := p.BaseBackoff
+	for i := 1; i < retry && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, p.MaxBackoff)
+
+	if backoff <= 0 {
+		return 0
+	}
+
+	switch p.Jitter {
+	case config.RetryJitterNone:
+		return backoff
+	case config.RetryJitterEqual:
+		return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+	default:
+		return time.Duration(rand.Int63n(int64(backoff) + 1))
+	}
+}
+
+// retryingStorageClient retries the operations of a StorageClient which fail with a transient error.
+type retryingStorageClient struct {
+	storageClient StorageClient
+	policy        RetryPolicy
+}
+
+// NewRetryingStorageClient returns a StorageClient retrying the operations of storageClient according to policy.
+// All operations are idempotent and retried, except for uploads of streams which cannot be rewound.
+func NewRetryingStorageClient(storageClient StorageClient, policy RetryPolicy) StorageClient {
+	return retryingStorageClient{storageClient: storageClient, policy: policy}
+}
+
+// retry calls attempt until it succeeds, fails with a permanent error, ctx is done or all attempts have been made.
+// The error of the last attempt is returned.
+func (c retryingStorageClient) retry(ctx context.Context, operation string, key string, attempt func() error) error {
+	for attemptNumber := 1; ; attemptNumber++ {
+		err := attempt()
+		if err == nil || attemptNumber >= c.policy.MaxAttempts || ctx.Err() != nil || !IsTransient(err) {
+			return err
+		}
+
+		backoff := c.policy.backoff(attemptNumber)
+		attrs := []any{slog.String(logKeyOperation, operation)}
+		if key != "" {
+			attrs = append(attrs, slog.String(logKeyKey, key))
+		}
+		slog.Warn("retrying operation", append(attrs,
+			slog.Int("attempt", attemptNumber),
+			slog.Int("max_attempts", c.policy.MaxAttempts),
+			slog.Duration("backoff", backoff),
+			slog.String(logKeyError, err.Error()),
+		)...)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// IsCancelled tells whether err results from a cancelled context or a context whose deadline passed. The timeouts of
+// the HTTP transport match context.DeadlineExceeded as well, but do not count, as they fail a single request.
+func IsCancelled(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return true
+	}
+
+	for ; err != nil; err = errors.Unwrap(err) {
+		if err == context.DeadlineExceeded {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTransient tells whether err is a transient failure, which may succeed if the operation is repeated.
+func IsTransient(err error) bool {
+	switch {
+	case IsCancelled(err):
+		return false
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrEncryptionKeyRequired):
+		return false
+	case errors.Is(err, ErrChecksumMismatch):
+		// A corrupted download is discarded and a corrupted upload overwritten, so repeating the transfer may succeed
+		return true
+	}
+
+	var serviceError oss.ServiceError
+	if errors.As(err, &serviceError) {
+		if retryableErrorCodes[serviceError.Code] {
+			return true
+		}
+		switch serviceError.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests:
+			return true
+		case http.StatusNotImplemented:
+			return false
+		}
+		return serviceError.StatusCode >= http.StatusInternalServerError
+	}
+
+	var crcError oss.CRCCheckError
+	if errors.As(err, &crcError) {
+		return true
+	}
+
+	var netError net.Error
+	if errors.As(err, &netError) && netError.Timeout() {
+		return true
+	}
+
+	// Connections closed by OSS or a proxy, including idle keep-alive connections closed while being reused
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// Upload is retried, as every attempt reads the file again. A failed multipart upload resumes from its checkpoint.
+func (c retryingStorageClient) Upload(ctx context.Context, sourceFilePath string, sourceFileMD5 string, destinationObject string) error {
+	return c.retry(ctx, "upload", destinationObject, func() error {
+		return c.storageClient.Upload(ctx, sourceFilePath, sourceFileMD5, destinationObject)
+	})
+}
+
+func (c retryingStorageClient) Download(ctx context.Context, sourceObject string, destinationFilePath string) error {
+	return c.retry(ctx, "download", sourceObject, func() error {
+		return c.storageClient.Download(ctx, sourceObject, destinationFilePath)
+	})
+}
+
+// UploadStream is only retried if source can be rewound to the position at which the first attempt started reading.
+// Otherwise, the content read by a failed attempt is lost and the upload is attempted once.
+func (c retryingStorageClient) UploadStream(
+	ctx context.Context,
+	source io.Reader,
+	size int64,
+	destinationObject string,
+	options PutOptions,
+) error {
+	seeker, ok := source.(io.Seeker)
+	if !ok {
+		return c.storageClient.UploadStream(ctx, source, size, destinationObject, options)
+	}
+
+	// Seeking fails for pipes, e.g. stdin
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return c.storageClient.UploadStream(ctx, source, size, destinationObject, options)
+	}
+
+	return c.retry(ctx, "upload_stream", destinationObject, func() error {
+		_, err := seeker.Seek(start, io.SeekStart)
+		if err != nil {
+			return err
+		}
+		return c.storageClient.UploadStream(ctx, source, size, destinationObject, options)
+	})
+}
+
+// OpenStream retries opening the stream. Failures while reading it are returned to the reader.
+func (c retryingStorageClient) OpenStream(ctx context.Context, sourceObject string, options GetOptions) (io.ReadCloser, error) {
+	var reader io.ReadCloser
+	err := c.retry(ctx, "open_stream", sourceObject, func() error {
+		var err error
+		reader, err = c.storageClient.OpenStream(ctx, sourceObject, options)
+		return err
+	})
+	return reader, err
+}
+
+func (c retryingStorageClient) Delete(ctx context.Context, object string) error {
+	return c.retry(ctx, "delete", object, func() error {
+		return c.storageClient.Delete(ctx, object)
+	})
+}
+
+func (c retryingStorageClient) Exists(ctx context.Context, object string) (bool, error) {
+	var exists bool
+	err := c.retry(ctx, "exists", object, func() error {
+		var err error
+		exists, err = c.storageClient.Exists(ctx, object)
+		return err
+	})
+	return exists, err
+}
+
+// SignURL is not retried, as signing does not send a request.
+func (c retryingStorageClient) SignURL(ctx context.Context, object string, method string, expiredInSec int64, options SignOptions) (SignedURL, error) {
+	return c.storageClient.SignURL(ctx, object, method, expiredInSec, options)
+}
+
+// SignPostPolicy is not retried, as signing does not send a request.
+func (c retryingStorageClient) SignPostPolicy(ctx context.Context, expiredInSec int64, options PostPolicyOptions) (SignedPostPolicy, error) {
+	return c.storageClient.SignPostPolicy(ctx, expiredInSec, options)
+}
+
+func (c retryingStorageClient) Head(ctx context.Context, object string) (ObjectProperties, error) {
+	var properties ObjectProperties
+	err := c.retry(ctx, "head", object, func() error {
+		var err error
+		properties, err = c.storageClient.Head(ctx, object)
+		return err
+	})
+	return properties, err
+}
+
+func (c retryingStorageClient) List(ctx context.Context, prefix string, delimiter string, maxKeys int) (ListResult, error) {
+	var result ListResult
+	err := c.retry(ctx, "list", prefix, func() error {
+		var err error
+		result, err = c.storageClient.List(ctx, prefix, delimiter, maxKeys)
+		return err
+	})
+	return result, err
+}
+
+func (c retryingStorageClient) ListPage(ctx context.Context, prefix string, continuationToken string) (ListResult, string, error) {
+	var result ListResult
+	var nextContinuationToken string
+	err := c.retry(ctx, "list_page", prefix, func() error {
+		var err error
+		result, nextContinuationToken, err = c.storageClient.ListPage(ctx, prefix, continuationToken)
+		return err
+	})
+	return result, nextContinuationToken, err
+}
+
+// DeleteObjects is retried if a batch fails with a transient error. Deleting blobs which have been deleted by a
+// previous attempt succeeds, so the result reports all blobs as deleted.
+func (c retryingStorageClient) DeleteObjects(ctx context.Context, objects []string) (DeleteResult, error) {
+	var result DeleteResult
+	err := c.retry(ctx, "delete_objects", "", func() error {
+		var err error
+		result, err = c.storageClient.DeleteObjects(ctx, objects)
+		return err
+	})
+	return result, err
+}
+
+// Copy is retried, as copying the same source again results in the same destination blob.
+func (c retryingStorageClient) Copy(ctx context.Context, sourceObject string, destinationBucketName string, destinationObject string) error {
+	return c.retry(ctx, "copy", sourceObject, func() error {
+		return c.storageClient.Copy(ctx, sourceObject, destinationBucketName, destinationObject)
+	})
+}
@@ -0,0 +1,155 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/client"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/client/clientfakes"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RetryingStorageClient", func() {
+	var (
+		storageClient  *clientfakes.FakeStorageClient
+		retryingClient client.StorageClient
+	)
+
+	serviceError := func(statusCode int, code string) error {
+		return oss.ServiceError{StatusCode: statusCode, Code: code}
+	}
+
+	BeforeEach(func() {
+		storageClient = &clientfakes.FakeStorageClient{}
+		retryingClient = client.NewRetryingStorageClient(storageClient, client.RetryPolicy{
+			MaxAttempts: 3,
+			BaseBackoff: time.Millisecond,
+			MaxBackoff:  time.Millisecond,
+			Jitter:      config.RetryJitterNone,
+		})
+	})
+
+	It("retries transient errors until the operation succeeds", func() {
+		storageClient.HeadReturnsOnCall(0, client.ObjectProperties{}, serviceError(http.StatusServiceUnavailable, "ServiceUnavailable"))
+		storageClient.HeadReturnsOnCall(1, client.ObjectProperties{}, &net.OpError{Op: "read", Err: syscall.ECONNRESET})
+		storageClient.HeadReturnsOnCall(2, client.ObjectProperties{Size: 3}, nil)
+
+		properties, err := retryingClient.Head(context.Background(), "blob")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(properties.Size).To(Equal(int64(3)))
+		Expect(storageClient.HeadCallCount()).To(Equal(3))
+	})
+
+	It("retries requests timing out while waiting for the response headers", func() {
+		storageClient.HeadReturnsOnCall(0, client.ObjectProperties{}, &url.Error{Op: "Head", URL: "http://foo", Err: headerTimeoutError{}})
+		storageClient.HeadReturnsOnCall(1, client.ObjectProperties{Size: 3}, nil)
+
+		_, err := retryingClient.Head(context.Background(), "blob")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(storageClient.HeadCallCount()).To(Equal(2))
+	})
+
+	It("returns the last error once all attempts failed", func() {
+		storageClient.DownloadReturns(fmt.Errorf("downloading: %w", client.ErrChecksumMismatch))
+
+		err := retryingClient.Download(context.Background(), "blob", "/tmp/blob")
+		Expect(errors.Is(err, client.ErrChecksumMismatch)).To(BeTrue())
+		Expect(storageClient.DownloadCallCount()).To(Equal(3))
+	})
+
+	It("does not retry permanent errors", func() {
+		for _, permanentErr := range []error{
+			serviceError(http.StatusForbidden, "AccessDenied"),
+			serviceError(http.StatusPreconditionFailed, "PreconditionFailed"),
+			serviceError(http.StatusNotImplemented, "NotImplemented"),
+			fmt.Errorf("%w: foo-bucket-name/blob", client.ErrNotFound),
+			client.ErrEncryptionKeyRequired,
+			context.Canceled,
+		} {
+			storageClient.CopyReturns(permanentErr)
+
+			err := retryingClient.Copy(context.Background(), "blob", "", "copy")
+			Expect(err).To(MatchError(permanentErr))
+		}
+		Expect(storageClient.CopyCallCount()).To(Equal(6))
+	})
+
+	It("retries throttled requests and internal errors regardless of their status code", func() {
+		storageClient.DeleteReturnsOnCall(0, serviceError(http.StatusTooManyRequests, ""))
+		storageClient.DeleteReturnsOnCall(1, serviceError(http.StatusBadRequest, "RequestTimeout"))
+
+		Expect(retryingClient.Delete(context.Background(), "blob")).To(Succeed())
+		Expect(storageClient.DeleteCallCount()).To(Equal(3))
+	})
+
+	It("stops retrying once the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		storageClient.ListStub = func(context.Context, string, string, int) (client.ListResult, error) {
+			cancel()
+			return client.ListResult{}, serviceError(http.StatusInternalServerError, "InternalError")
+		}
+
+		_, err := retryingClient.List(ctx, "", "", 0)
+		Expect(err).To(HaveOccurred())
+		Expect(storageClient.ListCallCount()).To(Equal(1))
+	})
+
+	It("rewinds seekable streams before retrying their upload", func() {
+		var uploaded []string
+		storageClient.UploadStreamStub = func(_ context.Context, source io.Reader, _ int64, _ string, _ client.PutOptions) error {
+			content, err := io.ReadAll(source)
+			Expect(err).ToNot(HaveOccurred())
+			uploaded = append(uploaded, string(content))
+
+			if len(uploaded) == 1 {
+				return io.ErrUnexpectedEOF
+			}
+			return nil
+		}
+
+		source := strings.NewReader("foobar")
+		_, err := source.Seek(3, io.SeekStart)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = retryingClient.UploadStream(context.Background(), source, -1, "blob", client.PutOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(uploaded).To(Equal([]string{"bar", "bar"}))
+	})
+
+	It("uploads streams which cannot be rewound only once", func() {
+		storageClient.UploadStreamReturns(io.ErrUnexpectedEOF)
+
+		err := retryingClient.UploadStream(context.Background(), io.MultiReader(strings.NewReader("foo")), -1, "blob", client.PutOptions{})
+		Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+		Expect(storageClient.UploadStreamCallCount()).To(Equal(1))
+	})
+
+	It("derives the retry policy from the configuration", func() {
+		policy := client.NewRetryPolicy(config.AliStorageConfig{RetryMaxAttempts: 5, RetryBaseBackoffMs: 50})
+		Expect(policy).To(Equal(client.RetryPolicy{
+			MaxAttempts: 5,
+			BaseBackoff: 50 * time.Millisecond,
+			MaxBackoff:  time.Duration(config.DefaultRetryMaxBackoffMs) * time.Millisecond,
+			Jitter:      config.RetryJitterFull,
+		}))
+	})
+})
+
+// headerTimeoutError is like the error of the HTTP transport for responses whose headers time out,
+// which matches context.DeadlineExceeded
+type headerTimeoutError struct{}
+
+func (headerTimeoutError) Error() string        { return "net/http: timeout awaiting response headers" }
+func (headerTimeoutError) Timeout() bool        { return true }
+func (headerTimeoutError) Is(target error) bool { return target == context.DeadlineExceeded }
@@ -1,172 +1,999 @@
 package client
 
 import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
-	"log"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/credentials"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . StorageClient
 type StorageClient interface {
 	Upload(
+		ctx context.Context,
 		sourceFilePath string,
 		sourceFileMD5 string,
 		destinationObject string,
 	) error
 
 	Download(
+		ctx context.Context,
 		sourceObject string,
 		destinationFilePath string,
 	) error
 
+	UploadStream(
+		ctx context.Context,
+		source io.Reader,
+		size int64,
+		destinationObject string,
+		options PutOptions,
+	) error
+
+	OpenStream(
+		ctx context.Context,
+		sourceObject string,
+		options GetOptions,
+	) (io.ReadCloser, error)
+
 	Delete(
+		ctx context.Context,
 		object string,
 	) error
 
 	Exists(
+		ctx context.Context,
 		object string,
 	) (bool, error)
 
-	SignedUrlPut(
+	SignURL(
+		ctx context.Context,
 		object string,
+		method string,
 		expiredInSec int64,
-	) (string, error)
+		options SignOptions,
+	) (SignedURL, error)
 
-	SignedUrlGet(
-		object string,
+	SignPostPolicy(
+		ctx context.Context,
 		expiredInSec int64,
-	) (string, error)
+		options PostPolicyOptions,
+	) (SignedPostPolicy, error)
+
+	Head(
+		ctx context.Context,
+		object string,
+	) (ObjectProperties, error)
+
+	List(
+		ctx context.Context,
+		prefix string,
+		delimiter string,
+		maxKeys int,
+	) (ListResult, error)
+
+	ListPage(
+		ctx context.Context,
+		prefix string,
+		continuationToken string,
+	) (ListResult, string, error)
+
+	DeleteObjects(
+		ctx context.Context,
+		objects []string,
+	) (DeleteResult, error)
+
+	Copy(
+		ctx context.Context,
+		sourceObject string,
+		destinationBucketName string,
+		destinationObject string,
+	) error
+}
+
+// ObjectProperties are the properties of a stored object
+type ObjectProperties struct {
+	Size                 int64             `json:"size"`
+	ETag                 string            `json:"etag"`
+	ContentMD5           string            `json:"content_md5,omitempty"`
+	CRC64                string            `json:"crc64,omitempty"`
+	ContentType          string            `json:"content_type,omitempty"`
+	LastModified         time.Time         `json:"last_modified"`
+	StorageClass         string            `json:"storage_class,omitempty"`
+	ServerSideEncryption string            `json:"server_side_encryption,omitempty"`
+	SSEKMSKeyID          string            `json:"sse_kms_key_id,omitempty"`
+	Metadata             map[string]string `json:"metadata,omitempty"`
+}
+
+// ErrNotFound is returned by Head for objects which do not exist
+var ErrNotFound = errors.New("blob not found")
+
+// ListedObject is an object returned by List
+type ListedObject struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
+	StorageClass string    `json:"storage_class"`
+}
+
+// ListResult holds the objects below a prefix and, when listing with a delimiter,
+// the common prefixes grouping the objects below the next delimiter
+type ListResult struct {
+	Objects        []ListedObject
+	CommonPrefixes []string
+}
+
+// DeleteResult holds the keys deleted by DeleteObjects and the keys which could not be deleted
+type DeleteResult struct {
+	Deleted []string
+	Failed  []DeleteFailure
+}
+
+// DeleteFailure is a key which could not be deleted
+type DeleteFailure struct {
+	Key   string `json:"key"`
+	Error string `json:"error"`
 }
 
+// maxKeysPerDeleteRequest is the maximum number of keys OSS deletes with a single DeleteObjects request
+const maxKeysPerDeleteRequest = 1000
+
+// deleteConcurrency is the number of DeleteObjects requests sent in parallel
+const deleteConcurrency = 4
+
+// maxKeysPerPage is the maximum number of keys OSS returns for a single list request
+const maxKeysPerPage = 1000
+
+const partialFileSuffix = ".partial"
+
+// abortTimeout limits the time spent aborting the multipart upload of a cancelled upload
+const abortTimeout = 30 * time.Second
+
 type DefaultStorageClient struct {
-	storageConfig config.AliStorageConfig
+	storageConfig       config.AliStorageConfig
+	credentialsProvider oss.CredentialsProvider
+	client              *oss.Client
+	bucket              *oss.Bucket
+	transport           *http.Transport
+	masterKey           MasterKey
 }
 
+// NewStorageClient validates the endpoint and bucket name and creates the OSS client and the HTTP transport shared by
+// all operations, so that subsequent calls reuse the connections of a single HTTP connection pool.
+// Operations failing with a transient error are retried according to the retry_* properties.
 func NewStorageClient(storageConfig config.AliStorageConfig) (StorageClient, error) {
-	return DefaultStorageClient{storageConfig: storageConfig}, nil
+	storageConfig.ApplyDefaults()
+
+	credentialsProvider, err := credentials.NewProvider(storageConfig)
+	if err != nil {
+		return nil, fmt.Errorf("creating credentials provider: %w", err)
+	}
+
+	var transport *http.Transport
+	client, err := oss.New(
+		storageConfig.Endpoint,
+		storageConfig.AccessKeyID,
+		storageConfig.AccessKeySecret,
+		oss.SetCredentialsProvider(credentialsProvider),
+		httpOptions(storageConfig),
+		authOptions(storageConfig),
+		// The transport is created from the HTTP options applied above, as the SDK would do for itself
+		func(client *oss.Client) {
+			transport = newTransport(client.Config)
+			client.HTTPClient = &http.Client{Transport: contextTransport{transport: transport}}
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating OSS client: %w", err)
+	}
+
+	bucket, err := client.Bucket(storageConfig.BucketName)
+	if err != nil {
+		return nil, fmt.Errorf("creating OSS bucket: %w", err)
+	}
+
+	var masterKey MasterKey
+	if storageConfig.ClientSideEncryptionKeyFile != "" {
+		masterKey, err = LoadMasterKey(storageConfig.ClientSideEncryptionKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client-side encryption key: %w", err)
+		}
+	}
+
+	storageClient := DefaultStorageClient{
+		storageConfig:       storageConfig,
+		credentialsProvider: credentialsProvider,
+		client:              client,
+		bucket:              bucket,
+		transport:           transport,
+		masterKey:           masterKey,
+	}
+
+	return NewRetryingStorageClient(storageClient, NewRetryPolicy(storageConfig)), nil
+}
+
+// transferBucket returns a handle of bucketName, or of the configured bucket if empty, for the multipart transfers
+// of the SDK, whose part requests do not take the context of the oss.WithContext option. Its client shares the
+// configuration and the transport of the storage client, but sends all requests with ctx, so that cancelling ctx
+// also cancels the part requests of UploadFile, DownloadFile and CopyFile.
+func (dsc DefaultStorageClient) transferBucket(ctx context.Context, bucketName string) (*oss.Bucket, error) {
+	if bucketName == "" {
+		bucketName = dsc.storageConfig.BucketName
+	}
+
+	clientConfig := *dsc.client.Config
+	client, err := oss.New(
+		clientConfig.Endpoint,
+		clientConfig.AccessKeyID,
+		clientConfig.AccessKeySecret,
+		func(client *oss.Client) {
+			*client.Config = clientConfig
+			client.HTTPClient = &http.Client{Transport: contextTransport{ctx: ctx, transport: dsc.transport}}
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating OSS client: %w", err)
+	}
+
+	return client.Bucket(bucketName)
+}
+
+// httpOptions overrides the HTTP timeouts and connection limits of the SDK with the configured values.
+// Properties which are not configured keep the SDK defaults.
+func httpOptions(storageConfig config.AliStorageConfig) oss.ClientOption {
+	return func(client *oss.Client) {
+		timeout := &client.Config.HTTPTimeout
+		if storageConfig.ConnectTimeoutSeconds > 0 {
+			timeout.ConnectTimeout = time.Duration(storageConfig.ConnectTimeoutSeconds) * time.Second
+		}
+		if storageConfig.ReadWriteTimeoutSeconds > 0 {
+			timeout.ReadWriteTimeout = time.Duration(storageConfig.ReadWriteTimeoutSeconds) * time.Second
+			timeout.HeaderTimeout = time.Duration(storageConfig.ReadWriteTimeoutSeconds) * time.Second
+		}
+		if storageConfig.IdleConnTimeoutSeconds > 0 {
+			timeout.IdleConnTimeout = time.Duration(storageConfig.IdleConnTimeoutSeconds) * time.Second
+		}
+
+		maxConns := &client.Config.HTTPMaxConns
+		if storageConfig.MaxIdleConns > 0 {
+			maxConns.MaxIdleConns = storageConfig.MaxIdleConns
+		}
+		if storageConfig.MaxIdleConnsPerHost > 0 {
+			maxConns.MaxIdleConnsPerHost = storageConfig.MaxIdleConnsPerHost
+		}
+		if storageConfig.MaxConnsPerHost > 0 {
+			maxConns.MaxConnsPerHost = storageConfig.MaxConnsPerHost
+		}
+	}
+}
+
+// authOptions selects the configured signature version of requests. V4 signatures are scoped to the configured region.
+func authOptions(storageConfig config.AliStorageConfig) oss.ClientOption {
+	return func(client *oss.Client) {
+		if storageConfig.AuthVersion == config.AuthVersionV4 {
+			client.Config.AuthVersion = oss.AuthV4
+			client.Config.Region = storageConfig.Region
+		}
+	}
 }
 
 func (dsc DefaultStorageClient) Upload(
+	ctx context.Context,
+	sourceFilePath string,
+	sourceFileMD5 string,
+	destinationObject string,
+) (err error) {
+	ctx, operation := dsc.startOperation(ctx, "upload", destinationObject)
+	var size int64
+	defer func() { operation.finish(err, slog.Int64(logKeyBytes, size)) }()
+
+	sourceFileInfo, err := os.Stat(sourceFilePath)
+	if err != nil {
+		return err
+	}
+	size = sourceFileInfo.Size()
+
+	if dsc.masterKey != nil {
+		return dsc.uploadEncrypted(ctx, sourceFilePath, sourceFileMD5, destinationObject)
+	}
+
+	return dsc.upload(ctx, sourceFilePath, sourceFileMD5, destinationObject, dsc.encryptionOptions(), true)
+}
+
+// uploadEncrypted encrypts sourceFilePath with a new data key into a temporary file and uploads it
+// along with the data key wrapped by the master key.
+func (dsc DefaultStorageClient) uploadEncrypted(
+	ctx context.Context,
+	sourceFilePath string,
+	sourceFileMD5 string,
+	destinationObject string,
+) error {
+	sourceFileInfo, err := os.Stat(sourceFilePath)
+	if err != nil {
+		return err
+	}
+
+	objectEnvelope, err := newEnvelope()
+	if err != nil {
+		return fmt.Errorf("creating data key: %w", err)
+	}
+
+	metadataOptions, err := objectEnvelope.metadataOptions(dsc.masterKey, sourceFileMD5, sourceFileInfo.Size())
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(dsc.storageConfig.CheckpointDir, 0700)
+	if err != nil {
+		return fmt.Errorf("creating checkpoint directory: %w", err)
+	}
+
+	encryptedFile, err := os.CreateTemp(dsc.storageConfig.CheckpointDir, "*.encrypted")
+	if err != nil {
+		return err
+	}
+	encryptedFilePath := encryptedFile.Name()
+	_ = encryptedFile.Close()
+	defer os.Remove(encryptedFilePath)
+
+	encryptedFileMD5, err := objectEnvelope.transformFile(sourceFilePath, encryptedFilePath)
+	if err != nil {
+		return fmt.Errorf("encrypting %s: %w", sourceFilePath, err)
+	}
+
+	// The encrypted file and so the checkpoint of its upload are gone once the upload fails, so it cannot be resumed
+	return dsc.upload(ctx, encryptedFilePath, encryptedFileMD5, destinationObject, append(dsc.encryptionOptions(), metadataOptions...), false)
+}
+
+// upload uploads sourceFilePath, with a multipart upload if it has at least multipart_threshold bytes. A multipart
+// upload failing after its checkpoint was written is left for a retry to resume, unless it is not resumable.
+func (dsc DefaultStorageClient) upload(
+	ctx context.Context,
 	sourceFilePath string,
 	sourceFileMD5 string,
 	destinationObject string,
+	options []oss.Option,
+	resumable bool,
 ) error {
-	log.Println(fmt.Sprintf("Uploading %s/%s", dsc.storageConfig.BucketName, destinationObject))
+	sourceFileInfo, err := os.Stat(sourceFilePath)
+	if err != nil {
+		return err
+	}
+
+	if sourceFileInfo.Size() < dsc.storageConfig.MultipartThreshold {
+		return dsc.bucket.PutObjectFromFile(
+			destinationObject,
+			sourceFilePath,
+			append(options, oss.ContentMD5(sourceFileMD5), oss.WithContext(ctx))...,
+		)
+	}
 
-	client, err := oss.New(dsc.storageConfig.Endpoint, dsc.storageConfig.AccessKeyID, dsc.storageConfig.AccessKeySecret)
+	ctx, initiated := withInitiatedUploads(ctx)
+	bucket, err := dsc.transferBucket(ctx, "")
 	if err != nil {
 		return err
 	}
 
-	bucket, err := client.Bucket(dsc.storageConfig.BucketName)
+	checkpointFilePath, err := dsc.checkpointFilePath("upload", sourceFilePath, destinationObject)
 	if err != nil {
 		return err
 	}
 
-	return bucket.PutObjectFromFile(destinationObject, sourceFilePath, oss.ContentMD5(sourceFileMD5))
+	operationLogger(ctx).Debug("using multipart upload",
+		slog.Int64("part_size", dsc.storageConfig.UploadPartSize),
+		slog.Int("concurrency", dsc.storageConfig.UploadConcurrency),
+		slog.String("checkpoint", checkpointFilePath),
+	)
+
+	// A checkpoint left behind by an interrupted upload of the same file is picked up by the SDK
+	// and only the missing parts are uploaded. The checkpoint is removed once the upload completes.
+	err = bucket.UploadFile(
+		destinationObject,
+		sourceFilePath,
+		dsc.storageConfig.UploadPartSize,
+		append(
+			options,
+			oss.Routines(dsc.storageConfig.UploadConcurrency),
+			oss.Checkpoint(true, checkpointFilePath),
+		)...,
+	)
+
+	if err != nil {
+		// A cancelled upload is not resumed, so its parts are removed instead of being left behind. Neither is an
+		// unresumable upload or one failing before the SDK wrote its checkpoint, as a retry initiates a new upload.
+		if !resumable || ctx.Err() != nil || !fileExists(checkpointFilePath) {
+			dsc.abortCancelledUpload(dsc.storageConfig.BucketName, destinationObject, checkpointFilePath, initiated.list())
+		}
+		return err
+	}
+
+	// Unlike a single request, the parts of the upload are not sent with the Content-MD5 of the whole file, so the
+	// file is compared with the CRC64 OSS calculated for the completed object instead
+	objectHeader, err := dsc.bucket.GetObjectDetailedMeta(destinationObject, oss.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("verifying uploaded object: %w", err)
+	}
+	return VerifyChecksum(sourceFilePath, objectHeader)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// abortCancelledUpload aborts the multipart upload of a cancelled or unresumable UploadFile or CopyFile and removes
+// its checkpoint. The SDK only writes the checkpoint once the first part has been transferred, so besides the upload
+// of the checkpoint the uploads initiated by the failed transfer itself are aborted.
+func (dsc DefaultStorageClient) abortCancelledUpload(
+	bucketName string,
+	destinationObject string,
+	checkpointFilePath string,
+	initiatedUploadIDs []string,
+) {
+	defer os.Remove(checkpointFilePath)
+
+	// The checkpoints of uploads and copies name the upload ID differently
+	checkpoint := struct{ UploadID, CopyID string }{}
+	contents, err := os.ReadFile(checkpointFilePath)
+	if err == nil {
+		_ = json.Unmarshal(contents, &checkpoint)
+	}
+
+	uploadIDs := initiatedUploadIDs
+	for _, uploadID := range []string{checkpoint.UploadID, checkpoint.CopyID} {
+		if uploadID != "" && !slices.Contains(uploadIDs, uploadID) {
+			uploadIDs = append(uploadIDs, uploadID)
+		}
+	}
+
+	for _, uploadID := range uploadIDs {
+		dsc.abortMultipartUpload(oss.InitiateMultipartUploadResult{
+			Bucket:   bucketName,
+			Key:      destinationObject,
+			UploadID: uploadID,
+		})
+	}
+}
+
+// abortMultipartUpload aborts a multipart upload independent of the context of the failed upload,
+// which is usually done at this point.
+func (dsc DefaultStorageClient) abortMultipartUpload(upload oss.InitiateMultipartUploadResult) {
+	ctx, cancel := context.WithTimeout(context.Background(), abortTimeout)
+	defer cancel()
+
+	bucket, err := dsc.client.Bucket(upload.Bucket)
+	if err == nil {
+		err = bucket.AbortMultipartUpload(upload, oss.WithContext(ctx))
+	}
+
+	attrs := []any{slog.String(logKeyKey, upload.Key), slog.String("upload_id", upload.UploadID)}
+	if err != nil {
+		slog.Warn("failed to abort multipart upload", append(attrs, slog.String(logKeyError, err.Error()))...)
+		return
+	}
+	slog.Info("aborted multipart upload", attrs...)
+}
+
+// encryptionOptions returns the headers requesting the configured server-side encryption for new objects.
+func (dsc DefaultStorageClient) encryptionOptions() []oss.Option {
+	var options []oss.Option
+
+	if dsc.storageConfig.ServerSideEncryption != "" {
+		options = append(options, oss.ServerSideEncryption(dsc.storageConfig.ServerSideEncryption))
+	}
+	if dsc.storageConfig.SSEKMSKeyID != "" {
+		options = append(options, oss.ServerSideEncryptionKeyID(dsc.storageConfig.SSEKMSKeyID))
+	}
+
+	return options
+}
+
+// checkpointFilePath returns a stable checkpoint location for transferring localFilePath from or to object,
+// so that a rerun of the same transfer resumes from the previous checkpoint.
+func (dsc DefaultStorageClient) checkpointFilePath(transfer string, localFilePath string, object string) (string, error) {
+	absoluteLocalFilePath, err := filepath.Abs(localFilePath)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(dsc.storageConfig.CheckpointDir, 0700)
+	if err != nil {
+		return "", fmt.Errorf("creating checkpoint directory: %w", err)
+	}
+
+	hash := md5.Sum([]byte(fmt.Sprintf("%s\noss://%s/%s", absoluteLocalFilePath, dsc.storageConfig.BucketName, object)))
+
+	return filepath.Join(dsc.storageConfig.CheckpointDir, hex.EncodeToString(hash[:])+"."+transfer+".cp"), nil
+}
+
+// copyCheckpointFilePath returns the path of the checkpoint file of a multipart copy of sourceObject to
+// destinationObject in destinationBucketName.
+func (dsc DefaultStorageClient) copyCheckpointFilePath(sourceObject string, destinationBucketName string, destinationObject string) (string, error) {
+	err := os.MkdirAll(dsc.storageConfig.CheckpointDir, 0700)
+	if err != nil {
+		return "", fmt.Errorf("creating checkpoint directory: %w", err)
+	}
+
+	hash := md5.Sum([]byte(fmt.Sprintf("oss://%s/%s\noss://%s/%s", dsc.storageConfig.BucketName, sourceObject, destinationBucketName, destinationObject)))
+
+	return filepath.Join(dsc.storageConfig.CheckpointDir, hex.EncodeToString(hash[:])+".copy.cp"), nil
 }
 
 func (dsc DefaultStorageClient) Download(
+	ctx context.Context,
 	sourceObject string,
 	destinationFilePath string,
-) error {
-	log.Println(fmt.Sprintf("Downloading %s/%s", dsc.storageConfig.BucketName, sourceObject))
+) (err error) {
+	ctx, operation := dsc.startOperation(ctx, "download", sourceObject)
+	var size int64
+	defer func() { operation.finish(err, slog.Int64(logKeyBytes, size)) }()
+
+	bucket, err := dsc.transferBucket(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	objectHeader, err := bucket.GetObjectDetailedMeta(sourceObject)
+	if err != nil {
+		return err
+	}
+	size, _ = strconv.ParseInt(objectHeader.Get(oss.HTTPHeaderContentLength), 10, 64)
+
+	objectEnvelope, err := dsc.objectEnvelope(sourceObject, objectHeader)
+	if err != nil {
+		return err
+	}
 
-	client, err := oss.New(dsc.storageConfig.Endpoint, dsc.storageConfig.AccessKeyID, dsc.storageConfig.AccessKeySecret)
+	checkpointFilePath, err := dsc.checkpointFilePath("download", destinationFilePath, sourceObject)
 	if err != nil {
 		return err
 	}
 
-	bucket, err := client.Bucket(dsc.storageConfig.BucketName)
+	// The byte ranges are downloaded into a partial file next to destinationFilePath, which only
+	// replaces destinationFilePath after the whole object is downloaded and its checksum verified.
+	// A rerun of an interrupted download continues with the ranges missing in the checkpoint.
+	partialFilePath := destinationFilePath + partialFileSuffix
+	err = bucket.DownloadFile(
+		sourceObject,
+		partialFilePath,
+		dsc.storageConfig.DownloadPartSize,
+		oss.Routines(dsc.storageConfig.DownloadConcurrency),
+		oss.Checkpoint(true, checkpointFilePath),
+		oss.IfMatch(objectHeader.Get(oss.HTTPHeaderEtag)),
+	)
+
+	// A cancelled download is not resumed, so the partial files are removed instead of being left behind.
+	// Neither is a corrupted download, whose checkpoint records the corrupted byte ranges as downloaded.
+	var crcError oss.CRCCheckError
+	if err != nil && (ctx.Err() != nil || errors.As(err, &crcError)) {
+		_ = os.Remove(partialFilePath + oss.TempFileSuffix)
+		_ = os.Remove(partialFilePath)
+		_ = os.Remove(checkpointFilePath)
+	}
 	if err != nil {
 		return err
 	}
 
-	return bucket.GetObjectToFile(sourceObject, destinationFilePath)
+	err = VerifyChecksum(partialFilePath, objectHeader)
+	if err != nil {
+		_ = os.Remove(partialFilePath)
+		return err
+	}
+
+	if objectEnvelope != nil {
+		return dsc.decrypt(*objectEnvelope, objectHeader, partialFilePath, destinationFilePath)
+	}
+
+	return os.Rename(partialFilePath, destinationFilePath)
 }
 
-func (dsc DefaultStorageClient) Delete(
-	object string,
+// decrypt replaces the downloaded encryptedFilePath by its decrypted content at destinationFilePath,
+// verifying the MD5 of the unencrypted content recorded on upload.
+func (dsc DefaultStorageClient) decrypt(
+	objectEnvelope envelope,
+	objectHeader http.Header,
+	encryptedFilePath string,
+	destinationFilePath string,
 ) error {
-	log.Println(fmt.Sprintf("Deleting %s/%s", dsc.storageConfig.BucketName, object))
+	defer os.Remove(encryptedFilePath)
 
-	client, err := oss.New(dsc.storageConfig.Endpoint, dsc.storageConfig.AccessKeyID, dsc.storageConfig.AccessKeySecret)
+	decryptedFilePath := destinationFilePath + partialFileSuffix + ".decrypted"
+	decryptedFileMD5, err := objectEnvelope.transformFile(encryptedFilePath, decryptedFilePath)
 	if err != nil {
-		return err
+		_ = os.Remove(decryptedFilePath)
+		return fmt.Errorf("decrypting %s: %w", encryptedFilePath, err)
 	}
 
-	bucket, err := client.Bucket(dsc.storageConfig.BucketName)
+	err = verifyDecryptedMD5(objectHeader, decryptedFileMD5)
 	if err != nil {
+		_ = os.Remove(decryptedFilePath)
 		return err
 	}
 
-	return bucket.DeleteObject(object)
+	return os.Rename(decryptedFilePath, destinationFilePath)
 }
 
-func (dsc DefaultStorageClient) Exists(object string) (bool, error) {
-	log.Println(fmt.Sprintf("Checking if blob: %s/%s", dsc.storageConfig.BucketName, object))
+// objectEnvelope returns the unwrapped envelope of a client-side encrypted object, or nil for other objects.
+// Objects without an envelope are downloaded as they are, even if a master key is configured.
+func (dsc DefaultStorageClient) objectEnvelope(object string, objectHeader http.Header) (*envelope, error) {
+	if !isClientSideEncrypted(objectHeader) {
+		return nil, nil
+	}
 
-	client, err := oss.New(dsc.storageConfig.Endpoint, dsc.storageConfig.AccessKeyID, dsc.storageConfig.AccessKeySecret)
+	opened, err := openEnvelope(objectHeader, dsc.masterKey)
 	if err != nil {
-		return false, err
+		return nil, fmt.Errorf("decrypting %s: %w", object, err)
 	}
+	return &opened, nil
+}
+
+func (dsc DefaultStorageClient) Delete(
+	ctx context.Context,
+	object string,
+) (err error) {
+	ctx, operation := dsc.startOperation(ctx, "delete", object)
+	defer func() { operation.finish(err) }()
+
+	return dsc.bucket.DeleteObject(object, oss.WithContext(ctx))
+}
+
+func (dsc DefaultStorageClient) Exists(ctx context.Context, object string) (exists bool, err error) {
+	ctx, operation := dsc.startOperation(ctx, "exists", object)
+	defer func() { operation.finish(err, slog.Bool("exists", exists)) }()
+
+	return dsc.bucket.IsObjectExist(object, oss.WithContext(ctx))
+}
+
+func (dsc DefaultStorageClient) Head(
+	ctx context.Context,
+	object string,
+) (properties ObjectProperties, err error) {
+	ctx, operation := dsc.startOperation(ctx, "head", object)
+	defer func() { operation.finish(err, slog.Int64(logKeyBytes, properties.Size)) }()
 
-	bucket, err := client.Bucket(dsc.storageConfig.BucketName)
+	header, err := dsc.bucket.GetObjectDetailedMeta(object, oss.WithContext(ctx))
 	if err != nil {
-		return false, err
+		var serviceError oss.ServiceError
+		if errors.As(err, &serviceError) && serviceError.StatusCode == http.StatusNotFound {
+			return ObjectProperties{}, fmt.Errorf("%w: %s/%s", ErrNotFound, dsc.storageConfig.BucketName, object)
+		}
+		return ObjectProperties{}, err
 	}
 
-	objectExists, err := bucket.IsObjectExist(object)
+	return objectProperties(header)
+}
+
+// objectProperties parses the properties of an object from the headers of a HEAD response
+func objectProperties(header http.Header) (ObjectProperties, error) {
+	size, err := strconv.ParseInt(header.Get(oss.HTTPHeaderContentLength), 10, 64)
 	if err != nil {
-		return false, err
+		return ObjectProperties{}, fmt.Errorf("parsing size of blob: %w", err)
+	}
+
+	var lastModified time.Time
+	if value := header.Get(oss.HTTPHeaderLastModified); value != "" {
+		lastModified, err = http.ParseTime(value)
+		if err != nil {
+			return ObjectProperties{}, fmt.Errorf("parsing last modification of blob: %w", err)
+		}
 	}
 
-	if objectExists {
-		log.Printf("File '%s' exists in bucket '%s'\n", object, dsc.storageConfig.BucketName)
-		return true, nil
-	} else {
-		log.Printf("File '%s' does not exist in bucket '%s'\n", object, dsc.storageConfig.BucketName)
-		return false, nil
+	var metadata map[string]string
+	for name := range header {
+		if strings.HasPrefix(name, oss.HTTPHeaderOssMetaPrefix) {
+			if metadata == nil {
+				metadata = map[string]string{}
+			}
+			metadata[strings.ToLower(strings.TrimPrefix(name, oss.HTTPHeaderOssMetaPrefix))] = header.Get(name)
+		}
 	}
+
+	return ObjectProperties{
+		Size:                 size,
+		ETag:                 strings.Trim(header.Get(oss.HTTPHeaderEtag), `"`),
+		ContentMD5:           header.Get(oss.HTTPHeaderContentMD5),
+		CRC64:                header.Get(oss.HTTPHeaderOssCRC64),
+		ContentType:          header.Get(oss.HTTPHeaderContentType),
+		LastModified:         lastModified,
+		StorageClass:         header.Get(oss.HTTPHeaderOssStorageClass),
+		ServerSideEncryption: header.Get(oss.HTTPHeaderOssServerSideEncryption),
+		SSEKMSKeyID:          header.Get(oss.HTTPHeaderOssServerSideEncryptionKeyID),
+		Metadata:             metadata,
+	}, nil
 }
 
-func (dsc DefaultStorageClient) SignedUrlPut(
-	object string,
-	expiredInSec int64,
-) (string, error) {
+// List pages through all objects below prefix. With a delimiter, keys containing the delimiter after the prefix
+// are grouped into common prefixes. A positive maxKeys limits the total number of returned objects and prefixes.
+func (dsc DefaultStorageClient) List(
+	ctx context.Context,
+	prefix string,
+	delimiter string,
+	maxKeys int,
+) (result ListResult, err error) {
+	ctx, operation := dsc.startOperation(ctx, "list", prefix)
+	defer func() {
+		operation.finish(err, slog.Int("objects", len(result.Objects)), slog.Int("prefixes", len(result.CommonPrefixes)))
+	}()
+
+	continuationToken := ""
+
+	for {
+		pageSize := maxKeysPerPage
+		if maxKeys > 0 {
+			pageSize = min(pageSize, maxKeys-len(result.Objects)-len(result.CommonPrefixes))
+		}
+
+		options := []oss.Option{oss.Prefix(prefix), oss.MaxKeys(pageSize), oss.WithContext(ctx)}
+		if delimiter != "" {
+			options = append(options, oss.Delimiter(delimiter))
+		}
+		if continuationToken != "" {
+			options = append(options, oss.ContinuationToken(continuationToken))
+		}
+
+		page, err := dsc.bucket.ListObjectsV2(options...)
+		if err != nil {
+			return ListResult{}, err
+		}
+
+		for _, object := range page.Objects {
+			result.Objects = append(result.Objects, ListedObject{
+				Key:          object.Key,
+				Size:         object.Size,
+				ETag:         strings.Trim(object.ETag, `"`),
+				LastModified: object.LastModified,
+				StorageClass: object.StorageClass,
+			})
+		}
+		result.CommonPrefixes = append(result.CommonPrefixes, page.CommonPrefixes...)
+
+		if !page.IsTruncated || (maxKeys > 0 && len(result.Objects)+len(result.CommonPrefixes) >= maxKeys) {
+			return result, nil
+		}
+		continuationToken = page.NextContinuationToken
+	}
+}
 
-	log.Println(fmt.Sprintf("Getting signed PUT url for blob %s/%s", dsc.storageConfig.BucketName, object))
+// ListPage lists a single page of up to 1000 objects below prefix, starting with the page of continuationToken,
+// or with the first page if empty. The returned continuation token of the next page is empty after the last page.
+func (dsc DefaultStorageClient) ListPage(
+	ctx context.Context,
+	prefix string,
+	continuationToken string,
+) (result ListResult, nextContinuationToken string, err error) {
+	ctx, operation := dsc.startOperation(ctx, "list_page", prefix)
+	defer func() { operation.finish(err, slog.Int("objects", len(result.Objects))) }()
+
+	options := []oss.Option{oss.Prefix(prefix), oss.MaxKeys(maxKeysPerPage), oss.WithContext(ctx)}
+	if continuationToken != "" {
+		options = append(options, oss.ContinuationToken(continuationToken))
+	}
 
-	client, err := oss.New(dsc.storageConfig.Endpoint, dsc.storageConfig.AccessKeyID, dsc.storageConfig.AccessKeySecret)
+	page, err := dsc.bucket.ListObjectsV2(options...)
 	if err != nil {
-		return "", err
+		return ListResult{}, "", err
+	}
+
+	for _, object := range page.Objects {
+		result.Objects = append(result.Objects, ListedObject{
+			Key:          object.Key,
+			Size:         object.Size,
+			ETag:         strings.Trim(object.ETag, `"`),
+			LastModified: object.LastModified,
+			StorageClass: object.StorageClass,
+		})
+	}
+
+	if page.IsTruncated {
+		nextContinuationToken = page.NextContinuationToken
+	}
+	return result, nextContinuationToken, nil
+}
+
+// DeleteObjects deletes objects with batch DeleteObjects requests of up to 1000 keys, sent in parallel.
+// Keys which OSS does not report as deleted, or whose request failed, are returned as failures
+// instead of an error, so that a single failed batch does not hide the outcome of the others. A batch failing
+// with a transient error or cancelled is returned as error as well, so that it can be retried and classified.
+func (dsc DefaultStorageClient) DeleteObjects(
+	ctx context.Context,
+	objects []string,
+) (result DeleteResult, err error) {
+	ctx, operation := dsc.startOperation(ctx, "delete_objects", "")
+	defer func() {
+		operation.finish(err, slog.Int("deleted", len(result.Deleted)), slog.Int("failed", len(result.Failed)))
+	}()
+
+	var chunks [][]string
+	for start := 0; start < len(objects); start += maxKeysPerDeleteRequest {
+		chunks = append(chunks, objects[start:min(start+maxKeysPerDeleteRequest, len(objects))])
+	}
+
+	results := make([]DeleteResult, len(chunks))
+	errs := make([]error, len(chunks))
+	semaphore := make(chan struct{}, deleteConcurrency)
+	var wg sync.WaitGroup
+
+	for i, chunk := range chunks {
+		wg.Add(1)
+		semaphore <- struct{}{}
+
+		go func(i int, chunk []string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			results[i], errs[i] = deleteChunk(ctx, dsc.bucket, chunk)
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	for i, chunkResult := range results {
+		result.Deleted = append(result.Deleted, chunkResult.Deleted...)
+		result.Failed = append(result.Failed, chunkResult.Failed...)
+		if err == nil {
+			err = errs[i]
+		}
 	}
 
-	bucket, err := client.Bucket(dsc.storageConfig.BucketName)
+	return result, err
+}
+
+// deleteChunk deletes a batch of objects. If the request fails, all objects are reported as failures, and the error
+// is returned as well if it is transient or caused by a cancellation.
+func deleteChunk(ctx context.Context, bucket *oss.Bucket, objects []string) (DeleteResult, error) {
+	result := DeleteResult{}
+
+	deleted, err := bucket.DeleteObjects(objects, oss.WithContext(ctx))
 	if err != nil {
-		return "", err
+		for _, object := range objects {
+			result.Failed = append(result.Failed, DeleteFailure{Key: object, Error: err.Error()})
+		}
+		if IsCancelled(err) || IsTransient(err) {
+			return result, err
+		}
+		return result, nil
 	}
 
-	return bucket.SignURL(object, oss.HTTPPut, expiredInSec)
+	deletedObjects := make(map[string]bool, len(deleted.DeletedObjects))
+	for _, object := range deleted.DeletedObjects {
+		deletedObjects[object] = true
+	}
+
+	for _, object := range objects {
+		if deletedObjects[object] {
+			result.Deleted = append(result.Deleted, object)
+		} else {
+			result.Failed = append(result.Failed, DeleteFailure{Key: object, Error: "not reported as deleted"})
+		}
+	}
+
+	return result, nil
 }
 
-func (dsc DefaultStorageClient) SignedUrlGet(
-	object string,
-	expiredInSec int64,
-) (string, error) {
+// Copy copies sourceObject server-side to destinationObject in destinationBucketName, or in the configured bucket
+// if destinationBucketName is empty. Objects of at least multipart_threshold bytes are copied with a multipart copy.
+// The content type and user metadata of sourceObject are preserved.
+func (dsc DefaultStorageClient) Copy(
+	ctx context.Context,
+	sourceObject string,
+	destinationBucketName string,
+	destinationObject string,
+) (err error) {
+	if destinationBucketName == "" {
+		destinationBucketName = dsc.storageConfig.BucketName
+	}
 
-	log.Println(fmt.Sprintf("Getting signed GET url for blob %s/%s", dsc.storageConfig.BucketName, object))
+	ctx, operation := dsc.startOperation(ctx, "copy", sourceObject)
+	var size int64
+	defer func() {
+		operation.finish(err,
+			slog.String("destination_bucket", destinationBucketName),
+			slog.String("destination_key", destinationObject),
+			slog.Int64(logKeyBytes, size),
+		)
+	}()
+
+	// Besides being pointless, a copy onto itself would make a move delete the blob
+	if destinationBucketName == dsc.storageConfig.BucketName && destinationObject == sourceObject {
+		return fmt.Errorf("source and destination of the copy are the same blob: %s", sourceObject)
+	}
 
-	client, err := oss.New(dsc.storageConfig.Endpoint, dsc.storageConfig.AccessKeyID, dsc.storageConfig.AccessKeySecret)
+	objectHeader, err := dsc.bucket.GetObjectDetailedMeta(sourceObject, oss.WithContext(ctx))
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	bucket, err := client.Bucket(dsc.storageConfig.BucketName)
+	size, err = strconv.ParseInt(objectHeader.Get(oss.HTTPHeaderContentLength), 10, 64)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("parsing size of blob: %w", err)
+	}
+
+	if size < dsc.storageConfig.MultipartThreshold {
+		// A single copy request keeps the metadata of the source object
+		_, err = dsc.bucket.CopyObjectTo(
+			destinationBucketName,
+			destinationObject,
+			sourceObject,
+			append(dsc.encryptionOptions(), oss.CopySourceIfMatch(objectHeader.Get(oss.HTTPHeaderEtag)), oss.WithContext(ctx))...,
+		)
+		return err
+	}
+
+	ctx, initiated := withInitiatedUploads(ctx)
+	destinationBucket, err := dsc.transferBucket(ctx, destinationBucketName)
+	if err != nil {
+		return fmt.Errorf("creating OSS bucket: %w", err)
+	}
+
+	checkpointFilePath, err := dsc.copyCheckpointFilePath(sourceObject, destinationBucketName, destinationObject)
+	if err != nil {
+		return err
+	}
+
+	operationLogger(ctx).Debug("using multipart copy",
+		slog.Int64("part_size", dsc.storageConfig.UploadPartSize),
+		slog.Int("concurrency", dsc.storageConfig.UploadConcurrency),
+		slog.String("checkpoint", checkpointFilePath),
+	)
+
+	// A multipart copy creates a new object, so the metadata of the source object is passed along explicitly
+	err = destinationBucket.CopyFile(
+		dsc.storageConfig.BucketName,
+		sourceObject,
+		destinationObject,
+		dsc.storageConfig.UploadPartSize,
+		append(
+			append(dsc.encryptionOptions(), copiedMetadataOptions(objectHeader)...),
+			oss.Routines(dsc.storageConfig.UploadConcurrency),
+			oss.Checkpoint(true, checkpointFilePath),
+		)...,
+	)
+
+	// Like a multipart upload, a cancelled copy or one failing before its checkpoint was written is aborted
+	if err != nil && (ctx.Err() != nil || !fileExists(checkpointFilePath)) {
+		dsc.abortCancelledUpload(destinationBucketName, destinationObject, checkpointFilePath, initiated.list())
+	}
+
+	return err
+}
+
+// copiedMetadataOptions returns the content headers and user metadata of an object as options for a new object.
+func copiedMetadataOptions(objectHeader http.Header) []oss.Option {
+	var options []oss.Option
+
+	contentHeaders := map[string]func(string) oss.Option{
+		oss.HTTPHeaderContentType:        oss.ContentType,
+		oss.HTTPHeaderCacheControl:       oss.CacheControl,
+		oss.HTTPHeaderContentDisposition: oss.ContentDisposition,
+		oss.HTTPHeaderContentEncoding:    oss.ContentEncoding,
+		oss.HTTPHeaderContentLanguage:    oss.ContentLanguage,
+	}
+	for header, option := range contentHeaders {
+		if value := objectHeader.Get(header); value != "" {
+			options = append(options, option(value))
+		}
+	}
+
+	for header := range objectHeader {
+		if strings.HasPrefix(header, oss.HTTPHeaderOssMetaPrefix) {
+			options = append(options, oss.Meta(strings.TrimPrefix(header, oss.HTTPHeaderOssMetaPrefix), objectHeader.Get(header)))
+		}
 	}
 
-	return bucket.SignURL(object, oss.HTTPGet, expiredInSec)
+	return options
 }
@@ -0,0 +1,707 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash/crc64"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing/iotest"
+	"time"
+
+	"github.com/cloudfoundry/bosh-ali-storage-cli/client"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewStorageClient", func() {
+	var storageConfig config.AliStorageConfig
+
+	BeforeEach(func() {
+		storageConfig = config.AliStorageConfig{
+			AccessKeyID:     "foo_access_key_id",
+			AccessKeySecret: "foo_access_key_secret",
+			Endpoint:        "oss-cn-hangzhou.aliyuncs.com",
+			BucketName:      "foo-bucket-name",
+		}
+	})
+
+	It("creates a storage client without contacting OSS", func() {
+		storageConfig.ConnectTimeoutSeconds = 5
+		storageConfig.MaxConnsPerHost = 10
+
+		storageClient, err := client.NewStorageClient(storageConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(storageClient).ToNot(BeNil())
+	})
+
+	It("fails eagerly for an invalid bucket name", func() {
+		storageConfig.BucketName = "Invalid_Bucket"
+
+		_, err := client.NewStorageClient(storageConfig)
+		Expect(err).To(MatchError(ContainSubstring("creating OSS bucket")))
+	})
+
+	It("fails eagerly for an invalid endpoint", func() {
+		storageConfig.Endpoint = "http://[::1"
+
+		_, err := client.NewStorageClient(storageConfig)
+		Expect(err).To(MatchError(ContainSubstring("creating OSS client")))
+	})
+})
+
+var _ = Describe("DefaultStorageClient", func() {
+	var (
+		server        *httptest.Server
+		storageClient client.StorageClient
+		handler       http.HandlerFunc
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(w, r)
+		}))
+
+		var err error
+		storageClient, err = client.NewStorageClient(config.AliStorageConfig{
+			AccessKeyID:     "foo_access_key_id",
+			AccessKeySecret: "foo_access_key_secret",
+			Endpoint:        server.URL,
+			BucketName:      "foo-bucket-name",
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Head", func() {
+		It("returns the properties and user metadata of the blob", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.Method).To(Equal(http.MethodHead))
+				Expect(r.URL.Path).To(Equal("/foo-bucket-name/blob"))
+
+				w.Header().Set("Content-Length", "3")
+				w.Header().Set("ETag", `"ACBD18DB4CC2F85CEDEF654FCCC4A4D8"`)
+				w.Header().Set("Content-MD5", "rL0Y20zC+Fzt72VPzMSk2A==")
+				w.Header().Set("Content-Type", "text/plain")
+				w.Header().Set("Last-Modified", "Tue, 06 Oct 2026 10:00:00 GMT")
+				w.Header().Set("X-Oss-Hash-Crc64ecma", "3231342946509354535")
+				w.Header().Set("X-Oss-Storage-Class", "Standard")
+				w.Header().Set("X-Oss-Server-Side-Encryption", "KMS")
+				w.Header().Set("X-Oss-Server-Side-Encryption-Key-Id", "foo-key-id")
+				w.Header().Set("X-Oss-Meta-Owner", "bosh")
+				w.WriteHeader(http.StatusOK)
+			}
+
+			properties, err := storageClient.Head(context.Background(), "blob")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(properties).To(Equal(client.ObjectProperties{
+				Size:                 3,
+				ETag:                 "ACBD18DB4CC2F85CEDEF654FCCC4A4D8",
+				ContentMD5:           "rL0Y20zC+Fzt72VPzMSk2A==",
+				CRC64:                "3231342946509354535",
+				ContentType:          "text/plain",
+				LastModified:         time.Date(2026, 10, 6, 10, 0, 0, 0, time.UTC),
+				StorageClass:         "Standard",
+				ServerSideEncryption: "KMS",
+				SSEKMSKeyID:          "foo-key-id",
+				Metadata:             map[string]string{"owner": "bosh"},
+			}))
+		})
+
+		It("returns ErrNotFound for a missing blob", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			}
+
+			_, err := storageClient.Head(context.Background(), "blob")
+			Expect(errors.Is(err, client.ErrNotFound)).To(BeTrue())
+		})
+	})
+
+	Describe("Copy", func() {
+		It("refuses to copy or move a blob onto itself, also with the configured bucket given explicitly", func() {
+			var requests []string
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.Path)
+			}
+
+			for _, destinationBucketName := range []string{"", "foo-bucket-name"} {
+				err := storageClient.Copy(context.Background(), "blob", destinationBucketName, "blob")
+				Expect(err).To(MatchError("source and destination of the copy are the same blob: blob"))
+
+				aliBlobstore, _ := client.New(storageClient)
+				err = aliBlobstore.Move(context.Background(), "blob", destinationBucketName, "blob")
+				Expect(err).To(MatchError("source and destination of the copy are the same blob: blob"))
+			}
+			Expect(requests).To(BeEmpty())
+		})
+	})
+
+	It("applies the configured read/write timeout to the requests of every operation", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(3 * time.Second):
+			}
+		}
+
+		var err error
+		storageClient, err = client.NewStorageClient(config.AliStorageConfig{
+			AccessKeyID:             "foo_access_key_id",
+			AccessKeySecret:         "foo_access_key_secret",
+			Endpoint:                server.URL,
+			BucketName:              "foo-bucket-name",
+			ReadWriteTimeoutSeconds: 1,
+			RetryMaxAttempts:        1,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		start := time.Now()
+		_, err = storageClient.Head(context.Background(), "blob")
+		Expect(err).To(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", 3*time.Second))
+	})
+
+	Describe("logging", func() {
+		var records *bytes.Buffer
+
+		BeforeEach(func() {
+			records = &bytes.Buffer{}
+			defaultLogger := slog.Default()
+			slog.SetDefault(slog.New(slog.NewJSONHandler(records, &slog.HandlerOptions{Level: slog.LevelDebug})))
+			DeferCleanup(slog.SetDefault, defaultLogger)
+		})
+
+		logRecords := func() []map[string]any {
+			var entries []map[string]any
+			decoder := json.NewDecoder(records)
+			for decoder.More() {
+				var entry map[string]any
+				Expect(decoder.Decode(&entry)).To(Succeed())
+				entries = append(entries, entry)
+			}
+			return entries
+		}
+
+		It("logs the outcome of an operation with its request ID", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Oss-Request-Id", "foo-request-id")
+				w.WriteHeader(http.StatusOK)
+			}
+
+			exists, err := storageClient.Exists(context.Background(), "blob")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeTrue())
+
+			Expect(logRecords()).To(ContainElement(SatisfyAll(
+				HaveKeyWithValue("level", "INFO"),
+				HaveKeyWithValue("msg", "operation completed"),
+				HaveKeyWithValue("operation", "exists"),
+				HaveKeyWithValue("bucket", "foo-bucket-name"),
+				HaveKeyWithValue("key", "blob"),
+				HaveKeyWithValue("exists", true),
+				HaveKeyWithValue("request_id", "foo-request-id"),
+				HaveKey("duration"),
+			)))
+		})
+
+		It("logs failed operations with the request ID of the OSS error", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Oss-Request-Id", "foo-request-id")
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = io.WriteString(w, "<Error><Code>AccessDenied</Code><RequestId>foo-request-id</RequestId></Error>")
+			}
+
+			err := storageClient.Delete(context.Background(), "blob")
+			Expect(err).To(HaveOccurred())
+
+			Expect(logRecords()).To(ContainElement(SatisfyAll(
+				HaveKeyWithValue("level", "WARN"),
+				HaveKeyWithValue("msg", "operation failed"),
+				HaveKeyWithValue("operation", "delete"),
+				HaveKeyWithValue("request_id", "foo-request-id"),
+				HaveKeyWithValue("error", ContainSubstring("AccessDenied")),
+			)))
+		})
+	})
+
+	Describe("cancellation", func() {
+		var (
+			checkpointDir string
+			ctx           context.Context
+			cancel        context.CancelFunc
+		)
+
+		BeforeEach(func() {
+			checkpointDir = GinkgoT().TempDir()
+			ctx, cancel = context.WithCancel(context.Background())
+
+			var err error
+			storageClient, err = client.NewStorageClient(config.AliStorageConfig{
+				AccessKeyID:        "foo_access_key_id",
+				AccessKeySecret:    "foo_access_key_secret",
+				Endpoint:           server.URL,
+				BucketName:         "foo-bucket-name",
+				MultipartThreshold: 100 * 1024,
+				UploadPartSize:     100 * 1024,
+				DownloadPartSize:   100 * 1024,
+				CheckpointDir:      checkpointDir,
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			cancel()
+		})
+
+		// interrupt cancels ctx while a part request is in flight
+		interrupt := func(w http.ResponseWriter, r *http.Request) {
+			cancel()
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		It("aborts the multipart upload and removes its checkpoint", func() {
+			var abortedUploadIDs []string
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				switch {
+				case r.Method == http.MethodPost && r.URL.Query().Has("uploads"):
+					_, _ = w.Write([]byte(`<InitiateMultipartUploadResult><Bucket>foo-bucket-name</Bucket><Key>blob</Key><UploadId>foo-upload-id</UploadId></InitiateMultipartUploadResult>`))
+				case r.Method == http.MethodGet && r.URL.Query().Has("uploads"):
+					// The uploads of the key may belong to other writers
+					Fail("listed the multipart uploads of the key")
+				case r.Method == http.MethodPut:
+					interrupt(w, r)
+				case r.Method == http.MethodDelete:
+					abortedUploadIDs = append(abortedUploadIDs, r.URL.Query().Get("uploadId"))
+					w.WriteHeader(http.StatusNoContent)
+				}
+			}
+
+			sourceFilePath := filepath.Join(GinkgoT().TempDir(), "source")
+			Expect(os.WriteFile(sourceFilePath, bytes.Repeat([]byte("a"), 200*1024), 0600)).To(Succeed())
+
+			err := storageClient.Upload(ctx, sourceFilePath, "", "blob")
+			Expect(err).To(HaveOccurred())
+			Expect(abortedUploadIDs).To(Equal([]string{"foo-upload-id"}))
+			Expect(os.ReadDir(checkpointDir)).To(BeEmpty())
+		})
+
+		It("aborts the multipart upload of an upload failing before its checkpoint is written", func() {
+			var abortedUploadIDs []string
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost && r.URL.Query().Has("uploads"):
+					_, _ = w.Write([]byte(`<InitiateMultipartUploadResult><Bucket>foo-bucket-name</Bucket><Key>blob</Key><UploadId>foo-upload-id</UploadId></InitiateMultipartUploadResult>`))
+				case r.Method == http.MethodPut:
+					w.WriteHeader(http.StatusForbidden)
+				case r.Method == http.MethodDelete:
+					abortedUploadIDs = append(abortedUploadIDs, r.URL.Query().Get("uploadId"))
+					w.WriteHeader(http.StatusNoContent)
+				}
+			}
+
+			sourceFilePath := filepath.Join(GinkgoT().TempDir(), "source")
+			Expect(os.WriteFile(sourceFilePath, bytes.Repeat([]byte("a"), 200*1024), 0600)).To(Succeed())
+
+			err := storageClient.Upload(context.Background(), sourceFilePath, "", "blob")
+			Expect(err).To(HaveOccurred())
+			Expect(abortedUploadIDs).To(Equal([]string{"foo-upload-id"}))
+			Expect(os.ReadDir(checkpointDir)).To(BeEmpty())
+		})
+
+		It("aborts a failed multipart upload of an encrypted file, which cannot be resumed", func() {
+			var abortedUploadIDs []string
+			var parts int
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost && r.URL.Query().Has("uploads"):
+					_, _ = w.Write([]byte(`<InitiateMultipartUploadResult><Bucket>foo-bucket-name</Bucket><Key>blob</Key><UploadId>foo-upload-id</UploadId></InitiateMultipartUploadResult>`))
+				case r.Method == http.MethodPut:
+					_, _ = io.Copy(io.Discard, r.Body)
+					parts++
+					if parts > 1 {
+						w.WriteHeader(http.StatusForbidden)
+						return
+					}
+					w.Header().Set("ETag", `"foo-part-etag"`)
+				case r.Method == http.MethodDelete:
+					abortedUploadIDs = append(abortedUploadIDs, r.URL.Query().Get("uploadId"))
+					w.WriteHeader(http.StatusNoContent)
+				}
+			}
+
+			keyFilePath := filepath.Join(GinkgoT().TempDir(), "master.key")
+			Expect(os.WriteFile(keyFilePath, []byte(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("k"), 32))), 0600)).To(Succeed())
+
+			encryptingClient, err := client.NewStorageClient(config.AliStorageConfig{
+				AccessKeyID:                 "foo_access_key_id",
+				AccessKeySecret:             "foo_access_key_secret",
+				Endpoint:                    server.URL,
+				BucketName:                  "foo-bucket-name",
+				MultipartThreshold:          100 * 1024,
+				UploadPartSize:              100 * 1024,
+				UploadConcurrency:           1,
+				CheckpointDir:               checkpointDir,
+				ClientSideEncryptionKeyFile: keyFilePath,
+				RetryMaxAttempts:            1,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			sourceFilePath := filepath.Join(GinkgoT().TempDir(), "source")
+			Expect(os.WriteFile(sourceFilePath, bytes.Repeat([]byte("a"), 300*1024), 0600)).To(Succeed())
+
+			err = encryptingClient.Upload(context.Background(), sourceFilePath, "", "blob")
+			Expect(err).To(HaveOccurred())
+			Expect(parts).To(BeNumerically(">", 1))
+			Expect(abortedUploadIDs).To(Equal([]string{"foo-upload-id"}))
+			Expect(os.ReadDir(checkpointDir)).To(BeEmpty())
+		})
+
+		It("aborts the multipart upload of a cancelled multipart copy and removes its checkpoint", func() {
+			var abortedUploadIDs []string
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodHead:
+					w.Header().Set("Content-Length", strconv.Itoa(200*1024))
+					w.Header().Set("ETag", `"foo-etag"`)
+				case r.Method == http.MethodPost && r.URL.Query().Has("uploads"):
+					_, _ = w.Write([]byte(`<InitiateMultipartUploadResult><Bucket>foo-bucket-name</Bucket><Key>destination</Key><UploadId>foo-upload-id</UploadId></InitiateMultipartUploadResult>`))
+				case r.Method == http.MethodPut:
+					interrupt(w, r)
+				case r.Method == http.MethodDelete:
+					abortedUploadIDs = append(abortedUploadIDs, r.URL.Query().Get("uploadId"))
+					w.WriteHeader(http.StatusNoContent)
+				}
+			}
+
+			err := storageClient.Copy(ctx, "blob", "", "destination")
+			Expect(err).To(HaveOccurred())
+			Expect(abortedUploadIDs).To(Equal([]string{"foo-upload-id"}))
+			Expect(os.ReadDir(checkpointDir)).To(BeEmpty())
+		})
+
+		It("returns the cancellation of a batch delete as error", func() {
+			cancel()
+
+			result, err := storageClient.DeleteObjects(ctx, []string{"a", "b"})
+			Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+			Expect(result.Failed).To(HaveLen(2))
+		})
+
+		It("removes the partial download", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodHead {
+					w.Header().Set("Content-Length", strconv.Itoa(200*1024))
+					w.Header().Set("ETag", `"foo-etag"`)
+					return
+				}
+				interrupt(w, r)
+			}
+
+			destinationDir := GinkgoT().TempDir()
+			err := storageClient.Download(ctx, "blob", filepath.Join(destinationDir, "blob"))
+			Expect(err).To(HaveOccurred())
+			Expect(os.ReadDir(destinationDir)).To(BeEmpty())
+			Expect(os.ReadDir(checkpointDir)).To(BeEmpty())
+		})
+	})
+
+	Describe("multipart Upload", func() {
+		var objectCRC64 string
+
+		BeforeEach(func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost && r.URL.Query().Has("uploads"):
+					_, _ = w.Write([]byte(`<InitiateMultipartUploadResult><Bucket>foo-bucket-name</Bucket><Key>blob</Key><UploadId>foo-upload-id</UploadId></InitiateMultipartUploadResult>`))
+				case r.Method == http.MethodPut:
+					_, _ = io.Copy(io.Discard, r.Body)
+					w.Header().Set("ETag", `"foo-part-etag"`)
+				case r.Method == http.MethodPost:
+					_, _ = w.Write([]byte(`<CompleteMultipartUploadResult><Bucket>foo-bucket-name</Bucket><Key>blob</Key><ETag>"foo-etag-2"</ETag></CompleteMultipartUploadResult>`))
+				case r.Method == http.MethodHead:
+					w.Header().Set("ETag", `"foo-etag-2"`)
+					w.Header().Set("X-Oss-Object-Type", "Multipart")
+					w.Header().Set("X-Oss-Hash-Crc64ecma", objectCRC64)
+				}
+			}
+
+			var err error
+			storageClient, err = client.NewStorageClient(config.AliStorageConfig{
+				AccessKeyID:        "foo_access_key_id",
+				AccessKeySecret:    "foo_access_key_secret",
+				Endpoint:           server.URL,
+				BucketName:         "foo-bucket-name",
+				MultipartThreshold: 100 * 1024,
+				UploadPartSize:     100 * 1024,
+				CheckpointDir:      GinkgoT().TempDir(),
+				RetryMaxAttempts:   1,
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		upload := func() error {
+			content := bytes.Repeat([]byte("a"), 200*1024)
+			sourceFilePath := filepath.Join(GinkgoT().TempDir(), "source")
+			Expect(os.WriteFile(sourceFilePath, content, 0600)).To(Succeed())
+			objectCRC64 = strconv.FormatUint(crc64.Checksum(content, crc64.MakeTable(crc64.ECMA)), 10)
+
+			return storageClient.Upload(context.Background(), sourceFilePath, "", "blob")
+		}
+
+		It("verifies the completed object against the CRC64 of the file", func() {
+			Expect(upload()).To(Succeed())
+		})
+
+		It("fails if the completed object does not match the file", func() {
+			handleUpload := handler
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodHead {
+					w.Header().Set("X-Oss-Hash-Crc64ecma", "1234")
+					return
+				}
+				handleUpload(w, r)
+			}
+
+			err := upload()
+			Expect(errors.Is(err, client.ErrChecksumMismatch)).To(BeTrue())
+		})
+	})
+
+	Describe("UploadStream and OpenStream", func() {
+		var objects map[string][]byte
+		var objectHeaders map[string]http.Header
+
+		BeforeEach(func() {
+			objects = map[string][]byte{}
+			objectHeaders = map[string]http.Header{}
+
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+
+				switch r.Method {
+				case http.MethodPut:
+					body, err := io.ReadAll(r.Body)
+					Expect(err).ToNot(HaveOccurred())
+
+					contentMD5 := md5.Sum(body)
+					if r.Header.Get("Content-MD5") != "" {
+						Expect(r.Header.Get("Content-MD5")).To(Equal(base64.StdEncoding.EncodeToString(contentMD5[:])))
+					}
+
+					header := http.Header{}
+					for name, values := range r.Header {
+						if strings.HasPrefix(name, "X-Oss-Meta-") {
+							header[name] = values
+						}
+					}
+					header.Set("Content-MD5", r.Header.Get("Content-MD5"))
+					objects[r.URL.Path], objectHeaders[r.URL.Path] = body, header
+
+				case http.MethodHead, http.MethodGet:
+					body, ok := objects[r.URL.Path]
+					if !ok {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					for name, values := range objectHeaders[r.URL.Path] {
+						w.Header()[name] = values
+					}
+					w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+					if r.Method == http.MethodGet {
+						_, _ = w.Write(body)
+					}
+				}
+			}
+		})
+
+		readAll := func(storageClient client.StorageClient, object string) (string, error) {
+			reader, err := storageClient.OpenStream(context.Background(), object, client.GetOptions{})
+			if err != nil {
+				return "", err
+			}
+			defer reader.Close()
+
+			content, err := io.ReadAll(reader)
+			return string(content), err
+		}
+
+		It("uploads a stream of unknown size in a single request with its Content-MD5", func() {
+			err := storageClient.UploadStream(context.Background(), strings.NewReader("foo"), -1, "blob", client.PutOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(objects["/foo-bucket-name/blob"])).To(Equal("foo"))
+			Expect(objectHeaders["/foo-bucket-name/blob"].Get("Content-MD5")).To(Equal("rL0Y20zC+Fzt72VPzMSk2A=="))
+
+			content, err := readAll(storageClient, "blob")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(content).To(Equal("foo"))
+		})
+
+		It("reads at most one part of a stream of unknown size ahead of its multipart upload", func() {
+			source := &countingReader{reader: bytes.NewReader(bytes.Repeat([]byte("a"), 150*1024))}
+			var readBeforeInitiate int64
+			var partSizes []int
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost && r.URL.Query().Has("uploads"):
+					readBeforeInitiate = source.count
+					_, _ = w.Write([]byte(`<InitiateMultipartUploadResult><Bucket>foo-bucket-name</Bucket><Key>blob</Key><UploadId>foo-upload-id</UploadId></InitiateMultipartUploadResult>`))
+				case r.Method == http.MethodPut:
+					body, _ := io.ReadAll(r.Body)
+					partSizes = append(partSizes, len(body))
+					w.Header().Set("ETag", `"foo-part-etag"`)
+				case r.Method == http.MethodPost:
+					_, _ = w.Write([]byte(`<CompleteMultipartUploadResult><Bucket>foo-bucket-name</Bucket><Key>blob</Key><ETag>"foo-etag-2"</ETag></CompleteMultipartUploadResult>`))
+				}
+			}
+
+			streamingClient, err := client.NewStorageClient(config.AliStorageConfig{
+				AccessKeyID:        "foo_access_key_id",
+				AccessKeySecret:    "foo_access_key_secret",
+				Endpoint:           server.URL,
+				BucketName:         "foo-bucket-name",
+				MultipartThreshold: 1024 * 1024,
+				UploadPartSize:     100 * 1024,
+				UploadConcurrency:  1,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = streamingClient.UploadStream(context.Background(), source, -1, "blob", client.PutOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(readBeforeInitiate).To(BeNumerically("<=", 100*1024))
+			Expect(partSizes).To(ConsistOf(100*1024, 50*1024))
+		})
+
+		It("aborts the multipart upload of a stream if completing it fails", func() {
+			var abortedUploadIDs []string
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost && r.URL.Query().Has("uploads"):
+					_, _ = w.Write([]byte(`<InitiateMultipartUploadResult><Bucket>foo-bucket-name</Bucket><Key>blob</Key><UploadId>foo-upload-id</UploadId></InitiateMultipartUploadResult>`))
+				case r.Method == http.MethodPut:
+					_, _ = io.Copy(io.Discard, r.Body)
+					w.Header().Set("ETag", `"foo-part-etag"`)
+				case r.Method == http.MethodPost:
+					w.WriteHeader(http.StatusForbidden)
+				case r.Method == http.MethodDelete:
+					abortedUploadIDs = append(abortedUploadIDs, r.URL.Query().Get("uploadId"))
+					w.WriteHeader(http.StatusNoContent)
+				}
+			}
+
+			streamingClient, err := client.NewStorageClient(config.AliStorageConfig{
+				AccessKeyID:        "foo_access_key_id",
+				AccessKeySecret:    "foo_access_key_secret",
+				Endpoint:           server.URL,
+				BucketName:         "foo-bucket-name",
+				MultipartThreshold: 100 * 1024,
+				UploadPartSize:     100 * 1024,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = streamingClient.UploadStream(context.Background(), bytes.NewReader(bytes.Repeat([]byte("a"), 150*1024)), -1, "blob", client.PutOptions{})
+			Expect(err).To(HaveOccurred())
+			Expect(abortedUploadIDs).To(Equal([]string{"foo-upload-id"}))
+		})
+
+		It("uploads a stream of known size with the user metadata", func() {
+			err := storageClient.UploadStream(context.Background(), iotest.OneByteReader(strings.NewReader("foo")), 3, "blob", client.PutOptions{
+				Metadata: map[string]string{"owner": "bosh"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(objects["/foo-bucket-name/blob"])).To(Equal("foo"))
+			Expect(objectHeaders["/foo-bucket-name/blob"].Get("X-Oss-Meta-Owner")).To(Equal("bosh"))
+		})
+
+		DescribeTable("fails instead of storing a blob if the stream does not match its known size",
+			func(content string, size int64, expectedErr string) {
+				handler = func(w http.ResponseWriter, r *http.Request) {
+					body, err := io.ReadAll(r.Body)
+					if err == nil {
+						objects[r.URL.Path] = body
+					}
+				}
+
+				err := storageClient.UploadStream(context.Background(), iotest.HalfReader(strings.NewReader(content)), size, "blob", client.PutOptions{})
+				Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+				Expect(objects).To(BeEmpty())
+			},
+			Entry("a short stream", "foo", int64(5), "stream ended after 3 of 5 bytes"),
+			Entry("a long stream", "foobar", int64(3), "stream is longer than 3 bytes"),
+			Entry("a non-empty stream of size 0", "foo", int64(0), "stream is longer than 0 bytes"),
+		)
+
+		It("does not send a request with a cancelled context", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := storageClient.UploadStream(ctx, strings.NewReader("foo"), -1, "blob", client.PutOptions{})
+			Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+			Expect(objects).To(BeEmpty())
+		})
+
+		It("reports a checksum mismatch after reading corrupted content", func() {
+			err := storageClient.UploadStream(context.Background(), strings.NewReader("foo"), -1, "blob", client.PutOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			objects["/foo-bucket-name/blob"] = []byte("bar")
+
+			content, err := readAll(storageClient, "blob")
+			Expect(errors.Is(err, client.ErrChecksumMismatch)).To(BeTrue())
+			Expect(content).To(Equal("bar"))
+		})
+
+		It("encrypts and decrypts streams with a client-side encryption key", func() {
+			keyFilePath := filepath.Join(GinkgoT().TempDir(), "master.key")
+			Expect(os.WriteFile(keyFilePath, []byte(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("k"), 32))), 0600)).To(Succeed())
+
+			encryptingClient, err := client.NewStorageClient(config.AliStorageConfig{
+				AccessKeyID:                 "foo_access_key_id",
+				AccessKeySecret:             "foo_access_key_secret",
+				Endpoint:                    server.URL,
+				BucketName:                  "foo-bucket-name",
+				ClientSideEncryptionKeyFile: keyFilePath,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = encryptingClient.UploadStream(context.Background(), strings.NewReader("foo"), -1, "blob", client.PutOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(objects["/foo-bucket-name/blob"])).ToNot(Equal("foo"))
+			Expect(objectHeaders["/foo-bucket-name/blob"].Get("X-Oss-Meta-Client-Side-Encryption-Cek-Alg")).To(Equal("AES/CTR/NoPadding"))
+
+			content, err := readAll(encryptingClient, "blob")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(content).To(Equal("foo"))
+
+			_, err = readAll(storageClient, "blob")
+			Expect(errors.Is(err, client.ErrEncryptionKeyRequired)).To(BeTrue())
+		})
+	})
+})
+
+// countingReader counts the bytes read from reader.
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
@@ -0,0 +1,432 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// maxUploadParts is the maximum number of parts of a multipart upload
+const maxUploadParts = 10000
+
+// PutOptions are the optional properties of blobs uploaded from a reader
+type PutOptions struct {
+	ContentType string
+	// Metadata is stored as user metadata (x-oss-meta-*) of the blob
+	Metadata map[string]string
+}
+
+func (o PutOptions) ossOptions() []oss.Option {
+	var options []oss.Option
+
+	if o.ContentType != "" {
+		options = append(options, oss.ContentType(o.ContentType))
+	}
+	for name, value := range o.Metadata {
+		options = append(options, oss.Meta(name, value))
+	}
+
+	return options
+}
+
+// GetOptions are the optional conditions for reading blobs
+type GetOptions struct {
+	// IfMatch only reads the blob if its ETag matches, e.g. the ETag returned by Head
+	IfMatch string
+}
+
+// UploadStream uploads the content read from source. With a known size below multipart_threshold the content is
+// streamed with a single request. With an unknown size, a negative size, content smaller than both multipart_threshold
+// and upload_part_size is buffered in memory, so that the request carries its Content-MD5. Larger content is uploaded
+// with a multipart upload of upload_part_size parts, of which up to upload_concurrency are buffered and uploaded in
+// parallel, each with its MD5.
+// A stream of known size which ends early or continues beyond the size fails the upload.
+func (dsc DefaultStorageClient) UploadStream(
+	ctx context.Context,
+	source io.Reader,
+	size int64,
+	destinationObject string,
+	putOptions PutOptions,
+) (err error) {
+	ctx, operation := dsc.startOperation(ctx, "upload_stream", destinationObject)
+	counter := &countingReader{reader: source}
+	source = counter
+	defer func() { operation.finish(err, slog.Int64(logKeyBytes, counter.count)) }()
+
+	var sized *exactSizeReader
+	if size >= 0 {
+		sized = &exactSizeReader{reader: source, size: size, remaining: size}
+		source = sized
+	}
+
+	options := append(dsc.encryptionOptions(), putOptions.ossOptions()...)
+	options = append(options, oss.WithContext(ctx))
+
+	var objectEnvelope *envelope
+	plaintextHash := md5.New()
+
+	if dsc.masterKey != nil {
+		created, err := newEnvelope()
+		if err != nil {
+			return fmt.Errorf("creating data key: %w", err)
+		}
+		objectEnvelope = &created
+
+		source, err = objectEnvelope.streamReader(io.TeeReader(source, plaintextHash))
+		if err != nil {
+			return err
+		}
+	}
+
+	if size >= 0 && size < dsc.storageConfig.MultipartThreshold {
+		// The SDK verifies the CRC64 of the streamed content against the one calculated by OSS
+		if objectEnvelope != nil {
+			metadataOptions, err := objectEnvelope.metadataOptions(dsc.masterKey, "", size)
+			if err != nil {
+				return err
+			}
+			options = append(options, metadataOptions...)
+		}
+
+		// The limited reader tells the SDK the Content-Length. As it does not read empty content at all,
+		// the end of an empty stream is verified beforehand.
+		if size == 0 {
+			if err := sized.verifyEnd(); err != io.EOF {
+				return err
+			}
+		}
+		return dsc.bucket.PutObject(destinationObject, io.LimitReader(source, size), options...)
+	}
+
+	if size < 0 {
+		// At most one part is read ahead, which the multipart upload would buffer anyway
+		peekSize := min(dsc.storageConfig.MultipartThreshold, dsc.storageConfig.UploadPartSize)
+		buffered, err := io.ReadAll(io.LimitReader(source, peekSize))
+		if err != nil {
+			return err
+		}
+
+		if int64(len(buffered)) < peekSize {
+			return dsc.putBuffered(buffered, destinationObject, options, objectEnvelope, plaintextHash)
+		}
+
+		source = io.MultiReader(bytes.NewReader(buffered), source)
+	}
+
+	if objectEnvelope != nil {
+		metadataOptions, err := objectEnvelope.metadataOptions(dsc.masterKey, "", size)
+		if err != nil {
+			return err
+		}
+		options = append(options, metadataOptions...)
+	}
+
+	operationLogger(ctx).Debug("using multipart upload",
+		slog.Int64("part_size", dsc.storageConfig.UploadPartSize),
+		slog.Int("concurrency", dsc.storageConfig.UploadConcurrency),
+	)
+
+	return dsc.uploadStreamParts(ctx, source, destinationObject, options)
+}
+
+// putBuffered uploads content which has been read completely, so that its MD5 and,
+// for client-side encrypted content, the properties of the unencrypted content are known.
+func (dsc DefaultStorageClient) putBuffered(
+	buffered []byte,
+	destinationObject string,
+	options []oss.Option,
+	objectEnvelope *envelope,
+	plaintextHash hash.Hash,
+) error {
+	if objectEnvelope != nil {
+		metadataOptions, err := objectEnvelope.metadataOptions(
+			dsc.masterKey,
+			base64.StdEncoding.EncodeToString(plaintextHash.Sum(nil)),
+			int64(len(buffered)),
+		)
+		if err != nil {
+			return err
+		}
+		options = append(options, metadataOptions...)
+	}
+
+	contentMD5 := md5.Sum(buffered)
+	return dsc.bucket.PutObject(
+		destinationObject,
+		bytes.NewReader(buffered),
+		append(options, oss.ContentMD5(base64.StdEncoding.EncodeToString(contentMD5[:])))...,
+	)
+}
+
+// uploadStreamParts uploads source with a multipart upload, which is aborted if any part or its completion fails.
+func (dsc DefaultStorageClient) uploadStreamParts(
+	ctx context.Context,
+	source io.Reader,
+	destinationObject string,
+	options []oss.Option,
+) error {
+	upload, err := dsc.bucket.InitiateMultipartUpload(destinationObject, options...)
+	if err != nil {
+		return err
+	}
+
+	parts, err := dsc.uploadParts(ctx, upload, source)
+	if err != nil {
+		// The upload is aborted even if ctx is done, so that no parts are left behind
+		dsc.abortMultipartUpload(upload)
+		return err
+	}
+
+	_, err = dsc.bucket.CompleteMultipartUpload(upload, parts, oss.WithContext(ctx))
+	if err != nil {
+		// A stream cannot be read again, so the uploaded parts are of no use to a retry
+		dsc.abortMultipartUpload(upload)
+		return err
+	}
+	return nil
+}
+
+func (dsc DefaultStorageClient) uploadParts(
+	ctx context.Context,
+	upload oss.InitiateMultipartUploadResult,
+	source io.Reader,
+) ([]oss.UploadPart, error) {
+	var (
+		mutex    sync.Mutex
+		wg       sync.WaitGroup
+		parts    []oss.UploadPart
+		firstErr error
+	)
+
+	failed := func(err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	semaphore := make(chan struct{}, dsc.storageConfig.UploadConcurrency)
+
+	for partNumber := 1; ; partNumber++ {
+		semaphore <- struct{}{}
+
+		mutex.Lock()
+		stop := firstErr != nil
+		mutex.Unlock()
+		if stop {
+			<-semaphore
+			break
+		}
+
+		if ctx.Err() != nil {
+			<-semaphore
+			failed(ctx.Err())
+			break
+		}
+
+		part := make([]byte, dsc.storageConfig.UploadPartSize)
+		n, err := io.ReadFull(source, part)
+		if err == io.EOF {
+			<-semaphore
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			<-semaphore
+			failed(err)
+			break
+		}
+		if partNumber > maxUploadParts {
+			<-semaphore
+			failed(fmt.Errorf("stream exceeds %d parts of %d bytes", maxUploadParts, dsc.storageConfig.UploadPartSize))
+			break
+		}
+
+		wg.Add(1)
+		go func(partNumber int, part []byte) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			partMD5 := md5.Sum(part)
+			uploaded, err := dsc.bucket.UploadPart(
+				upload,
+				bytes.NewReader(part),
+				int64(len(part)),
+				partNumber,
+				oss.ContentMD5(base64.StdEncoding.EncodeToString(partMD5[:])),
+				oss.WithContext(ctx),
+			)
+			if err != nil {
+				failed(fmt.Errorf("uploading part %d: %w", partNumber, err))
+				return
+			}
+
+			mutex.Lock()
+			parts = append(parts, uploaded)
+			mutex.Unlock()
+		}(partNumber, part[:n])
+
+		if n < len(part) {
+			break
+		}
+	}
+
+	wg.Wait()
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	return parts, firstErr
+}
+
+// OpenStream returns a reader of the content of sourceObject, decrypting client-side encrypted objects.
+// The checksum is calculated while reading and verified at the end of the content, so instead of io.EOF
+// the reader returns ErrChecksumMismatch after all content has been read if the content is corrupted.
+func (dsc DefaultStorageClient) OpenStream(
+	ctx context.Context,
+	sourceObject string,
+	getOptions GetOptions,
+) (_ io.ReadCloser, err error) {
+	ctx, operation := dsc.startOperation(ctx, "open_stream", sourceObject)
+	var size int64
+	defer func() { operation.finish(err, slog.Int64(logKeyBytes, size)) }()
+
+	options := []oss.Option{oss.WithContext(ctx)}
+	if getOptions.IfMatch != "" {
+		options = append(options, oss.IfMatch(getOptions.IfMatch))
+	}
+
+	objectHeader, err := dsc.bucket.GetObjectDetailedMeta(sourceObject, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	size, _ = strconv.ParseInt(objectHeader.Get(oss.HTTPHeaderContentLength), 10, 64)
+
+	objectEnvelope, err := dsc.objectEnvelope(sourceObject, objectHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := dsc.bucket.GetObject(sourceObject, oss.IfMatch(objectHeader.Get(oss.HTTPHeaderEtag)), oss.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	verifier := newChecksumVerifier()
+	reader := &verifyingReadCloser{
+		body:         body,
+		reader:       io.TeeReader(body, verifier),
+		objectHeader: objectHeader,
+		verifier:     verifier,
+	}
+
+	if objectEnvelope != nil {
+		decrypted, err := objectEnvelope.streamReader(reader.reader)
+		if err != nil {
+			_ = body.Close()
+			return nil, err
+		}
+		reader.plaintextHash = md5.New()
+		reader.reader = io.TeeReader(decrypted, reader.plaintextHash)
+	}
+
+	return reader, nil
+}
+
+// verifyingReadCloser verifies the checksum of the object, and of the decrypted content of client-side
+// encrypted objects, once the content has been read completely.
+type verifyingReadCloser struct {
+	body          io.ReadCloser
+	reader        io.Reader
+	objectHeader  http.Header
+	verifier      *checksumVerifier
+	plaintextHash hash.Hash
+}
+
+func (r *verifyingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err != io.EOF {
+		return n, err
+	}
+
+	verifyErr := r.verifier.verify(r.objectHeader)
+	if verifyErr == nil && r.plaintextHash != nil {
+		verifyErr = verifyDecryptedMD5(r.objectHeader, base64.StdEncoding.EncodeToString(r.plaintextHash.Sum(nil)))
+	}
+	if verifyErr != nil {
+		return n, verifyErr
+	}
+
+	return n, io.EOF
+}
+
+func (r *verifyingReadCloser) Close() error {
+	return r.body.Close()
+}
+
+// countingReader counts the bytes read from reader.
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
+
+// exactSizeReader reads a stream of known size, failing instead of ending early or leaving content behind, so that
+// neither a truncated blob is stored nor content beyond the size is silently dropped.
+type exactSizeReader struct {
+	reader    io.Reader
+	size      int64
+	remaining int64
+}
+
+func (r *exactSizeReader) Read(p []byte) (int, error) {
+	if r.remaining == 0 {
+		return 0, r.verifyEnd()
+	}
+
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+
+	switch {
+	case err == io.EOF && r.remaining > 0:
+		return n, fmt.Errorf("stream ended after %d of %d bytes", r.size-r.remaining, r.size)
+	case err != nil && err != io.EOF:
+		return n, err
+	case r.remaining == 0:
+		// Readers limited to the size do not read any further, so the end is verified along with the last bytes,
+		// which are held back on failure so that the request does not complete
+		if err := r.verifyEnd(); err != io.EOF {
+			return 0, err
+		}
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// verifyEnd returns io.EOF if the stream ends after size bytes, or else an error.
+func (r *exactSizeReader) verifyEnd() error {
+	var extra [1]byte
+	n, err := io.ReadFull(r.reader, extra[:])
+	if n > 0 {
+		return fmt.Errorf("stream is longer than %d bytes", r.size)
+	}
+	return err
+}
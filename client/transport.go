@@ -0,0 +1,155 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/xml"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// newTransport creates the HTTP transport shared by all requests, configured like the transport the SDK creates
+// for itself from the HTTP timeouts and connection limits of clientConfig.
+func newTransport(clientConfig *oss.Config) *http.Transport {
+	timeout := clientConfig.HTTPTimeout
+	maxConns := clientConfig.HTTPMaxConns
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialer := net.Dialer{Timeout: timeout.ConnectTimeout, KeepAlive: 30 * time.Second}
+
+			conn, err := dialer.DialContext(ctx, network, address)
+			if err != nil {
+				return nil, err
+			}
+			return &deadlineConn{Conn: conn, timeout: timeout.ReadWriteTimeout}, nil
+		},
+		MaxIdleConns:          maxConns.MaxIdleConns,
+		MaxIdleConnsPerHost:   maxConns.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       maxConns.MaxConnsPerHost,
+		IdleConnTimeout:       timeout.IdleConnTimeout,
+		ResponseHeaderTimeout: timeout.HeaderTimeout,
+	}
+
+	if clientConfig.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	return transport
+}
+
+// deadlineConn extends the deadline of the connection with every read and write, so that a stalled
+// transfer fails after the read/write timeout without limiting the duration of the whole transfer.
+type deadlineConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *deadlineConn) Read(b []byte) (int, error) {
+	if c.timeout > 0 {
+		_ = c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *deadlineConn) Write(b []byte) (int, error) {
+	if c.timeout > 0 {
+		_ = c.Conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	}
+	return c.Conn.Write(b)
+}
+
+// contextTransport logs every request at debug level and records the request IDs of the responses for the operation
+// of the context of the request, passed with the oss.WithContext option. If ctx is set, all requests are sent with
+// ctx instead, including the requests for which the SDK does not accept a context.
+type contextTransport struct {
+	ctx       context.Context
+	transport http.RoundTripper
+}
+
+func (t contextTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	start := time.Now()
+	attrs := []any{slog.String("method", request.Method), slog.String("path", request.URL.Path)}
+
+	ctx := request.Context()
+	if t.ctx != nil {
+		ctx = t.ctx
+		request = request.WithContext(ctx)
+	}
+
+	response, err := t.transport.RoundTrip(request)
+	if err != nil {
+		slog.Debug("request failed", append(attrs, slog.Duration(logKeyDuration, time.Since(start)), slog.String(logKeyError, err.Error()))...)
+		return nil, err
+	}
+
+	requestID := response.Header.Get(oss.HTTPHeaderOssRequestID)
+	recordRequestID(ctx, requestID)
+
+	if request.Method == http.MethodPost && request.URL.Query().Has("uploads") && response.StatusCode == http.StatusOK {
+		response.Body, err = recordInitiatedUpload(ctx, response.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	slog.Debug("request completed", append(attrs,
+		slog.Int("status", response.StatusCode),
+		slog.String(logKeyRequestID, requestID),
+		slog.Duration(logKeyDuration, time.Since(start)),
+	)...)
+	return response, nil
+}
+
+// initiatedUploadsKey is the context key of the initiatedUploads of an operation
+type initiatedUploadsKey struct{}
+
+// initiatedUploads keeps the IDs of the multipart uploads initiated by an operation, so that the operation only
+// aborts its own uploads and never the uploads of the same key by another writer.
+type initiatedUploads struct {
+	mutex     sync.Mutex
+	uploadIDs []string
+}
+
+// withInitiatedUploads returns a context recording the IDs of the multipart uploads initiated with it.
+func withInitiatedUploads(ctx context.Context) (context.Context, *initiatedUploads) {
+	uploads := &initiatedUploads{}
+	return context.WithValue(ctx, initiatedUploadsKey{}, uploads), uploads
+}
+
+func (u *initiatedUploads) list() []string {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return append([]string(nil), u.uploadIDs...)
+}
+
+// recordInitiatedUpload records the upload ID of an InitiateMultipartUpload response for ctx, if it records them,
+// and returns the body to be read by the SDK in its place.
+func recordInitiatedUpload(ctx context.Context, body io.ReadCloser) (io.ReadCloser, error) {
+	uploads, ok := ctx.Value(initiatedUploadsKey{}).(*initiatedUploads)
+	if !ok {
+		return body, nil
+	}
+
+	content, err := io.ReadAll(body)
+	_ = body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var initiated oss.InitiateMultipartUploadResult
+	if xml.Unmarshal(content, &initiated) == nil && initiated.UploadID != "" {
+		uploads.mutex.Lock()
+		uploads.uploadIDs = append(uploads.uploadIDs, initiated.UploadID)
+		uploads.mutex.Unlock()
+	}
+
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
@@ -0,0 +1,165 @@
+package clierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/client"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
+)
+
+// Kind is the category of a failed command, which determines the exit code of the CLI.
+type Kind string
+
+const (
+	// KindGeneral is any failure not covered by a more specific kind
+	KindGeneral Kind = "general"
+	// KindUsage is an invalid command, e.g. a missing argument or an unknown flag
+	KindUsage Kind = "usage"
+	// KindNotFound is a missing blob
+	KindNotFound Kind = "not_found"
+	// KindChecksumMismatch is downloaded or uploaded content which does not match the checksum of the blob
+	KindChecksumMismatch Kind = "checksum_mismatch"
+	// KindInvalidConfig is an invalid configuration, including a missing bucket or client-side encryption key
+	KindInvalidConfig Kind = "invalid_config"
+	// KindAccessDenied is a request denied by the policies of the bucket or the RAM user
+	KindAccessDenied Kind = "access_denied"
+	// KindAuthentication is a request which could not be authenticated, e.g. for an unknown access key or an expired token
+	KindAuthentication Kind = "authentication"
+	// KindClockSkew is a request rejected because the clock of the host differs too much from the clock of OSS
+	KindClockSkew Kind = "clock_skew"
+	// KindUnavailable is a transient failure which persisted through all retries
+	KindUnavailable Kind = "unavailable"
+	// KindCancelled is a command cancelled by a signal or the --timeout flag
+	KindCancelled Kind = "cancelled"
+)
+
+// exitCodes are the documented exit codes of the kinds of failures. `0` is reserved for success.
+var exitCodes = map[Kind]int{
+	KindGeneral:          1,
+	KindUsage:            2,
+	KindNotFound:         3,
+	KindChecksumMismatch: 4,
+	KindInvalidConfig:    5,
+	KindAccessDenied:     6,
+	KindAuthentication:   7,
+	KindClockSkew:        8,
+	KindUnavailable:      9,
+	KindCancelled:        10,
+}
+
+// serviceErrorKinds are the kinds of the OSS error codes which are not identified by their HTTP status code alone
+var serviceErrorKinds = map[string]Kind{
+	"NoSuchKey":             KindNotFound,
+	"NoSuchBucket":          KindInvalidConfig,
+	"InvalidBucketName":     KindInvalidConfig,
+	"AccessDenied":          KindAccessDenied,
+	"InvalidAccessKeyId":    KindAuthentication,
+	"SignatureDoesNotMatch": KindAuthentication,
+	"InvalidSecurityToken":  KindAuthentication,
+	"SecurityTokenExpired":  KindAuthentication,
+	"RequestTimeTooSkewed":  KindClockSkew,
+}
+
+// Error is a failure of the CLI classified by its kind. For failed OSS requests, it carries the OSS error code,
+// message and request ID, which identify the request for the OSS support.
+type Error struct {
+	Kind       Kind
+	Code       string
+	Message    string
+	RequestID  string
+	StatusCode int
+	Err        error
+}
+
+// New returns an error of the given kind wrapping err.
+func New(kind Kind, err error) *Error {
+	return &Error{Kind: kind, Err: err}
+}
+
+// Newf returns an error of the given kind with a formatted message.
+func Newf(kind Kind, format string, args ...interface{}) *Error {
+	return New(kind, fmt.Errorf(format, args...))
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of the CLI failing with e.
+func (e *Error) ExitCode() int {
+	return ExitCode(e.Kind)
+}
+
+// ExitCode returns the exit code of the CLI for failures of the given kind.
+func ExitCode(kind Kind) int {
+	exitCode, ok := exitCodes[kind]
+	if !ok {
+		return exitCodes[KindGeneral]
+	}
+	return exitCode
+}
+
+// Classify returns err as an Error, classified by the errors of the client and configuration and the OSS
+// service error it wraps. Errors which are already classified are returned as they are.
+func Classify(err error) *Error {
+	var classified *Error
+	if errors.As(err, &classified) {
+		return classified
+	}
+
+	classified = New(KindGeneral, err)
+
+	var serviceError oss.ServiceError
+	isServiceError := errors.As(err, &serviceError)
+	if isServiceError {
+		classified.Code = serviceError.Code
+		classified.Message = serviceError.Message
+		classified.RequestID = serviceError.RequestID
+		classified.StatusCode = serviceError.StatusCode
+	}
+
+	var validationError config.ValidationError
+	switch {
+	case client.IsCancelled(err):
+		classified.Kind = KindCancelled
+	case errors.Is(err, client.ErrNotFound):
+		classified.Kind = KindNotFound
+	case errors.Is(err, client.ErrChecksumMismatch):
+		classified.Kind = KindChecksumMismatch
+	case errors.Is(err, client.ErrEncryptionKeyRequired), errors.As(err, &validationError):
+		classified.Kind = KindInvalidConfig
+	case isServiceError:
+		classified.Kind = serviceErrorKind(serviceError)
+	case client.IsTransient(err):
+		classified.Kind = KindUnavailable
+	}
+
+	return classified
+}
+
+// serviceErrorKind returns the kind of an OSS error response by its error code, or else by its HTTP status code.
+func serviceErrorKind(serviceError oss.ServiceError) Kind {
+	kind, ok := serviceErrorKinds[serviceError.Code]
+	switch {
+	case ok:
+		return kind
+	case client.IsTransient(serviceError):
+		return KindUnavailable
+	}
+
+	// Responses to HEAD requests carry no error code
+	switch serviceError.StatusCode {
+	case http.StatusNotFound:
+		return KindNotFound
+	case http.StatusForbidden:
+		return KindAccessDenied
+	}
+	return KindGeneral
+}
@@ -0,0 +1,90 @@
+package clierror_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/client"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/clierror"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Classify", func() {
+	serviceError := func(statusCode int, code string) error {
+		return fmt.Errorf("upload failure: %w", oss.ServiceError{
+			StatusCode: statusCode,
+			Code:       code,
+			Message:    "foo message",
+			RequestID:  "foo-request-id",
+		})
+	}
+
+	DescribeTable("classifies OSS errors by their error code",
+		func(statusCode int, code string, kind clierror.Kind, exitCode int) {
+			classified := clierror.Classify(serviceError(statusCode, code))
+			Expect(classified.Kind).To(Equal(kind))
+			Expect(classified.ExitCode()).To(Equal(exitCode))
+		},
+		Entry("NoSuchKey", http.StatusNotFound, "NoSuchKey", clierror.KindNotFound, 3),
+		Entry("NoSuchBucket", http.StatusNotFound, "NoSuchBucket", clierror.KindInvalidConfig, 5),
+		Entry("AccessDenied", http.StatusForbidden, "AccessDenied", clierror.KindAccessDenied, 6),
+		Entry("InvalidAccessKeyId", http.StatusForbidden, "InvalidAccessKeyId", clierror.KindAuthentication, 7),
+		Entry("SignatureDoesNotMatch", http.StatusForbidden, "SignatureDoesNotMatch", clierror.KindAuthentication, 7),
+		Entry("RequestTimeTooSkewed", http.StatusForbidden, "RequestTimeTooSkewed", clierror.KindClockSkew, 8),
+		Entry("InternalError", http.StatusInternalServerError, "InternalError", clierror.KindUnavailable, 9),
+		Entry("a HEAD response for a missing blob", http.StatusNotFound, "", clierror.KindNotFound, 3),
+		Entry("a HEAD response for a denied request", http.StatusForbidden, "", clierror.KindAccessDenied, 6),
+		Entry("an unknown error code", http.StatusConflict, "ObjectNotAppendable", clierror.KindGeneral, 1),
+	)
+
+	It("keeps the OSS error code, message and request ID", func() {
+		err := serviceError(http.StatusForbidden, "AccessDenied")
+
+		classified := clierror.Classify(err)
+		Expect(classified.Code).To(Equal("AccessDenied"))
+		Expect(classified.Message).To(Equal("foo message"))
+		Expect(classified.RequestID).To(Equal("foo-request-id"))
+		Expect(classified.StatusCode).To(Equal(http.StatusForbidden))
+		Expect(classified).To(MatchError(err))
+	})
+
+	DescribeTable("classifies the errors of the client and the configuration",
+		func(err error, kind clierror.Kind) {
+			Expect(clierror.Classify(err).Kind).To(Equal(kind))
+		},
+		Entry("a missing blob", fmt.Errorf("%w: foo-bucket/blob", client.ErrNotFound), clierror.KindNotFound),
+		Entry("a checksum mismatch", fmt.Errorf("%w: expected CRC64 1, got 2", client.ErrChecksumMismatch), clierror.KindChecksumMismatch),
+		Entry("a missing encryption key", client.ErrEncryptionKeyRequired, clierror.KindInvalidConfig),
+		Entry("an invalid configuration", config.ValidationError{Problems: []string{"bucket_name is required"}}, clierror.KindInvalidConfig),
+		Entry("a cancelled context", fmt.Errorf("performing operation get: %w", context.Canceled), clierror.KindCancelled),
+		Entry("an exceeded timeout", context.DeadlineExceeded, clierror.KindCancelled),
+		Entry("a request timing out", &url.Error{Op: "Get", URL: "http://foo", Err: headerTimeoutError{}}, clierror.KindUnavailable),
+		Entry("a refused connection", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, clierror.KindUnavailable),
+		Entry("any other error", errors.New("foo"), clierror.KindGeneral),
+	)
+
+	It("returns errors which are already classified as they are", func() {
+		usageError := clierror.Newf(clierror.KindUsage, "unknown command: '%s'", "foo")
+
+		classified := clierror.Classify(fmt.Errorf("wrapped: %w", usageError))
+		Expect(classified).To(BeIdenticalTo(usageError))
+		Expect(classified.ExitCode()).To(Equal(2))
+		Expect(classified).To(MatchError("unknown command: 'foo'"))
+	})
+})
+
+// headerTimeoutError is like the error of the HTTP transport for responses whose headers time out,
+// which matches context.DeadlineExceeded
+type headerTimeoutError struct{}
+
+func (headerTimeoutError) Error() string        { return "net/http: timeout awaiting response headers" }
+func (headerTimeoutError) Timeout() bool        { return true }
+func (headerTimeoutError) Is(target error) bool { return target == context.DeadlineExceeded }
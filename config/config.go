@@ -3,6 +3,64 @@ package config
 import (
 	"encoding/json"
 	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+)
+
+const (
+	// DefaultMultipartThreshold is the file size from which uploads are split into parts
+	DefaultMultipartThreshold int64 = 100 * 1024 * 1024
+	// DefaultUploadPartSize is the size of a single part of a multipart upload
+	DefaultUploadPartSize int64 = 32 * 1024 * 1024
+	// DefaultUploadConcurrency is the number of parts uploaded in parallel
+	DefaultUploadConcurrency = 4
+	// DefaultDownloadPartSize is the size of a single byte range of a parallel download
+	DefaultDownloadPartSize int64 = 32 * 1024 * 1024
+	// DefaultDownloadConcurrency is the number of byte ranges downloaded in parallel
+	DefaultDownloadConcurrency = 4
+	// DefaultRetryMaxAttempts is the number of attempts of an operation failing with a transient error
+	DefaultRetryMaxAttempts = 3
+	// DefaultRetryBaseBackoffMs is the backoff before the first retry, doubled for every further retry
+	DefaultRetryBaseBackoffMs int64 = 200
+	// DefaultRetryMaxBackoffMs is the upper limit of the backoff between two attempts
+	DefaultRetryMaxBackoffMs int64 = 10000
+)
+
+const (
+	// CredentialsSourceStatic signs requests with the configured access key
+	CredentialsSourceStatic = "static"
+	// CredentialsSourceSTS signs requests with the configured access key and STS security token
+	CredentialsSourceSTS = "sts"
+	// CredentialsSourceRAMRole assumes the configured RAM role with the configured access key
+	CredentialsSourceRAMRole = "ram_role"
+	// CredentialsSourceECSRAMRole uses the RAM role of the ECS instance provided by the metadata service
+	CredentialsSourceECSRAMRole = "ecs_ram_role"
+
+	// ServerSideEncryptionAES256 encrypts objects at rest with keys managed by OSS
+	ServerSideEncryptionAES256 = "AES256"
+	// ServerSideEncryptionKMS encrypts objects at rest with a key managed by KMS
+	ServerSideEncryptionKMS = "KMS"
+	// ServerSideEncryptionSM4 encrypts objects at rest with the SM4 algorithm and keys managed by OSS
+	ServerSideEncryptionSM4 = "SM4"
+
+	// DefaultSTSEndpoint is the STS API endpoint used to assume RAM roles
+	DefaultSTSEndpoint = "https://sts.aliyuncs.com"
+	// DefaultMetadataEndpoint is the ECS instance metadata service
+	DefaultMetadataEndpoint = "http://100.100.100.200"
+
+	// RetryJitterFull waits a random duration between zero and the backoff
+	RetryJitterFull = "full"
+	// RetryJitterEqual waits half of the backoff plus a random duration up to the other half
+	RetryJitterEqual = "equal"
+	// RetryJitterNone waits exactly the backoff
+	RetryJitterNone = "none"
+
+	// AuthVersionV1 signs requests and URLs with HMAC-SHA1 signatures
+	AuthVersionV1 = "v1"
+	// AuthVersionV4 signs requests and URLs with HMAC-SHA256 signatures scoped to the region of the bucket
+	AuthVersionV4 = "v4"
 )
 
 type AliStorageConfig struct {
@@ -10,21 +68,143 @@ type AliStorageConfig struct {
 	AccessKeySecret string `json:"access_key_secret"`
 	Endpoint        string `json:"endpoint"`
 	BucketName      string `json:"bucket_name"`
+
+	CredentialsSource          string `json:"credentials_source,omitempty"`
+	SecurityToken              string `json:"security_token,omitempty"`
+	RoleARN                    string `json:"role_arn,omitempty"`
+	RoleSessionName            string `json:"role_session_name,omitempty"`
+	RoleSessionDurationSeconds int64  `json:"role_session_duration_seconds,omitempty"`
+	STSEndpoint                string `json:"sts_endpoint,omitempty"`
+	ECSRAMRoleName             string `json:"ecs_ram_role_name,omitempty"`
+	MetadataEndpoint           string `json:"metadata_endpoint,omitempty"`
+
+	AuthVersion string `json:"auth_version,omitempty"`
+	Region      string `json:"region,omitempty"`
+
+	ServerSideEncryption string `json:"server_side_encryption,omitempty"`
+	SSEKMSKeyID          string `json:"sse_kms_key_id,omitempty"`
+
+	ClientSideEncryptionKeyFile string `json:"client_side_encryption_key_file,omitempty"`
+
+	MultipartThreshold int64  `json:"multipart_threshold,omitempty"`
+	UploadPartSize     int64  `json:"upload_part_size,omitempty"`
+	UploadConcurrency  int    `json:"upload_concurrency,omitempty"`
+	CheckpointDir      string `json:"checkpoint_dir,omitempty"`
+
+	DownloadPartSize    int64 `json:"download_part_size,omitempty"`
+	DownloadConcurrency int   `json:"download_concurrency,omitempty"`
+
+	ConnectTimeoutSeconds   int64 `json:"connect_timeout_seconds,omitempty"`
+	ReadWriteTimeoutSeconds int64 `json:"read_write_timeout_seconds,omitempty"`
+	IdleConnTimeoutSeconds  int64 `json:"idle_conn_timeout_seconds,omitempty"`
+	MaxIdleConns            int   `json:"max_idle_conns,omitempty"`
+	MaxIdleConnsPerHost     int   `json:"max_idle_conns_per_host,omitempty"`
+	MaxConnsPerHost         int   `json:"max_conns_per_host,omitempty"`
+
+	RetryMaxAttempts   int    `json:"retry_max_attempts,omitempty"`
+	RetryBaseBackoffMs int64  `json:"retry_base_backoff_ms,omitempty"`
+	RetryMaxBackoffMs  int64  `json:"retry_max_backoff_ms,omitempty"`
+	RetryJitter        string `json:"retry_jitter,omitempty"`
+
+	// unknownProperties are the properties found in the JSON which are not part of the configuration
+	unknownProperties []string
 }
 
 // NewFromReader returns a new ali-storage-cli configuration struct from the contents of reader.
 // reader.Read() is expected to return valid JSON
 func NewFromReader(reader io.Reader) (AliStorageConfig, error) {
-	bytes, err := io.ReadAll(reader)
-	if err != nil {
-		return AliStorageConfig{}, err
-	}
 	config := AliStorageConfig{}
 
-	err = json.Unmarshal(bytes, &config)
+	err := config.decode(reader)
 	if err != nil {
 		return AliStorageConfig{}, err
 	}
 
+	config.ApplyDefaults()
+
 	return config, nil
 }
+
+// decode sets the properties contained in the JSON read from reader, keeping all other properties.
+// Properties which are not part of the configuration are recorded and reported by Validate.
+func (config *AliStorageConfig) decode(reader io.Reader) error {
+	bytes, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(bytes, config)
+	if err != nil {
+		return err
+	}
+
+	properties := map[string]json.RawMessage{}
+	err = json.Unmarshal(bytes, &properties)
+	if err != nil {
+		return err
+	}
+
+	configType := reflect.TypeOf(*config)
+	for i := 0; i < configType.NumField(); i++ {
+		delete(properties, propertyName(configType.Field(i)))
+	}
+
+	for name := range properties {
+		config.unknownProperties = append(config.unknownProperties, name)
+	}
+	sort.Strings(config.unknownProperties)
+
+	return nil
+}
+
+// ApplyDefaults sets all optional properties which are not configured to their default values.
+func (config *AliStorageConfig) ApplyDefaults() {
+	if config.CredentialsSource == "" {
+		config.CredentialsSource = CredentialsSourceStatic
+	}
+	if config.RoleSessionName == "" {
+		config.RoleSessionName = "bosh-ali-storage-cli"
+	}
+	if config.STSEndpoint == "" {
+		config.STSEndpoint = DefaultSTSEndpoint
+	}
+	if config.MetadataEndpoint == "" {
+		config.MetadataEndpoint = DefaultMetadataEndpoint
+	}
+	if config.AuthVersion == "" {
+		config.AuthVersion = AuthVersionV1
+	}
+	if config.Region == "" {
+		config.Region = endpointRegion(config.Endpoint)
+	}
+	if config.MultipartThreshold <= 0 {
+		config.MultipartThreshold = DefaultMultipartThreshold
+	}
+	if config.UploadPartSize <= 0 {
+		config.UploadPartSize = DefaultUploadPartSize
+	}
+	if config.UploadConcurrency <= 0 {
+		config.UploadConcurrency = DefaultUploadConcurrency
+	}
+	if config.DownloadPartSize <= 0 {
+		config.DownloadPartSize = DefaultDownloadPartSize
+	}
+	if config.DownloadConcurrency <= 0 {
+		config.DownloadConcurrency = DefaultDownloadConcurrency
+	}
+	if config.RetryMaxAttempts <= 0 {
+		config.RetryMaxAttempts = DefaultRetryMaxAttempts
+	}
+	if config.RetryBaseBackoffMs <= 0 {
+		config.RetryBaseBackoffMs = DefaultRetryBaseBackoffMs
+	}
+	if config.RetryMaxBackoffMs <= 0 {
+		config.RetryMaxBackoffMs = DefaultRetryMaxBackoffMs
+	}
+	if config.RetryJitter == "" {
+		config.RetryJitter = RetryJitterFull
+	}
+	if config.CheckpointDir == "" {
+		config.CheckpointDir = filepath.Join(os.TempDir(), "bosh-ali-storage-cli")
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
 )
 
 var _ = Describe("Config", func() {
@@ -26,6 +28,203 @@ var _ = Describe("Config", func() {
 		Expect(config.BucketName).To(Equal("foo_bucket_name"))
 	})
 
+	It("contains optional multipart upload properties", func() {
+		configJson := []byte(`{"access_key_id": "foo_access_key_id",
+								"access_key_secret": "foo_access_key_secret",
+								"endpoint": "foo_endpoint",
+								"bucket_name": "foo_bucket_name",
+								"multipart_threshold": 1048576,
+								"upload_part_size": 524288,
+								"upload_concurrency": 8,
+								"checkpoint_dir": "/var/vcap/data/tmp"}`)
+		configReader := bytes.NewReader(configJson)
+
+		config, err := config.NewFromReader(configReader)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.MultipartThreshold).To(Equal(int64(1048576)))
+		Expect(config.UploadPartSize).To(Equal(int64(524288)))
+		Expect(config.UploadConcurrency).To(Equal(8))
+		Expect(config.CheckpointDir).To(Equal("/var/vcap/data/tmp"))
+	})
+
+	It("contains optional parallel download properties", func() {
+		configJson := []byte(`{"access_key_id": "foo_access_key_id",
+								"access_key_secret": "foo_access_key_secret",
+								"endpoint": "foo_endpoint",
+								"bucket_name": "foo_bucket_name",
+								"download_part_size": 524288,
+								"download_concurrency": 8}`)
+		configReader := bytes.NewReader(configJson)
+
+		config, err := config.NewFromReader(configReader)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.DownloadPartSize).To(Equal(int64(524288)))
+		Expect(config.DownloadConcurrency).To(Equal(8))
+	})
+
+	It("contains optional HTTP connection properties", func() {
+		configJson := []byte(`{"access_key_id": "foo_access_key_id",
+								"access_key_secret": "foo_access_key_secret",
+								"endpoint": "foo_endpoint",
+								"bucket_name": "foo_bucket_name",
+								"connect_timeout_seconds": 5,
+								"read_write_timeout_seconds": 30,
+								"idle_conn_timeout_seconds": 90,
+								"max_idle_conns": 50,
+								"max_idle_conns_per_host": 20,
+								"max_conns_per_host": 10}`)
+		configReader := bytes.NewReader(configJson)
+
+		config, err := config.NewFromReader(configReader)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.ConnectTimeoutSeconds).To(Equal(int64(5)))
+		Expect(config.ReadWriteTimeoutSeconds).To(Equal(int64(30)))
+		Expect(config.IdleConnTimeoutSeconds).To(Equal(int64(90)))
+		Expect(config.MaxIdleConns).To(Equal(50))
+		Expect(config.MaxIdleConnsPerHost).To(Equal(20))
+		Expect(config.MaxConnsPerHost).To(Equal(10))
+	})
+
+	It("contains optional credentials properties", func() {
+		configJson := []byte(`{"endpoint": "foo_endpoint",
+								"bucket_name": "foo_bucket_name",
+								"credentials_source": "ram_role",
+								"security_token": "foo_security_token",
+								"role_arn": "acs:ram::123456:role/blobstore",
+								"role_session_name": "foo_session",
+								"role_session_duration_seconds": 900,
+								"sts_endpoint": "https://sts.cn-hangzhou.aliyuncs.com",
+								"ecs_ram_role_name": "foo_role",
+								"metadata_endpoint": "http://127.0.0.1:8080"}`)
+		configReader := bytes.NewReader(configJson)
+
+		c, err := config.NewFromReader(configReader)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.CredentialsSource).To(Equal(config.CredentialsSourceRAMRole))
+		Expect(c.SecurityToken).To(Equal("foo_security_token"))
+		Expect(c.RoleARN).To(Equal("acs:ram::123456:role/blobstore"))
+		Expect(c.RoleSessionName).To(Equal("foo_session"))
+		Expect(c.RoleSessionDurationSeconds).To(Equal(int64(900)))
+		Expect(c.STSEndpoint).To(Equal("https://sts.cn-hangzhou.aliyuncs.com"))
+		Expect(c.ECSRAMRoleName).To(Equal("foo_role"))
+		Expect(c.MetadataEndpoint).To(Equal("http://127.0.0.1:8080"))
+	})
+
+	It("contains optional signature properties", func() {
+		configJson := []byte(`{"access_key_id": "foo_access_key_id",
+								"access_key_secret": "foo_access_key_secret",
+								"endpoint": "foo_endpoint",
+								"bucket_name": "foo_bucket_name",
+								"auth_version": "v4",
+								"region": "cn-shanghai"}`)
+		configReader := bytes.NewReader(configJson)
+
+		c, err := config.NewFromReader(configReader)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.AuthVersion).To(Equal(config.AuthVersionV4))
+		Expect(c.Region).To(Equal("cn-shanghai"))
+	})
+
+	It("derives the region from public and internal OSS endpoints", func() {
+		for endpoint, region := range map[string]string{
+			"oss-cn-hangzhou.aliyuncs.com":                   "cn-hangzhou",
+			"https://oss-eu-central-1-internal.aliyuncs.com": "eu-central-1",
+			"oss-accelerate.aliyuncs.com":                    "",
+			"127.0.0.1:8080":                                 "",
+		} {
+			configJson := []byte(`{"access_key_id": "foo_access_key_id",
+									"access_key_secret": "foo_access_key_secret",
+									"endpoint": "` + endpoint + `",
+									"bucket_name": "foo_bucket_name"}`)
+
+			c, err := config.NewFromReader(bytes.NewReader(configJson))
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Region).To(Equal(region), endpoint)
+		}
+	})
+
+	It("contains optional server-side encryption properties", func() {
+		configJson := []byte(`{"access_key_id": "foo_access_key_id",
+								"access_key_secret": "foo_access_key_secret",
+								"endpoint": "foo_endpoint",
+								"bucket_name": "foo_bucket_name",
+								"server_side_encryption": "KMS",
+								"sse_kms_key_id": "foo_kms_key_id"}`)
+		configReader := bytes.NewReader(configJson)
+
+		config, err := config.NewFromReader(configReader)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.ServerSideEncryption).To(Equal("KMS"))
+		Expect(config.SSEKMSKeyID).To(Equal("foo_kms_key_id"))
+	})
+
+	It("contains the optional client-side encryption key file", func() {
+		configJson := []byte(`{"access_key_id": "foo_access_key_id",
+								"access_key_secret": "foo_access_key_secret",
+								"endpoint": "foo_endpoint",
+								"bucket_name": "foo_bucket_name",
+								"client_side_encryption_key_file": "/foo/master.key"}`)
+		configReader := bytes.NewReader(configJson)
+
+		config, err := config.NewFromReader(configReader)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.ClientSideEncryptionKeyFile).To(Equal("/foo/master.key"))
+	})
+
+	It("contains optional retry properties", func() {
+		configJson := []byte(`{"access_key_id": "foo_access_key_id",
+								"access_key_secret": "foo_access_key_secret",
+								"endpoint": "foo_endpoint",
+								"bucket_name": "foo_bucket_name",
+								"retry_max_attempts": 5,
+								"retry_base_backoff_ms": 100,
+								"retry_max_backoff_ms": 2000,
+								"retry_jitter": "equal"}`)
+		configReader := bytes.NewReader(configJson)
+
+		c, err := config.NewFromReader(configReader)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.RetryMaxAttempts).To(Equal(5))
+		Expect(c.RetryBaseBackoffMs).To(Equal(int64(100)))
+		Expect(c.RetryMaxBackoffMs).To(Equal(int64(2000)))
+		Expect(c.RetryJitter).To(Equal(config.RetryJitterEqual))
+	})
+
+	It("uses defaults for optional properties which are not configured", func() {
+		configJson := []byte(`{"access_key_id": "foo_access_key_id",
+								"access_key_secret": "foo_access_key_secret",
+								"endpoint": "foo_endpoint",
+								"bucket_name": "foo_bucket_name"}`)
+		configReader := bytes.NewReader(configJson)
+
+		c, err := config.NewFromReader(configReader)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.CredentialsSource).To(Equal(config.CredentialsSourceStatic))
+		Expect(c.STSEndpoint).To(Equal(config.DefaultSTSEndpoint))
+		Expect(c.MetadataEndpoint).To(Equal(config.DefaultMetadataEndpoint))
+		Expect(c.MultipartThreshold).To(Equal(config.DefaultMultipartThreshold))
+		Expect(c.UploadPartSize).To(Equal(config.DefaultUploadPartSize))
+		Expect(c.UploadConcurrency).To(Equal(config.DefaultUploadConcurrency))
+		Expect(c.DownloadPartSize).To(Equal(config.DefaultDownloadPartSize))
+		Expect(c.DownloadConcurrency).To(Equal(config.DefaultDownloadConcurrency))
+		Expect(c.RetryMaxAttempts).To(Equal(config.DefaultRetryMaxAttempts))
+		Expect(c.RetryBaseBackoffMs).To(Equal(config.DefaultRetryBaseBackoffMs))
+		Expect(c.RetryMaxBackoffMs).To(Equal(config.DefaultRetryMaxBackoffMs))
+		Expect(c.RetryJitter).To(Equal(config.RetryJitterFull))
+		Expect(c.AuthVersion).To(Equal(config.AuthVersionV1))
+		Expect(c.CheckpointDir).To(Equal(filepath.Join(os.TempDir(), "bosh-ali-storage-cli")))
+	})
+
 	It("is empty if config cannot be parsed", func() {
 		configJson := []byte(`~`)
 		configReader := bytes.NewReader(configJson)
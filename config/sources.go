@@ -0,0 +1,238 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// EnvPrefix is prepended to the upper-cased JSON property names to form the environment variable names,
+	// e.g. ALI_ACCESS_KEY_ID for access_key_id
+	EnvPrefix = "ALI_"
+	// EnvProfileFile overrides the location of the Aliyun CLI configuration file
+	EnvProfileFile = "ALI_PROFILE_FILE"
+	// EnvProfile selects the profile of the Aliyun CLI configuration file
+	EnvProfile = "ALI_PROFILE"
+)
+
+// LookupEnvFunc returns the value of an environment variable and whether it is set, like os.LookupEnv
+type LookupEnvFunc func(string) (string, bool)
+
+// Load resolves the configuration from the following sources, each one overriding the properties set by the previous ones:
+//  1. the credentials of a profile in the Aliyun CLI configuration file (~/.aliyun/config.json)
+//  2. the JSON configuration file at configPath, if configPath is not empty
+//  3. the ALI_* environment variables
+//
+// If the JSON configuration file or the environment supplies credentials, the credentials of the profile are not used.
+// Unless a profile is selected with ALI_PROFILE or ALI_PROFILE_FILE, the profile is then skipped altogether.
+func Load(configPath string, lookupEnv LookupEnvFunc) (AliStorageConfig, error) {
+	var configBytes []byte
+	if configPath != "" {
+		var err error
+		configBytes, err = os.ReadFile(configPath)
+		if err != nil {
+			return AliStorageConfig{}, err
+		}
+	}
+
+	// The sources overriding the profile are resolved on their own first, to tell whether they supply credentials
+	sources := AliStorageConfig{}
+	err := sources.applySources(configBytes, lookupEnv)
+	if err != nil {
+		return AliStorageConfig{}, err
+	}
+
+	config := AliStorageConfig{}
+	err = config.applyProfileFile(lookupEnv, sources.suppliesCredentials())
+	if err != nil {
+		return AliStorageConfig{}, err
+	}
+
+	err = config.applySources(configBytes, lookupEnv)
+	if err != nil {
+		return AliStorageConfig{}, err
+	}
+
+	config.ApplyDefaults()
+
+	return config, nil
+}
+
+// applySources sets the properties of the JSON configuration, if any, and of the environment.
+func (config *AliStorageConfig) applySources(configBytes []byte, lookupEnv LookupEnvFunc) error {
+	if configBytes != nil {
+		err := config.decode(bytes.NewReader(configBytes))
+		if err != nil {
+			return err
+		}
+	}
+
+	return config.applyEnvironment(lookupEnv)
+}
+
+// suppliesCredentials tells whether an access key or a credentials source is set.
+func (config *AliStorageConfig) suppliesCredentials() bool {
+	return config.AccessKeyID != "" || config.AccessKeySecret != "" || config.CredentialsSource != ""
+}
+
+// applyEnvironment sets every property for which an ALI_<PROPERTY_NAME> environment variable is set.
+func (config *AliStorageConfig) applyEnvironment(lookupEnv LookupEnvFunc) error {
+	value := reflect.ValueOf(config).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		name := propertyName(value.Type().Field(i))
+		if name == "" {
+			continue
+		}
+
+		envName := EnvPrefix + strings.ToUpper(name)
+		envValue, ok := lookupEnv(envName)
+		if !ok {
+			continue
+		}
+
+		field := value.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(envValue)
+		case reflect.Int, reflect.Int64:
+			parsed, err := strconv.ParseInt(envValue, 10, 64)
+			if err != nil {
+				return fmt.Errorf("parsing environment variable %s: %w", envName, err)
+			}
+			field.SetInt(parsed)
+		case reflect.Bool:
+			parsed, err := strconv.ParseBool(envValue)
+			if err != nil {
+				return fmt.Errorf("parsing environment variable %s: %w", envName, err)
+			}
+			field.SetBool(parsed)
+		}
+	}
+
+	return nil
+}
+
+type aliyunCLIConfig struct {
+	Current  string             `json:"current"`
+	Profiles []aliyunCLIProfile `json:"profiles"`
+}
+
+type aliyunCLIProfile struct {
+	Name            string `json:"name"`
+	Mode            string `json:"mode"`
+	AccessKeyID     string `json:"access_key_id"`
+	AccessKeySecret string `json:"access_key_secret"`
+	StsToken        string `json:"sts_token"`
+	RAMRoleName     string `json:"ram_role_name"`
+	RAMRoleARN      string `json:"ram_role_arn"`
+	RAMSessionName  string `json:"ram_session_name"`
+	ExpiredSeconds  int64  `json:"expired_seconds"`
+	RegionID        string `json:"region_id"`
+}
+
+// applyProfileFile sets the credentials and the endpoint of the selected profile of the Aliyun CLI configuration file.
+// A profile is selected explicitly by setting ALI_PROFILE or ALI_PROFILE_FILE, or implicitly otherwise. A missing
+// file, a missing profile and an unsupported mode are ignored for an implicitly selected profile, which is not used at
+// all if credentialsSupplied tells that later sources supply credentials. An explicitly selected profile only contributes
+// its endpoint then.
+func (config *AliStorageConfig) applyProfileFile(lookupEnv LookupEnvFunc, credentialsSupplied bool) error {
+	profileFilePath, explicitFile := lookupEnv(EnvProfileFile)
+	profileName, explicitProfile := lookupEnv(EnvProfile)
+	explicit := explicitFile || explicitProfile
+	if !explicit && credentialsSupplied {
+		return nil
+	}
+
+	if !explicitFile {
+		homeDir, ok := lookupEnv("HOME")
+		if !ok {
+			return nil
+		}
+		profileFilePath = filepath.Join(homeDir, ".aliyun", "config.json")
+	}
+
+	content, err := os.ReadFile(profileFilePath)
+	if errors.Is(err, fs.ErrNotExist) && !explicitFile {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading profile file: %w", err)
+	}
+
+	cliConfig := aliyunCLIConfig{}
+	err = json.Unmarshal(content, &cliConfig)
+	if err != nil {
+		return fmt.Errorf("parsing profile file %s: %w", profileFilePath, err)
+	}
+
+	if !explicitProfile {
+		profileName = cliConfig.Current
+	}
+	if profileName == "" {
+		profileName = "default"
+	}
+
+	for _, profile := range cliConfig.Profiles {
+		if profile.Name == profileName {
+			err = config.applyProfile(profile, credentialsSupplied)
+			if errors.Is(err, errUnsupportedMode) && !explicit {
+				return nil
+			}
+			return err
+		}
+	}
+
+	if explicitProfile {
+		return fmt.Errorf("profile '%s' not found in profile file %s", profileName, profileFilePath)
+	}
+	return nil
+}
+
+var errUnsupportedMode = errors.New("unsupported mode")
+
+// applyProfile sets the credentials of profile, unless credentialsSupplied, and the endpoint of its region.
+func (config *AliStorageConfig) applyProfile(profile aliyunCLIProfile, credentialsSupplied bool) error {
+	credentials := AliStorageConfig{}
+	switch profile.Mode {
+	case "", "AK":
+		credentials.CredentialsSource = CredentialsSourceStatic
+	case "StsToken":
+		credentials.CredentialsSource = CredentialsSourceSTS
+		credentials.SecurityToken = profile.StsToken
+	case "RamRoleArn":
+		credentials.CredentialsSource = CredentialsSourceRAMRole
+		credentials.RoleARN = profile.RAMRoleARN
+		credentials.RoleSessionName = profile.RAMSessionName
+		credentials.RoleSessionDurationSeconds = profile.ExpiredSeconds
+	case "EcsRamRole":
+		credentials.CredentialsSource = CredentialsSourceECSRAMRole
+		credentials.ECSRAMRoleName = profile.RAMRoleName
+	default:
+		return fmt.Errorf("profile '%s' has %w '%s'", profile.Name, errUnsupportedMode, profile.Mode)
+	}
+
+	if !credentialsSupplied {
+		config.CredentialsSource = credentials.CredentialsSource
+		config.SecurityToken = credentials.SecurityToken
+		config.RoleARN = credentials.RoleARN
+		config.RoleSessionName = credentials.RoleSessionName
+		config.RoleSessionDurationSeconds = credentials.RoleSessionDurationSeconds
+		config.ECSRAMRoleName = credentials.ECSRAMRoleName
+		config.AccessKeyID = profile.AccessKeyID
+		config.AccessKeySecret = profile.AccessKeySecret
+	}
+
+	if profile.RegionID != "" {
+		config.Endpoint = fmt.Sprintf("oss-%s.aliyuncs.com", profile.RegionID)
+	}
+
+	return nil
+}
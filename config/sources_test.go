@@ -0,0 +1,228 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Load", func() {
+	var tmpDir string
+	var env map[string]string
+
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	writeFile := func(name string, content string) string {
+		path := filepath.Join(tmpDir, name)
+		Expect(os.WriteFile(path, []byte(content), 0600)).To(Succeed())
+		return path
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "ali-storage-cli-config")
+		Expect(err).ToNot(HaveOccurred())
+
+		env = map[string]string{"HOME": tmpDir}
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	It("reads the configuration from the environment without a config file", func() {
+		env["ALI_ACCESS_KEY_ID"] = "env_access_key_id"
+		env["ALI_ACCESS_KEY_SECRET"] = "env_access_key_secret"
+		env["ALI_ENDPOINT"] = "env_endpoint"
+		env["ALI_BUCKET_NAME"] = "env_bucket_name"
+		env["ALI_UPLOAD_CONCURRENCY"] = "12"
+
+		c, err := config.Load("", lookupEnv)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.AccessKeyID).To(Equal("env_access_key_id"))
+		Expect(c.AccessKeySecret).To(Equal("env_access_key_secret"))
+		Expect(c.Endpoint).To(Equal("env_endpoint"))
+		Expect(c.BucketName).To(Equal("env_bucket_name"))
+		Expect(c.UploadConcurrency).To(Equal(12))
+		Expect(c.DownloadConcurrency).To(Equal(config.DefaultDownloadConcurrency))
+	})
+
+	It("overrides the config file with the environment", func() {
+		configPath := writeFile("config.json", `{"access_key_id": "file_access_key_id",
+			"access_key_secret": "file_access_key_secret",
+			"endpoint": "file_endpoint",
+			"bucket_name": "file_bucket_name"}`)
+		env["ALI_ACCESS_KEY_SECRET"] = "env_access_key_secret"
+
+		c, err := config.Load(configPath, lookupEnv)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.AccessKeyID).To(Equal("file_access_key_id"))
+		Expect(c.AccessKeySecret).To(Equal("env_access_key_secret"))
+		Expect(c.BucketName).To(Equal("file_bucket_name"))
+	})
+
+	It("fails for an environment variable of the wrong type", func() {
+		env["ALI_UPLOAD_PART_SIZE"] = "large"
+
+		_, err := config.Load("", lookupEnv)
+		Expect(err).To(MatchError(ContainSubstring("parsing environment variable ALI_UPLOAD_PART_SIZE")))
+	})
+
+	It("fails if the config file does not exist", func() {
+		_, err := config.Load(filepath.Join(tmpDir, "missing.json"), lookupEnv)
+		Expect(err).To(HaveOccurred())
+	})
+
+	Context("with a profile file", func() {
+		BeforeEach(func() {
+			env[config.EnvProfileFile] = writeFile("aliyun.json", `{"current": "ci", "profiles": [
+				{"name": "default", "mode": "AK", "access_key_id": "default_access_key_id", "access_key_secret": "default_access_key_secret", "region_id": "cn-hangzhou"},
+				{"name": "ci", "mode": "StsToken", "access_key_id": "ci_access_key_id", "access_key_secret": "ci_access_key_secret", "sts_token": "ci_token", "region_id": "eu-central-1"},
+				{"name": "role", "mode": "RamRoleArn", "access_key_id": "role_access_key_id", "access_key_secret": "role_access_key_secret", "ram_role_arn": "acs:ram::123456:role/ci", "ram_session_name": "ci", "expired_seconds": 900},
+				{"name": "ecs", "mode": "EcsRamRole", "ram_role_name": "blobstore"}
+			]}`)
+			env["ALI_BUCKET_NAME"] = "env_bucket_name"
+		})
+
+		It("uses the current profile", func() {
+			c, err := config.Load("", lookupEnv)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.CredentialsSource).To(Equal(config.CredentialsSourceSTS))
+			Expect(c.AccessKeyID).To(Equal("ci_access_key_id"))
+			Expect(c.AccessKeySecret).To(Equal("ci_access_key_secret"))
+			Expect(c.SecurityToken).To(Equal("ci_token"))
+			Expect(c.Endpoint).To(Equal("oss-eu-central-1.aliyuncs.com"))
+			Expect(c.BucketName).To(Equal("env_bucket_name"))
+		})
+
+		It("uses the profile selected in the environment", func() {
+			env[config.EnvProfile] = "role"
+
+			c, err := config.Load("", lookupEnv)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.CredentialsSource).To(Equal(config.CredentialsSourceRAMRole))
+			Expect(c.RoleARN).To(Equal("acs:ram::123456:role/ci"))
+			Expect(c.RoleSessionName).To(Equal("ci"))
+			Expect(c.RoleSessionDurationSeconds).To(Equal(int64(900)))
+		})
+
+		It("maps ECS RAM role profiles", func() {
+			env[config.EnvProfile] = "ecs"
+
+			c, err := config.Load("", lookupEnv)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.CredentialsSource).To(Equal(config.CredentialsSourceECSRAMRole))
+			Expect(c.ECSRAMRoleName).To(Equal("blobstore"))
+		})
+
+		It("is overridden by the config file", func() {
+			configPath := writeFile("config.json", `{"endpoint": "file_endpoint"}`)
+
+			c, err := config.Load(configPath, lookupEnv)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.AccessKeyID).To(Equal("ci_access_key_id"))
+			Expect(c.Endpoint).To(Equal("file_endpoint"))
+		})
+
+		It("drops the credentials of the profile if the config file sets an access key", func() {
+			configPath := writeFile("config.json", `{"access_key_id": "file_access_key_id", "access_key_secret": "file_access_key_secret"}`)
+
+			c, err := config.Load(configPath, lookupEnv)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.CredentialsSource).To(Equal(config.CredentialsSourceStatic))
+			Expect(c.AccessKeyID).To(Equal("file_access_key_id"))
+			Expect(c.SecurityToken).To(BeEmpty())
+			Expect(c.Endpoint).To(Equal("oss-eu-central-1.aliyuncs.com"))
+		})
+
+		It("drops the credentials of the profile if the environment sets an access key", func() {
+			env[config.EnvProfile] = "role"
+			env["ALI_ACCESS_KEY_ID"] = "env_access_key_id"
+			env["ALI_ACCESS_KEY_SECRET"] = "env_access_key_secret"
+
+			c, err := config.Load("", lookupEnv)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.CredentialsSource).To(Equal(config.CredentialsSourceStatic))
+			Expect(c.AccessKeyID).To(Equal("env_access_key_id"))
+			Expect(c.RoleARN).To(BeEmpty())
+		})
+
+		It("fails for an unknown profile selected in the environment", func() {
+			env[config.EnvProfile] = "unknown"
+
+			_, err := config.Load("", lookupEnv)
+			Expect(err).To(MatchError(ContainSubstring("profile 'unknown' not found")))
+		})
+	})
+
+	It("reads the profile file from the home directory", func() {
+		Expect(os.Mkdir(filepath.Join(tmpDir, ".aliyun"), 0700)).To(Succeed())
+		writeFile(".aliyun/config.json", `{"profiles": [{"name": "default", "access_key_id": "home_access_key_id"}]}`)
+
+		c, err := config.Load("", lookupEnv)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.AccessKeyID).To(Equal("home_access_key_id"))
+	})
+
+	Context("with a profile file in the home directory", func() {
+		BeforeEach(func() {
+			Expect(os.Mkdir(filepath.Join(tmpDir, ".aliyun"), 0700)).To(Succeed())
+		})
+
+		It("is not used if the config file supplies credentials", func() {
+			writeFile(".aliyun/config.json", `{"profiles": [{"name": "default", "mode": "EcsRamRole", "ram_role_name": "home_role", "region_id": "cn-hangzhou"}]}`)
+			configPath := writeFile("config.json", `{"access_key_id": "file_access_key_id", "access_key_secret": "file_access_key_secret"}`)
+
+			c, err := config.Load(configPath, lookupEnv)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.CredentialsSource).To(Equal(config.CredentialsSourceStatic))
+			Expect(c.AccessKeyID).To(Equal("file_access_key_id"))
+			Expect(c.ECSRAMRoleName).To(BeEmpty())
+			Expect(c.Endpoint).To(BeEmpty())
+		})
+
+		It("ignores an unsupported mode", func() {
+			writeFile(".aliyun/config.json", `{"profiles": [{"name": "default", "mode": "ChainableRamRoleArn"}]}`)
+			env["ALI_ACCESS_KEY_ID"] = "env_access_key_id"
+
+			c, err := config.Load("", lookupEnv)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.AccessKeyID).To(Equal("env_access_key_id"))
+
+			delete(env, "ALI_ACCESS_KEY_ID")
+			_, err = config.Load("", lookupEnv)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("fails for an unsupported mode of a profile selected in the environment", func() {
+			writeFile(".aliyun/config.json", `{"profiles": [{"name": "default", "mode": "ChainableRamRoleArn"}]}`)
+			env[config.EnvProfile] = "default"
+
+			_, err := config.Load("", lookupEnv)
+			Expect(err).To(MatchError(ContainSubstring("unsupported mode 'ChainableRamRoleArn'")))
+		})
+	})
+
+	It("fails if an explicitly configured profile file does not exist", func() {
+		env[config.EnvProfileFile] = filepath.Join(tmpDir, "missing.json")
+
+		_, err := config.Load("", lookupEnv)
+		Expect(err).To(MatchError(ContainSubstring("reading profile file")))
+	})
+})
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const (
+	minPartSize = 100 * 1024
+	maxPartSize = 5 * 1024 * 1024 * 1024
+)
+
+var (
+	bucketNamePattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,61}[a-z0-9]$`)
+	// ossEndpointPattern matches the regional OSS endpoints and the global transfer acceleration endpoints
+	ossEndpointPattern = regexp.MustCompile(`^oss-([a-z]{2,}(-[a-z0-9]+)+|accelerate(-overseas)?)\.aliyuncs\.com$`)
+	// ossRegionPattern captures the region of public and internal OSS endpoints, e.g. cn-hangzhou of
+	// oss-cn-hangzhou.aliyuncs.com and oss-cn-hangzhou-internal.aliyuncs.com
+	ossRegionPattern = regexp.MustCompile(`^oss-([a-z]{2,}(?:-[a-z0-9]+)+?)(?:-internal)?\.aliyuncs\.com$`)
+)
+
+// ValidationError lists all problems found in a configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks the configuration for missing required properties, malformed values and unknown
+// properties found while parsing. All problems are returned at once as a ValidationError.
+func (config AliStorageConfig) Validate() error {
+	var problems []string
+	addProblem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	for _, name := range config.unknownProperties {
+		addProblem("unknown property '%s'", name)
+	}
+
+	switch config.CredentialsSource {
+	case "", CredentialsSourceStatic, CredentialsSourceSTS, CredentialsSourceRAMRole:
+		if config.AccessKeyID == "" {
+			addProblem("access_key_id is required")
+		}
+		if config.AccessKeySecret == "" {
+			addProblem("access_key_secret is required")
+		}
+		if config.CredentialsSource == CredentialsSourceSTS && config.SecurityToken == "" {
+			addProblem("security_token is required for credentials_source '%s'", CredentialsSourceSTS)
+		}
+		if config.CredentialsSource == CredentialsSourceRAMRole && config.RoleARN == "" {
+			addProblem("role_arn is required for credentials_source '%s'", CredentialsSourceRAMRole)
+		}
+	case CredentialsSourceECSRAMRole:
+	default:
+		addProblem("credentials_source '%s' is not one of '%s', '%s', '%s' or '%s'", config.CredentialsSource,
+			CredentialsSourceStatic, CredentialsSourceSTS, CredentialsSourceRAMRole, CredentialsSourceECSRAMRole)
+	}
+
+	if config.RoleSessionDurationSeconds != 0 && (config.RoleSessionDurationSeconds < 900 || config.RoleSessionDurationSeconds > 43200) {
+		addProblem("role_session_duration_seconds %d is not between 900 and 43200", config.RoleSessionDurationSeconds)
+	}
+
+	switch config.ServerSideEncryption {
+	case "", ServerSideEncryptionAES256, ServerSideEncryptionSM4:
+		if config.SSEKMSKeyID != "" {
+			addProblem("sse_kms_key_id requires server_side_encryption '%s'", ServerSideEncryptionKMS)
+		}
+	case ServerSideEncryptionKMS:
+	default:
+		addProblem("server_side_encryption '%s' is not one of '%s', '%s' or '%s'", config.ServerSideEncryption,
+			ServerSideEncryptionAES256, ServerSideEncryptionKMS, ServerSideEncryptionSM4)
+	}
+
+	if config.BucketName == "" {
+		addProblem("bucket_name is required")
+	} else if !bucketNamePattern.MatchString(config.BucketName) {
+		addProblem("bucket_name '%s' must be 3 to 63 lowercase letters, digits or hyphens, starting and ending with a letter or digit", config.BucketName)
+	}
+
+	if config.Endpoint == "" {
+		addProblem("endpoint is required")
+	} else {
+		problems = append(problems, config.endpointProblems()...)
+	}
+
+	switch config.AuthVersion {
+	case "", AuthVersionV1:
+	case AuthVersionV4:
+		if config.Region == "" {
+			addProblem("region is required for auth_version '%s' unless the endpoint is an OSS endpoint oss-<region>.aliyuncs.com", AuthVersionV4)
+		}
+	default:
+		addProblem("auth_version '%s' is not one of '%s' or '%s'", config.AuthVersion, AuthVersionV1, AuthVersionV4)
+	}
+
+	if config.UploadPartSize != 0 && (config.UploadPartSize < minPartSize || config.UploadPartSize > maxPartSize) {
+		addProblem("upload_part_size %d is not between %d and %d bytes", config.UploadPartSize, minPartSize, maxPartSize)
+	}
+
+	for _, property := range []struct {
+		name  string
+		value int64
+	}{
+		{"multipart_threshold", config.MultipartThreshold},
+		{"upload_concurrency", int64(config.UploadConcurrency)},
+		{"download_part_size", config.DownloadPartSize},
+		{"download_concurrency", int64(config.DownloadConcurrency)},
+		{"connect_timeout_seconds", config.ConnectTimeoutSeconds},
+		{"read_write_timeout_seconds", config.ReadWriteTimeoutSeconds},
+		{"idle_conn_timeout_seconds", config.IdleConnTimeoutSeconds},
+		{"max_idle_conns", int64(config.MaxIdleConns)},
+		{"max_idle_conns_per_host", int64(config.MaxIdleConnsPerHost)},
+		{"max_conns_per_host", int64(config.MaxConnsPerHost)},
+		{"retry_max_attempts", int64(config.RetryMaxAttempts)},
+		{"retry_base_backoff_ms", config.RetryBaseBackoffMs},
+		{"retry_max_backoff_ms", config.RetryMaxBackoffMs},
+	} {
+		if property.value < 0 {
+			addProblem("%s %d must not be negative", property.name, property.value)
+		}
+	}
+
+	if config.RetryBaseBackoffMs > 0 && config.RetryMaxBackoffMs > 0 && config.RetryBaseBackoffMs > config.RetryMaxBackoffMs {
+		addProblem("retry_base_backoff_ms %d must not be greater than retry_max_backoff_ms %d", config.RetryBaseBackoffMs, config.RetryMaxBackoffMs)
+	}
+
+	switch config.RetryJitter {
+	case "", RetryJitterFull, RetryJitterEqual, RetryJitterNone:
+	default:
+		addProblem("retry_jitter '%s' is not one of '%s', '%s' or '%s'", config.RetryJitter, RetryJitterFull, RetryJitterEqual, RetryJitterNone)
+	}
+
+	if len(problems) > 0 {
+		return ValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+// endpointProblems checks that the endpoint is a host with an optional http(s) scheme and port.
+// Endpoints of Alibaba Cloud OSS have to follow the oss-<region>.aliyuncs.com pattern.
+func (config AliStorageConfig) endpointProblems() []string {
+	endpoint := config.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return []string{fmt.Sprintf("endpoint '%s' is not a valid URL: %s", config.Endpoint, err)}
+	}
+
+	var problems []string
+	if endpointURL.Scheme != "http" && endpointURL.Scheme != "https" {
+		problems = append(problems, fmt.Sprintf("endpoint '%s' must use the http or https scheme", config.Endpoint))
+	}
+	if strings.Trim(endpointURL.Path, "/") != "" || endpointURL.RawQuery != "" {
+		problems = append(problems, fmt.Sprintf("endpoint '%s' must not contain a path or query", config.Endpoint))
+	}
+
+	host := endpointURL.Hostname()
+	if host == "" {
+		problems = append(problems, fmt.Sprintf("endpoint '%s' must contain a host", config.Endpoint))
+	} else if net.ParseIP(host) == nil && strings.HasSuffix(host, ".aliyuncs.com") {
+		if config.BucketName != "" && strings.HasPrefix(host, config.BucketName+".") {
+			problems = append(problems, fmt.Sprintf("endpoint '%s' must not contain the bucket name", config.Endpoint))
+		} else if !ossEndpointPattern.MatchString(host) {
+			problems = append(problems, fmt.Sprintf("endpoint '%s' does not match the OSS endpoint pattern oss-<region>.aliyuncs.com", config.Endpoint))
+		}
+	}
+
+	return problems
+}
+
+// endpointRegion returns the region of an OSS endpoint, or an empty string for other endpoints, e.g. IP addresses.
+func endpointRegion(endpoint string) string {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+
+	match := ossRegionPattern.FindStringSubmatch(endpointURL.Hostname())
+	if match == nil || strings.HasPrefix(match[1], "accelerate") {
+		return ""
+	}
+	return match[1]
+}
+
+// propertyName returns the JSON name of a configuration property, or an empty string for fields which are no property.
+func propertyName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
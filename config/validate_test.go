@@ -0,0 +1,165 @@
+package config_test
+
+import (
+	"bytes"
+
+	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validate", func() {
+	var c config.AliStorageConfig
+
+	BeforeEach(func() {
+		c = config.AliStorageConfig{
+			AccessKeyID:     "foo_access_key_id",
+			AccessKeySecret: "foo_access_key_secret",
+			Endpoint:        "oss-cn-hangzhou.aliyuncs.com",
+			BucketName:      "foo-bucket-name",
+		}
+	})
+
+	problems := func(err error) []string {
+		Expect(err).To(BeAssignableToTypeOf(config.ValidationError{}))
+		return err.(config.ValidationError).Problems
+	}
+
+	It("accepts a valid configuration", func() {
+		c.ApplyDefaults()
+		Expect(c.Validate()).To(Succeed())
+	})
+
+	It("accepts endpoints with scheme, port, internal and acceleration endpoints", func() {
+		for _, endpoint := range []string{
+			"https://oss-cn-hangzhou.aliyuncs.com",
+			"oss-eu-central-1-internal.aliyuncs.com",
+			"oss-accelerate.aliyuncs.com",
+			"https://oss-accelerate-overseas.aliyuncs.com",
+			"http://127.0.0.1:8080",
+			"blobs.example.com",
+		} {
+			c.Endpoint = endpoint
+			Expect(c.Validate()).To(Succeed(), endpoint)
+		}
+	})
+
+	It("reports all missing required properties at once", func() {
+		err := config.AliStorageConfig{}.Validate()
+
+		Expect(err).To(MatchError(ContainSubstring("invalid configuration")))
+		Expect(problems(err)).To(ConsistOf(
+			"access_key_id is required",
+			"access_key_secret is required",
+			"bucket_name is required",
+			"endpoint is required",
+		))
+	})
+
+	It("requires the properties of the credentials source", func() {
+		c.CredentialsSource = config.CredentialsSourceSTS
+		Expect(problems(c.Validate())).To(ConsistOf("security_token is required for credentials_source 'sts'"))
+
+		c.CredentialsSource = config.CredentialsSourceRAMRole
+		c.RoleSessionDurationSeconds = 60
+		Expect(problems(c.Validate())).To(ConsistOf(
+			"role_arn is required for credentials_source 'ram_role'",
+			"role_session_duration_seconds 60 is not between 900 and 43200",
+		))
+
+		c = config.AliStorageConfig{Endpoint: c.Endpoint, BucketName: c.BucketName, CredentialsSource: config.CredentialsSourceECSRAMRole}
+		Expect(c.Validate()).To(Succeed())
+
+		c.CredentialsSource = "magic"
+		Expect(problems(c.Validate())).To(ContainElement(ContainSubstring("credentials_source 'magic' is not one of")))
+	})
+
+	It("accepts the server-side encryption modes", func() {
+		for _, mode := range []string{"AES256", "KMS", "SM4"} {
+			c.ServerSideEncryption = mode
+			Expect(c.Validate()).To(Succeed(), mode)
+		}
+
+		c.SSEKMSKeyID = "kms-key-id"
+		Expect(c.Validate()).To(MatchError(ContainSubstring("sse_kms_key_id requires server_side_encryption 'KMS'")))
+
+		c.ServerSideEncryption = "DES"
+		Expect(problems(c.Validate())).To(ContainElement(ContainSubstring("server_side_encryption 'DES' is not one of")))
+	})
+
+	It("rejects malformed endpoints", func() {
+		c.Endpoint = "ftp://oss-cn-hangzhou.aliyuncs.com/path"
+		Expect(problems(c.Validate())).To(ConsistOf(
+			"endpoint 'ftp://oss-cn-hangzhou.aliyuncs.com/path' must use the http or https scheme",
+			"endpoint 'ftp://oss-cn-hangzhou.aliyuncs.com/path' must not contain a path or query",
+		))
+
+		c.Endpoint = "foo-bucket-name.oss-cn-hangzhou.aliyuncs.com"
+		Expect(problems(c.Validate())).To(ConsistOf("endpoint 'foo-bucket-name.oss-cn-hangzhou.aliyuncs.com' must not contain the bucket name"))
+
+		c.Endpoint = "cn-hangzhou.aliyuncs.com"
+		Expect(problems(c.Validate())).To(ConsistOf(ContainSubstring("does not match the OSS endpoint pattern")))
+	})
+
+	It("rejects invalid bucket names", func() {
+		for _, bucketName := range []string{"ab", "Foo-Bucket", "-foo", "foo_bucket"} {
+			c.BucketName = bucketName
+			Expect(problems(c.Validate())).To(ConsistOf(ContainSubstring("bucket_name '"+bucketName+"' must be")), bucketName)
+		}
+	})
+
+	It("rejects out of range transfer and connection properties", func() {
+		c.UploadPartSize = 1024
+		c.UploadConcurrency = -1
+		c.MaxConnsPerHost = -2
+
+		Expect(problems(c.Validate())).To(ConsistOf(
+			"upload_part_size 1024 is not between 102400 and 5368709120 bytes",
+			"upload_concurrency -1 must not be negative",
+			"max_conns_per_host -2 must not be negative",
+		))
+	})
+
+	It("rejects an invalid retry policy", func() {
+		c.RetryMaxAttempts = -1
+		c.RetryBaseBackoffMs = 5000
+		c.RetryMaxBackoffMs = 1000
+		c.RetryJitter = "random"
+
+		Expect(problems(c.Validate())).To(ConsistOf(
+			"retry_max_attempts -1 must not be negative",
+			"retry_base_backoff_ms 5000 must not be greater than retry_max_backoff_ms 1000",
+			"retry_jitter 'random' is not one of 'full', 'equal' or 'none'",
+		))
+	})
+
+	It("requires a region for V4 signatures unless it is derived from the endpoint", func() {
+		c.AuthVersion = config.AuthVersionV4
+		c.ApplyDefaults()
+		Expect(c.Validate()).To(Succeed())
+
+		c.Endpoint = "http://127.0.0.1:8080"
+		c.Region = ""
+		Expect(problems(c.Validate())).To(ConsistOf("region is required for auth_version 'v4' unless the endpoint is an OSS endpoint oss-<region>.aliyuncs.com"))
+
+		c.AuthVersion = "v2"
+		Expect(problems(c.Validate())).To(ConsistOf("auth_version 'v2' is not one of 'v1' or 'v4'"))
+	})
+
+	It("reports unknown properties of the parsed JSON", func() {
+		configJson := []byte(`{"access_key_id": "foo_access_key_id",
+								"access_key_secret": "foo_access_key_secret",
+								"endpoint": "oss-cn-hangzhou.aliyuncs.com",
+								"bucket_name": "foo-bucket-name",
+								"bucket": "typo",
+								"access_key": "typo"}`)
+
+		c, err := config.NewFromReader(bytes.NewReader(configJson))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(problems(c.Validate())).To(ConsistOf(
+			"unknown property 'bucket'",
+			"unknown property 'access_key'",
+		))
+	})
+})
@@ -0,0 +1,131 @@
+package credentials
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// assumeRole fetches temporary credentials of a RAM role by calling the STS AssumeRole API
+// with the configured access key.
+type assumeRole struct {
+	httpClient      *http.Client
+	endpoint        string
+	accessKeyID     string
+	accessKeySecret string
+	roleARN         string
+	roleSessionName string
+	durationSeconds int64
+}
+
+type assumeRoleResponse struct {
+	RequestId   string
+	Code        string
+	Message     string
+	Credentials struct {
+		AccessKeyId     string
+		AccessKeySecret string
+		SecurityToken   string
+		Expiration      time.Time
+	}
+}
+
+func (r assumeRole) fetch() (Credentials, error) {
+	nonce := make([]byte, 16)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	params := map[string]string{
+		"Action":           "AssumeRole",
+		"Format":           "JSON",
+		"Version":          "2015-04-01",
+		"AccessKeyId":      r.accessKeyID,
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureVersion": "1.0",
+		"SignatureNonce":   hex.EncodeToString(nonce),
+		"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		"RoleArn":          r.roleARN,
+		"RoleSessionName":  r.roleSessionName,
+	}
+	if r.durationSeconds > 0 {
+		params["DurationSeconds"] = strconv.FormatInt(r.durationSeconds, 10)
+	}
+
+	query := canonicalizedQuery(params)
+	params["Signature"] = signRPC(http.MethodGet, query, r.accessKeySecret)
+
+	response, err := r.httpClient.Get(strings.TrimSuffix(r.endpoint, "/") + "/?" + canonicalizedQuery(params))
+	if err != nil {
+		return Credentials{}, fmt.Errorf("assuming RAM role '%s': %w", r.roleARN, err)
+	}
+
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("assuming RAM role '%s': %w", r.roleARN, err)
+	}
+
+	result := assumeRoleResponse{}
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("assuming RAM role '%s': parsing response with status %d: %w", r.roleARN, response.StatusCode, err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return Credentials{}, fmt.Errorf("assuming RAM role '%s': %s: %s (RequestId: %s)", r.roleARN, result.Code, result.Message, result.RequestId)
+	}
+
+	return Credentials{
+		AccessKeyID:     result.Credentials.AccessKeyId,
+		AccessKeySecret: result.Credentials.AccessKeySecret,
+		SecurityToken:   result.Credentials.SecurityToken,
+		Expiration:      result.Credentials.Expiration,
+	}, nil
+}
+
+// canonicalizedQuery returns the parameters sorted by name and encoded as required by the RPC signature.
+func canonicalizedQuery(params map[string]string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, percentEncode(name)+"="+percentEncode(params[name]))
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// signRPC calculates the HMAC-SHA1 signature of an Alibaba Cloud RPC API request.
+func signRPC(method string, canonicalizedQuery string, accessKeySecret string) string {
+	stringToSign := method + "&" + percentEncode("/") + "&" + percentEncode(canonicalizedQuery)
+
+	mac := hmac.New(sha1.New, []byte(accessKeySecret+"&"))
+	mac.Write([]byte(stringToSign))
+
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func percentEncode(value string) string {
+	encoded := url.QueryEscape(value)
+	encoded = strings.ReplaceAll(encoded, "+", "%20")
+	encoded = strings.ReplaceAll(encoded, "*", "%2A")
+	return strings.ReplaceAll(encoded, "%7E", "~")
+}
@@ -0,0 +1,123 @@
+package credentials
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
+)
+
+// refreshBeforeExpiry is the time before the expiration at which temporary credentials are renewed
+const refreshBeforeExpiry = 5 * time.Minute
+
+// Credentials is a set of keys used to sign OSS requests.
+// Temporary credentials carry a security token and expire at Expiration.
+type Credentials struct {
+	AccessKeyID     string
+	AccessKeySecret string
+	SecurityToken   string
+	Expiration      time.Time
+}
+
+func (c Credentials) GetAccessKeyID() string {
+	return c.AccessKeyID
+}
+
+func (c Credentials) GetAccessKeySecret() string {
+	return c.AccessKeySecret
+}
+
+func (c Credentials) GetSecurityToken() string {
+	return c.SecurityToken
+}
+
+// NewProvider returns the credentials provider for the configured credentials source.
+// Temporary credentials are fetched once before returning, so that misconfigurations are reported eagerly.
+func NewProvider(storageConfig config.AliStorageConfig) (oss.CredentialsProvider, error) {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
+	switch storageConfig.CredentialsSource {
+	case "", config.CredentialsSourceStatic, config.CredentialsSourceSTS:
+		return staticProvider{credentials: Credentials{
+			AccessKeyID:     storageConfig.AccessKeyID,
+			AccessKeySecret: storageConfig.AccessKeySecret,
+			SecurityToken:   storageConfig.SecurityToken,
+		}}, nil
+
+	case config.CredentialsSourceRAMRole:
+		return newRefreshingProvider(assumeRole{
+			httpClient:      httpClient,
+			endpoint:        storageConfig.STSEndpoint,
+			accessKeyID:     storageConfig.AccessKeyID,
+			accessKeySecret: storageConfig.AccessKeySecret,
+			roleARN:         storageConfig.RoleARN,
+			roleSessionName: storageConfig.RoleSessionName,
+			durationSeconds: storageConfig.RoleSessionDurationSeconds,
+		}.fetch, time.Now)
+
+	case config.CredentialsSourceECSRAMRole:
+		return newRefreshingProvider(ecsRAMRole{
+			httpClient: httpClient,
+			endpoint:   storageConfig.MetadataEndpoint,
+			roleName:   storageConfig.ECSRAMRoleName,
+		}.fetch, time.Now)
+
+	default:
+		return nil, fmt.Errorf("unknown credentials source: '%s'", storageConfig.CredentialsSource)
+	}
+}
+
+type staticProvider struct {
+	credentials Credentials
+}
+
+func (p staticProvider) GetCredentials() oss.Credentials {
+	return p.credentials
+}
+
+type fetchFunc func() (Credentials, error)
+
+// refreshingProvider caches temporary credentials and fetches new ones shortly before they expire.
+type refreshingProvider struct {
+	fetch fetchFunc
+	now   func() time.Time
+
+	mutex       sync.Mutex
+	credentials Credentials
+}
+
+func newRefreshingProvider(fetch fetchFunc, now func() time.Time) (*refreshingProvider, error) {
+	credentials, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	return &refreshingProvider{fetch: fetch, now: now, credentials: credentials}, nil
+}
+
+// GetCredentials returns the cached credentials, renewing them when they are about to expire.
+// If the renewal fails the previous credentials are used until OSS rejects them.
+func (p *refreshingProvider) GetCredentials() oss.Credentials {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.now().Add(refreshBeforeExpiry).Before(p.credentials.Expiration) {
+		return p.credentials
+	}
+
+	credentials, err := p.fetch()
+	if err != nil {
+		slog.Warn("failed to refresh credentials",
+			slog.Time("expiration", p.credentials.Expiration),
+			slog.String("error", err.Error()),
+		)
+		return p.credentials
+	}
+
+	p.credentials = credentials
+	return p.credentials
+}
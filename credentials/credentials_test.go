@@ -0,0 +1,176 @@
+package credentials_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/credentials"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewProvider", func() {
+	var storageConfig config.AliStorageConfig
+
+	BeforeEach(func() {
+		storageConfig = config.AliStorageConfig{
+			AccessKeyID:     "foo_access_key_id",
+			AccessKeySecret: "foo_access_key_secret",
+			Endpoint:        "oss-cn-hangzhou.aliyuncs.com",
+			BucketName:      "foo-bucket-name",
+		}
+	})
+
+	Context("static credentials", func() {
+		It("returns the configured access key", func() {
+			provider, err := credentials.NewProvider(storageConfig)
+			Expect(err).ToNot(HaveOccurred())
+
+			creds := provider.GetCredentials()
+			Expect(creds.GetAccessKeyID()).To(Equal("foo_access_key_id"))
+			Expect(creds.GetAccessKeySecret()).To(Equal("foo_access_key_secret"))
+			Expect(creds.GetSecurityToken()).To(BeEmpty())
+		})
+
+		It("returns the configured security token for STS credentials", func() {
+			storageConfig.CredentialsSource = config.CredentialsSourceSTS
+			storageConfig.SecurityToken = "foo_security_token"
+
+			provider, err := credentials.NewProvider(storageConfig)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(provider.GetCredentials().GetSecurityToken()).To(Equal("foo_security_token"))
+		})
+	})
+
+	Context("ECS RAM role credentials", func() {
+		var metadataServer *httptest.Server
+		var fetches atomic.Int32
+		var expiration time.Time
+
+		BeforeEach(func() {
+			fetches.Store(0)
+			expiration = time.Now().Add(time.Hour)
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/latest/meta-data/ram/security-credentials/", func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/latest/meta-data/ram/security-credentials/" {
+					http.NotFound(w, r)
+					return
+				}
+				_, _ = fmt.Fprint(w, "discovered-role")
+			})
+			mux.HandleFunc("/latest/meta-data/ram/security-credentials/discovered-role", func(w http.ResponseWriter, r *http.Request) {
+				fetch := fetches.Add(1)
+				_, _ = fmt.Fprintf(w, `{"Code": "Success", "AccessKeyId": "ecs_key_id_%d", "AccessKeySecret": "ecs_key_secret",
+					"SecurityToken": "ecs_token", "Expiration": "%s"}`, fetch, expiration.UTC().Format(time.RFC3339))
+			})
+			metadataServer = httptest.NewServer(mux)
+
+			storageConfig.CredentialsSource = config.CredentialsSourceECSRAMRole
+			storageConfig.MetadataEndpoint = metadataServer.URL
+		})
+
+		AfterEach(func() {
+			metadataServer.Close()
+		})
+
+		It("fetches the credentials of the discovered role once while they are valid", func() {
+			provider, err := credentials.NewProvider(storageConfig)
+			Expect(err).ToNot(HaveOccurred())
+
+			creds := provider.GetCredentials()
+			Expect(creds.GetAccessKeyID()).To(Equal("ecs_key_id_1"))
+			Expect(creds.GetAccessKeySecret()).To(Equal("ecs_key_secret"))
+			Expect(creds.GetSecurityToken()).To(Equal("ecs_token"))
+
+			provider.GetCredentials()
+			Expect(fetches.Load()).To(Equal(int32(1)))
+		})
+
+		It("refreshes the credentials before they expire", func() {
+			expiration = time.Now().Add(time.Minute)
+
+			provider, err := credentials.NewProvider(storageConfig)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(provider.GetCredentials().GetAccessKeyID()).To(Equal("ecs_key_id_2"))
+			Expect(fetches.Load()).To(Equal(int32(2)))
+		})
+
+		It("fails eagerly if the configured role is not attached to the instance", func() {
+			storageConfig.ECSRAMRoleName = "unknown-role"
+
+			_, err := credentials.NewProvider(storageConfig)
+			Expect(err).To(MatchError(ContainSubstring("fetching credentials of ECS RAM role 'unknown-role'")))
+		})
+	})
+
+	Context("RAM role credentials", func() {
+		var stsServer *httptest.Server
+		var query map[string]string
+
+		BeforeEach(func() {
+			stsServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query = map[string]string{}
+				for name := range r.URL.Query() {
+					query[name] = r.URL.Query().Get(name)
+				}
+
+				if query["RoleArn"] != "acs:ram::123456:role/blobstore" {
+					w.WriteHeader(http.StatusForbidden)
+					_, _ = fmt.Fprint(w, `{"RequestId": "request-id", "Code": "NoPermission", "Message": "not allowed"}`)
+					return
+				}
+
+				_, _ = fmt.Fprintf(w, `{"RequestId": "request-id", "Credentials": {"AccessKeyId": "sts_key_id",
+					"AccessKeySecret": "sts_key_secret", "SecurityToken": "sts_token", "Expiration": "%s"}}`,
+					time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+			}))
+
+			storageConfig.CredentialsSource = config.CredentialsSourceRAMRole
+			storageConfig.RoleARN = "acs:ram::123456:role/blobstore"
+			storageConfig.RoleSessionName = "session"
+			storageConfig.RoleSessionDurationSeconds = 900
+			storageConfig.STSEndpoint = stsServer.URL
+		})
+
+		AfterEach(func() {
+			stsServer.Close()
+		})
+
+		It("assumes the role with a signed request", func() {
+			provider, err := credentials.NewProvider(storageConfig)
+			Expect(err).ToNot(HaveOccurred())
+
+			creds := provider.GetCredentials()
+			Expect(creds.GetAccessKeyID()).To(Equal("sts_key_id"))
+			Expect(creds.GetAccessKeySecret()).To(Equal("sts_key_secret"))
+			Expect(creds.GetSecurityToken()).To(Equal("sts_token"))
+
+			Expect(query).To(HaveKeyWithValue("Action", "AssumeRole"))
+			Expect(query).To(HaveKeyWithValue("AccessKeyId", "foo_access_key_id"))
+			Expect(query).To(HaveKeyWithValue("RoleSessionName", "session"))
+			Expect(query).To(HaveKeyWithValue("DurationSeconds", "900"))
+			Expect(query).To(HaveKey("Signature"))
+		})
+
+		It("returns the STS error", func() {
+			storageConfig.RoleARN = "acs:ram::123456:role/other"
+
+			_, err := credentials.NewProvider(storageConfig)
+			Expect(err).To(MatchError(ContainSubstring("NoPermission: not allowed (RequestId: request-id)")))
+		})
+	})
+
+	It("fails for an unknown credentials source", func() {
+		storageConfig.CredentialsSource = "magic"
+
+		_, err := credentials.NewProvider(storageConfig)
+		Expect(err).To(MatchError("unknown credentials source: 'magic'"))
+	})
+})
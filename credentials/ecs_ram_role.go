@@ -0,0 +1,84 @@
+package credentials
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const ecsRAMRoleCredentialsPath = "/latest/meta-data/ram/security-credentials/"
+
+// ecsRAMRole fetches the credentials of the RAM role attached to an ECS instance from the instance metadata service.
+type ecsRAMRole struct {
+	httpClient *http.Client
+	endpoint   string
+	roleName   string
+}
+
+type ecsRAMRoleResponse struct {
+	Code            string
+	AccessKeyId     string
+	AccessKeySecret string
+	SecurityToken   string
+	Expiration      time.Time
+}
+
+func (r ecsRAMRole) fetch() (Credentials, error) {
+	roleName := r.roleName
+	if roleName == "" {
+		body, err := r.get(ecsRAMRoleCredentialsPath)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("discovering ECS RAM role: %w", err)
+		}
+
+		roleName = strings.TrimSpace(string(body))
+		if roleName == "" {
+			return Credentials{}, fmt.Errorf("discovering ECS RAM role: no RAM role attached to the instance")
+		}
+	}
+
+	body, err := r.get(ecsRAMRoleCredentialsPath + roleName)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("fetching credentials of ECS RAM role '%s': %w", roleName, err)
+	}
+
+	response := ecsRAMRoleResponse{}
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("parsing credentials of ECS RAM role '%s': %w", roleName, err)
+	}
+
+	if response.Code != "Success" {
+		return Credentials{}, fmt.Errorf("fetching credentials of ECS RAM role '%s': metadata service returned code '%s'", roleName, response.Code)
+	}
+
+	return Credentials{
+		AccessKeyID:     response.AccessKeyId,
+		AccessKeySecret: response.AccessKeySecret,
+		SecurityToken:   response.SecurityToken,
+		Expiration:      response.Expiration,
+	}, nil
+}
+
+func (r ecsRAMRole) get(path string) ([]byte, error) {
+	response, err := r.httpClient.Get(strings.TrimSuffix(r.endpoint, "/") + path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata service returned status %d", response.StatusCode)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,418 @@
+package fakeoss
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	v1AuthorizationPrefix = "OSS "
+	v4Algorithm           = "OSS4-HMAC-SHA256"
+	v4DateFormat          = "20060102T150405Z"
+	v4DayFormat           = "20060102"
+	v4Product             = "oss"
+	v4Terminator          = "aliyun_v4_request"
+	v4UnsignedPayload     = "UNSIGNED-PAYLOAD"
+	// v4MaxExpires is the longest validity of presigned URLs with V4 signatures in seconds, 7 days
+	v4MaxExpires = 7 * 24 * 60 * 60
+)
+
+// v1SignedParams are the query parameters which are part of the canonicalized resource of V1 signatures,
+// the sub-resources signed by the SDK
+var v1SignedParams = map[string]bool{}
+
+func init() {
+	for _, param := range []string{
+		"acl", "uploads", "location", "cors", "logging", "website", "referer", "lifecycle", "delete", "append",
+		"tagging", "objectMeta", "uploadId", "partNumber", "security-token", "position", "img", "style",
+		"styleName", "replication", "replicationProgress", "replicationLocation", "cname", "bucketInfo", "comp",
+		"qos", "live", "status", "vod", "startTime", "endTime", "symlink", "x-oss-process",
+		"response-content-type", "x-oss-traffic-limit", "response-content-language", "response-expires",
+		"response-cache-control", "response-content-disposition", "response-content-encoding", "udf",
+		"udfName", "udfImage", "udfId", "udfImageDesc", "udfApplication", "udfApplicationLog", "restore",
+		"callback", "callback-var", "qosInfo", "policy", "stat", "encryption", "versions", "versioning",
+		"versionId", "requestPayment", "x-oss-request-payer", "sequential", "inventory", "inventoryId",
+		"continuation-token", "asyncFetch", "worm", "wormId", "wormExtend", "withHashContext",
+		"x-oss-enable-md5", "x-oss-enable-sha1", "x-oss-enable-sha256", "x-oss-hash-ctx", "x-oss-md5-ctx",
+		"transferAcceleration", "regionList", "cloudboxes", "x-oss-ac-source-ip", "x-oss-ac-subnet-mask",
+		"x-oss-ac-vpc-id", "x-oss-ac-forward-allow", "metaQuery", "resourceGroup", "rtc",
+		"x-oss-async-process", "responseHeader",
+	} {
+		v1SignedParams[param] = true
+	}
+}
+
+// authenticate verifies the signature of a request, given either in the Authorization header or, for presigned
+// URLs, in the query parameters.
+func (s *Server) authenticate(req *request) error {
+	authorization := req.Header.Get("Authorization")
+	switch {
+	case isPostObject(req):
+		// The policy of PostObject uploads and its signature are form fields, which are verified by postObject
+		return nil
+	case req.query.Has("OSSAccessKeyId"):
+		return s.verifyV1Query(req)
+	case req.query.Get("x-oss-signature-version") == v4Algorithm:
+		return s.verifyV4Query(req)
+	case strings.HasPrefix(authorization, v1AuthorizationPrefix):
+		return s.verifyV1Header(req, strings.TrimPrefix(authorization, v1AuthorizationPrefix))
+	case strings.HasPrefix(authorization, v4Algorithm+" "):
+		return s.verifyV4Header(req, strings.TrimPrefix(authorization, v4Algorithm+" "))
+	case authorization != "":
+		return errInvalidArgument("Authorization header is invalid.")
+	}
+	return &serviceError{
+		StatusCode: http.StatusForbidden,
+		Code:       "AccessDenied",
+		Message:    "You have no right to access this object because of bucket acl.",
+	}
+}
+
+// accessKeySecret returns the secret of a known access key.
+func (s *Server) accessKeySecret(accessKeyID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lockedAccessKeySecret(accessKeyID)
+}
+
+// lockedAccessKeySecret is accessKeySecret for callers which have locked the server.
+func (s *Server) lockedAccessKeySecret(accessKeyID string) (string, error) {
+	secret, ok := s.accessKeys[accessKeyID]
+	if !ok {
+		return "", &serviceError{
+			StatusCode: http.StatusForbidden,
+			Code:       "InvalidAccessKeyId",
+			Message:    "The OSS Access Key Id you provided does not exist in our records.",
+		}
+	}
+	return secret, nil
+}
+
+func (s *Server) verifyV1Header(req *request, credential string) error {
+	accessKeyID, signature, ok := strings.Cut(credential, ":")
+	if !ok {
+		return errInvalidArgument("Authorization header is invalid.")
+	}
+
+	date, err := time.Parse(http.TimeFormat, req.Header.Get("Date"))
+	if err != nil {
+		return errInvalidArgument("Date header is missing or invalid.")
+	}
+	err = checkClockSkew(date)
+	if err != nil {
+		return err
+	}
+
+	secret, err := s.accessKeySecret(accessKeyID)
+	if err != nil {
+		return err
+	}
+
+	return verifyV1Signature(req, secret, req.Header.Get("Date"), signature)
+}
+
+// verifyV1Query verifies a presigned URL, which is signed like a request with the expiry time as date.
+func (s *Server) verifyV1Query(req *request) error {
+	expires := req.query.Get("Expires")
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return errInvalidArgument("Expires parameter is missing or invalid.")
+	}
+	if time.Now().Unix() > expiresAt {
+		return &serviceError{StatusCode: http.StatusForbidden, Code: "AccessDenied", Message: "Request has expired."}
+	}
+
+	secret, err := s.accessKeySecret(req.query.Get("OSSAccessKeyId"))
+	if err != nil {
+		return err
+	}
+
+	return verifyV1Signature(req, secret, expires, req.query.Get("Signature"))
+}
+
+func verifyV1Signature(req *request, secret string, date string, signature string) error {
+	canonicalizedHeaders := ""
+	headers := ossHeaders(req.Header, false)
+	for _, name := range sortedKeys(headers) {
+		canonicalizedHeaders += name + ":" + headers[name] + "\n"
+	}
+
+	stringToSign := req.Method + "\n" +
+		req.Header.Get("Content-MD5") + "\n" +
+		req.Header.Get("Content-Type") + "\n" +
+		date + "\n" +
+		canonicalizedHeaders +
+		v1Resource(req)
+
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(stringToSign))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errSignatureDoesNotMatch(stringToSign)
+	}
+	return nil
+}
+
+// v1Resource returns the canonicalized resource of V1 signatures, the unescaped bucket and object followed by
+// the signed sub-resources with their unescaped values.
+func v1Resource(req *request) string {
+	var subResources []string
+	for _, name := range sortedKeys(req.query) {
+		if !v1SignedParams[name] {
+			continue
+		}
+		if value := req.query.Get(name); value != "" {
+			subResources = append(subResources, name+"="+value)
+		} else {
+			subResources = append(subResources, name)
+		}
+	}
+
+	resource := "/" + req.bucketName + "/" + req.key
+	if len(subResources) > 0 {
+		resource += "?" + strings.Join(subResources, "&")
+	}
+	return resource
+}
+
+// verifyV4Header verifies the V4 signature of the Authorization header, e.g.
+// OSS4-HMAC-SHA256 Credential=<id>/<day>/<region>/oss/aliyun_v4_request,AdditionalHeaders=host,Signature=<hex>
+func (s *Server) verifyV4Header(req *request, authorization string) error {
+	fields := map[string]string{}
+	for _, field := range strings.Split(authorization, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		fields[name] = value
+	}
+	if fields["Signature"] == "" {
+		return errInvalidArgument("Authorization header is invalid.")
+	}
+
+	signDate := req.Header.Get("X-Oss-Date")
+	date, err := time.Parse(v4DateFormat, signDate)
+	if signDate == "" {
+		signDate = req.Header.Get("Date")
+		date, err = time.Parse(http.TimeFormat, signDate)
+	}
+	if err != nil {
+		return errInvalidArgument("x-oss-date header is missing or invalid.")
+	}
+	err = checkClockSkew(date)
+	if err != nil {
+		return err
+	}
+
+	hashedPayload := req.Header.Get("X-Oss-Content-Sha256")
+	if hashedPayload == "" {
+		return errInvalidArgument("x-oss-content-sha256 header is missing.")
+	}
+
+	return s.verifyV4Signature(req, v4Signature{
+		credential:        fields["Credential"],
+		signDate:          signDate,
+		date:              date,
+		additionalHeaders: fields["AdditionalHeaders"],
+		query:             req.query,
+		hashedPayload:     hashedPayload,
+		signature:         fields["Signature"],
+	})
+}
+
+// verifyV4Query verifies a presigned URL with a V4 signature, which is given with its signing date, validity and
+// credential in the query parameters and signs the query parameters except for itself.
+func (s *Server) verifyV4Query(req *request) error {
+	signDate := req.query.Get("x-oss-date")
+	date, err := time.Parse(v4DateFormat, signDate)
+	if err != nil {
+		return errInvalidArgument("x-oss-date parameter is missing or invalid.")
+	}
+	expires, err := strconv.ParseInt(req.query.Get("x-oss-expires"), 10, 64)
+	if err != nil || expires < 0 || expires > v4MaxExpires {
+		return errInvalidArgument("x-oss-expires parameter is missing or invalid.")
+	}
+	if time.Now().After(date.Add(time.Duration(expires) * time.Second)) {
+		return &serviceError{StatusCode: http.StatusForbidden, Code: "AccessDenied", Message: "Request has expired."}
+	}
+	if req.query.Get("x-oss-signature") == "" {
+		return errInvalidArgument("x-oss-signature parameter is missing.")
+	}
+
+	query := url.Values{}
+	for name, values := range req.query {
+		if name != "x-oss-signature" {
+			query[name] = values
+		}
+	}
+
+	return s.verifyV4Signature(req, v4Signature{
+		credential:        req.query.Get("x-oss-credential"),
+		signDate:          signDate,
+		date:              date,
+		additionalHeaders: req.query.Get("x-oss-additional-headers"),
+		query:             query,
+		hashedPayload:     v4UnsignedPayload,
+		signature:         req.query.Get("x-oss-signature"),
+	})
+}
+
+// v4Signature is a V4 signature of a request, given either in its Authorization header or in its query.
+type v4Signature struct {
+	// credential is <id>/<day>/<region>/oss/aliyun_v4_request
+	credential string
+	signDate   string
+	date       time.Time
+	// additionalHeaders are the names of the signed headers in addition to the default ones, separated by ;
+	additionalHeaders string
+	// query are the signed query parameters
+	query         url.Values
+	hashedPayload string
+	signature     string
+}
+
+func (s *Server) verifyV4Signature(req *request, signature v4Signature) error {
+	scope := strings.Split(signature.credential, "/")
+	if len(scope) != 5 || scope[3] != v4Product || scope[4] != v4Terminator {
+		return errInvalidArgument("Credential is invalid.")
+	}
+	accessKeyID, day, region := scope[0], scope[1], scope[2]
+
+	secret, err := s.accessKeySecret(accessKeyID)
+	if err != nil {
+		return err
+	}
+
+	var additionalHeaders []string
+	if signature.additionalHeaders != "" {
+		additionalHeaders = strings.Split(signature.additionalHeaders, ";")
+	}
+
+	headers := ossHeaders(req.Header, true)
+	for _, name := range append([]string{"content-type", "content-md5"}, additionalHeaders...) {
+		value := req.Header.Get(name)
+		if name == "host" {
+			// The server moves the Host header of requests to the Host field
+			value = req.Host
+		}
+		if value != "" {
+			headers[name] = strings.TrimSpace(value)
+		}
+	}
+
+	canonicalHeaders := ""
+	for _, name := range sortedKeys(headers) {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+
+	canonicalRequest := req.Method + "\n" +
+		v4EscapePath("/"+req.bucketName+"/"+req.key) + "\n" +
+		v4CanonicalQuery(signature.query) + "\n" +
+		canonicalHeaders + "\n" +
+		strings.Join(additionalHeaders, ";") + "\n" +
+		signature.hashedPayload
+
+	hashedRequest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := v4Algorithm + "\n" +
+		signature.signDate + "\n" +
+		day + "/" + region + "/" + v4Product + "/" + v4Terminator + "\n" +
+		hex.EncodeToString(hashedRequest[:])
+
+	key := []byte("aliyun_v4" + secret)
+	for _, part := range []string{day, region, v4Product, v4Terminator} {
+		key = hmacSHA256(key, part)
+	}
+	expected := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	if day != signature.date.UTC().Format(v4DayFormat) || !hmac.Equal([]byte(expected), []byte(signature.signature)) {
+		return errSignatureDoesNotMatch(stringToSign)
+	}
+	return nil
+}
+
+// v4CanonicalQuery returns all query parameters escaped and sorted, with empty values omitted.
+func v4CanonicalQuery(query url.Values) string {
+	escaped := map[string]string{}
+	for name, values := range query {
+		escaped[url.QueryEscape(name)] = ""
+		if len(values) > 0 && values[0] != "" {
+			escaped[url.QueryEscape(name)] = "=" + strings.ReplaceAll(url.QueryEscape(values[0]), "+", "%20")
+		}
+	}
+
+	var params []string
+	for _, name := range sortedKeys(escaped) {
+		params = append(params, name+escaped[name])
+	}
+	return strings.Join(params, "&")
+}
+
+// v4EscapePath escapes a path like the query escaping of V4 signatures, except for slashes and with spaces as %20.
+func v4EscapePath(path string) string {
+	escaped := strings.ReplaceAll(url.QueryEscape(path), "+", "%20")
+	return strings.ReplaceAll(escaped, "%2F", "/")
+}
+
+// ossHeaders returns the first values of the x-oss-* headers by their lower-case names, optionally trimmed.
+func ossHeaders(header http.Header, trim bool) map[string]string {
+	headers := map[string]string{}
+	for name, values := range header {
+		name = strings.ToLower(name)
+		if !strings.HasPrefix(name, "x-oss-") {
+			continue
+		}
+		headers[name] = values[0]
+		if trim {
+			headers[name] = strings.TrimSpace(values[0])
+		}
+	}
+	return headers
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func checkClockSkew(date time.Time) error {
+	skew := time.Since(date)
+	if skew > maxClockSkew || skew < -maxClockSkew {
+		return errRequestTimeTooSkewed()
+	}
+	return nil
+}
+
+func errRequestTimeTooSkewed() *serviceError {
+	return &serviceError{
+		StatusCode: http.StatusForbidden,
+		Code:       "RequestTimeTooSkewed",
+		Message:    "The difference between the request time and the current time is too large.",
+	}
+}
+
+func errSignatureDoesNotMatch(stringToSign string) *serviceError {
+	return &serviceError{
+		StatusCode:   http.StatusForbidden,
+		Code:         "SignatureDoesNotMatch",
+		Message:      "The request signature we calculated does not match the signature you provided. Check your key and signing method.",
+		StringToSign: stringToSign,
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
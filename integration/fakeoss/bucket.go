@@ -0,0 +1,266 @@
+package fakeoss
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultMaxKeys    = 100
+	maxMaxKeys        = 1000
+	defaultMaxUploads = 1000
+)
+
+// encodeKey URL-encodes an object key or prefix in a result if the request asks for it with encoding-type=url,
+// which the SDK does for all listings.
+func encodeKey(req *request, key string) string {
+	if req.query.Get("encoding-type") == "url" {
+		return url.QueryEscape(key)
+	}
+	return key
+}
+
+type listBucketResult struct {
+	XMLName               xml.Name        `xml:"ListBucketResult"`
+	Name                  string          `xml:"Name"`
+	Prefix                string          `xml:"Prefix"`
+	ContinuationToken     string          `xml:"ContinuationToken,omitempty"`
+	StartAfter            string          `xml:"StartAfter,omitempty"`
+	MaxKeys               int             `xml:"MaxKeys"`
+	Delimiter             string          `xml:"Delimiter"`
+	EncodingType          string          `xml:"EncodingType,omitempty"`
+	IsTruncated           bool            `xml:"IsTruncated"`
+	NextContinuationToken string          `xml:"NextContinuationToken,omitempty"`
+	KeyCount              int             `xml:"KeyCount"`
+	Contents              []objectSummary `xml:"Contents"`
+	CommonPrefixes        []commonPrefix  `xml:"CommonPrefixes"`
+}
+
+type objectSummary struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Type         string `xml:"Type"`
+	Size         int    `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type commonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+// listObjectsV2 lists the objects of a bucket in lexicographical order, grouping the keys which contain the
+// delimiter after the prefix into common prefixes. Continuation tokens are the base64-encoded last key or prefix
+// of the previous page.
+func listObjectsV2(w http.ResponseWriter, req *request, b *bucket) error {
+	prefix := req.query.Get("prefix")
+	delimiter := req.query.Get("delimiter")
+
+	maxKeys := defaultMaxKeys
+	if value := req.query.Get("max-keys"); value != "" {
+		var err error
+		maxKeys, err = strconv.Atoi(value)
+		if err != nil || maxKeys < 1 || maxKeys > maxMaxKeys {
+			return errInvalidArgument(fmt.Sprintf("max-keys must be an integer between 1 and %d.", maxMaxKeys))
+		}
+	}
+
+	marker := req.query.Get("start-after")
+	if token := req.query.Get("continuation-token"); token != "" {
+		decoded, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return errInvalidArgument("The continuation token provided is incorrect.")
+		}
+		marker = max(marker, string(decoded))
+	}
+
+	result := listBucketResult{
+		Name:              req.bucketName,
+		Prefix:            encodeKey(req, prefix),
+		ContinuationToken: req.query.Get("continuation-token"),
+		StartAfter:        encodeKey(req, req.query.Get("start-after")),
+		MaxKeys:           maxKeys,
+		Delimiter:         encodeKey(req, delimiter),
+		EncodingType:      req.query.Get("encoding-type"),
+	}
+
+	last, lastCommonPrefix := "", ""
+	for _, key := range sortedKeys(b.objects) {
+		if !strings.HasPrefix(key, prefix) || key <= marker {
+			continue
+		}
+
+		keyCommonPrefix := ""
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				keyCommonPrefix = key[:len(prefix)+i+len(delimiter)]
+			}
+		}
+		if keyCommonPrefix != "" && (keyCommonPrefix <= marker || keyCommonPrefix == lastCommonPrefix) {
+			continue
+		}
+
+		if result.KeyCount == maxKeys {
+			result.IsTruncated = true
+			result.NextContinuationToken = encodeKey(req, base64.StdEncoding.EncodeToString([]byte(last)))
+			break
+		}
+		result.KeyCount++
+
+		if keyCommonPrefix != "" {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: encodeKey(req, keyCommonPrefix)})
+			last, lastCommonPrefix = keyCommonPrefix, keyCommonPrefix
+			continue
+		}
+
+		listed := b.objects[key]
+		result.Contents = append(result.Contents, objectSummary{
+			Key:          encodeKey(req, key),
+			LastModified: listed.lastModified.Format(isoTimeFormat),
+			ETag:         listed.etag,
+			Type:         listed.objectType,
+			Size:         len(listed.data),
+			StorageClass: storageClass,
+		})
+		last = key
+	}
+
+	return writeXML(w, result)
+}
+
+type listMultipartUploadsResult struct {
+	XMLName            xml.Name        `xml:"ListMultipartUploadsResult"`
+	Bucket             string          `xml:"Bucket"`
+	EncodingType       string          `xml:"EncodingType,omitempty"`
+	KeyMarker          string          `xml:"KeyMarker"`
+	UploadIDMarker     string          `xml:"UploadIdMarker"`
+	NextKeyMarker      string          `xml:"NextKeyMarker"`
+	NextUploadIDMarker string          `xml:"NextUploadIdMarker"`
+	Prefix             string          `xml:"Prefix"`
+	MaxUploads         int             `xml:"MaxUploads"`
+	IsTruncated        bool            `xml:"IsTruncated"`
+	Uploads            []uploadSummary `xml:"Upload"`
+}
+
+type uploadSummary struct {
+	Key       string `xml:"Key"`
+	UploadID  string `xml:"UploadId"`
+	Initiated string `xml:"Initiated"`
+}
+
+// listMultipartUploads lists the uploads of a bucket ordered by key and initiation time. Grouping by delimiter
+// is not supported.
+func listMultipartUploads(w http.ResponseWriter, req *request, b *bucket) error {
+	if req.query.Get("delimiter") != "" {
+		return errNotImplemented
+	}
+
+	prefix := req.query.Get("prefix")
+	keyMarker := req.query.Get("key-marker")
+	uploadIDMarker := req.query.Get("upload-id-marker")
+
+	maxUploads := defaultMaxUploads
+	if value := req.query.Get("max-uploads"); value != "" {
+		var err error
+		maxUploads, err = strconv.Atoi(value)
+		if err != nil || maxUploads < 1 || maxUploads > defaultMaxUploads {
+			return errInvalidArgument(fmt.Sprintf("max-uploads must be an integer between 1 and %d.", defaultMaxUploads))
+		}
+	}
+
+	uploadIDs := sortedKeys(b.uploads)
+	sort.SliceStable(uploadIDs, func(i, j int) bool {
+		first, second := b.uploads[uploadIDs[i]], b.uploads[uploadIDs[j]]
+		if first.key != second.key {
+			return first.key < second.key
+		}
+		return first.initiated.Before(second.initiated)
+	})
+
+	result := listMultipartUploadsResult{
+		Bucket:         req.bucketName,
+		EncodingType:   req.query.Get("encoding-type"),
+		KeyMarker:      encodeKey(req, keyMarker),
+		UploadIDMarker: uploadIDMarker,
+		Prefix:         encodeKey(req, prefix),
+		MaxUploads:     maxUploads,
+	}
+
+	for _, uploadID := range uploadIDs {
+		listed := b.uploads[uploadID]
+		if !strings.HasPrefix(listed.key, prefix) {
+			continue
+		}
+		if listed.key < keyMarker || (listed.key == keyMarker && (uploadIDMarker == "" || uploadID <= uploadIDMarker)) {
+			continue
+		}
+
+		if len(result.Uploads) == maxUploads {
+			result.IsTruncated = true
+			break
+		}
+
+		result.Uploads = append(result.Uploads, uploadSummary{
+			Key:       encodeKey(req, listed.key),
+			UploadID:  uploadID,
+			Initiated: listed.initiated.Format(isoTimeFormat),
+		})
+		result.NextKeyMarker = encodeKey(req, listed.key)
+		result.NextUploadIDMarker = uploadID
+	}
+
+	return writeXML(w, result)
+}
+
+type deleteRequest struct {
+	XMLName xml.Name `xml:"Delete"`
+	Quiet   bool     `xml:"Quiet"`
+	Objects []struct {
+		Key string `xml:"Key"`
+	} `xml:"Object"`
+}
+
+type deleteResult struct {
+	XMLName      xml.Name        `xml:"DeleteResult"`
+	EncodingType string          `xml:"EncodingType,omitempty"`
+	Deleted      []deletedObject `xml:"Deleted"`
+}
+
+type deletedObject struct {
+	Key string `xml:"Key"`
+}
+
+// deleteObjects deletes the objects listed in the body. Like OSS, keys of objects which do not exist are
+// reported as deleted.
+func deleteObjects(w http.ResponseWriter, req *request, b *bucket) error {
+	err := checkContentMD5(req)
+	if err != nil {
+		return err
+	}
+
+	var deleted deleteRequest
+	err = xml.Unmarshal(req.body, &deleted)
+	if err != nil || len(deleted.Objects) == 0 || len(deleted.Objects) > maxMaxKeys {
+		return &serviceError{
+			StatusCode: http.StatusBadRequest,
+			Code:       "MalformedXML",
+			Message:    "The XML you provided was not well-formed or did not validate against our published schema.",
+		}
+	}
+
+	result := deleteResult{EncodingType: req.query.Get("encoding-type")}
+	for _, requested := range deleted.Objects {
+		delete(b.objects, requested.Key)
+		if !deleted.Quiet {
+			result.Deleted = append(result.Deleted, deletedObject{Key: encodeKey(req, requested.Key)})
+		}
+	}
+
+	return writeXML(w, result)
+}
@@ -0,0 +1,13 @@
+package fakeoss_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestFakeOSS(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Fake OSS Suite")
+}
@@ -0,0 +1,211 @@
+package fakeoss
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Operation is the name of an OSS API operation served by the fake, as in the OSS API reference.
+type Operation string
+
+const (
+	OperationPutObject               Operation = "PutObject"
+	OperationPostObject              Operation = "PostObject"
+	OperationGetObject               Operation = "GetObject"
+	OperationHeadObject              Operation = "HeadObject"
+	OperationGetObjectMeta           Operation = "GetObjectMeta"
+	OperationDeleteObject            Operation = "DeleteObject"
+	OperationCopyObject              Operation = "CopyObject"
+	OperationInitiateMultipartUpload Operation = "InitiateMultipartUpload"
+	OperationUploadPart              Operation = "UploadPart"
+	OperationUploadPartCopy          Operation = "UploadPartCopy"
+	OperationCompleteMultipartUpload Operation = "CompleteMultipartUpload"
+	OperationAbortMultipartUpload    Operation = "AbortMultipartUpload"
+	OperationListObjectsV2           Operation = "ListObjectsV2"
+	OperationListMultipartUploads    Operation = "ListMultipartUploads"
+	OperationDeleteMultipleObjects   Operation = "DeleteMultipleObjects"
+)
+
+// FaultKind is the way a fault disturbs the requests it applies to.
+type FaultKind string
+
+const (
+	// FaultLatency delays the request by the Latency of the fault before serving it
+	FaultLatency FaultKind = "latency"
+	// FaultSlowDown rejects the request with 503 SlowDown, as OSS does when a bucket is throttled
+	FaultSlowDown FaultKind = "slow_down"
+	// FaultClockSkew rejects the request with 403 RequestTimeTooSkewed
+	FaultClockSkew FaultKind = "clock_skew"
+	// FaultDropConnection closes the connection halfway through the body of the request, or if the request has no
+	// body, halfway through the body of the response
+	FaultDropConnection FaultKind = "drop_connection"
+	// FaultTruncateBody serves the request but cuts the body of the response to half its length, with a
+	// Content-Length matching the truncated body, so that only checksums can tell
+	FaultTruncateBody FaultKind = "truncate_body"
+)
+
+// Fault is a scripted failure of the requests for an operation and object.
+type Fault struct {
+	// Operation is the operation the fault applies to, or empty for all operations
+	Operation Operation
+	// Key is the object the fault applies to, or empty for all objects and for bucket operations
+	Key string
+	// Times is the number of requests the fault applies to before it is used up, or 0 for all requests
+	Times int
+	Kind  FaultKind
+	// Latency is the delay of FaultLatency
+	Latency time.Duration
+}
+
+// injectedFault is a fault with the number of requests it still applies to, which is negative for unlimited faults.
+type injectedFault struct {
+	Fault
+	remaining int
+}
+
+// InjectFault makes the requests matching the operation and key of fault fail. Faults apply in the order of their
+// injection, one per request, until they are used up or cleared.
+func (s *Server) InjectFault(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	remaining := fault.Times
+	if remaining == 0 {
+		remaining = -1
+	}
+	s.faults = append(s.faults, &injectedFault{Fault: fault, remaining: remaining})
+}
+
+// ClearFaults removes all faults, so that all requests are served normally.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = nil
+}
+
+// takeFault returns the first fault matching a request for the given operation and key and uses it once, or nil
+// if no fault applies.
+func (s *Server) takeFault(operation Operation, key string) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, injected := range s.faults {
+		if (injected.Operation != "" && injected.Operation != operation) || (injected.Key != "" && injected.Key != key) {
+			continue
+		}
+
+		if injected.remaining > 0 {
+			injected.remaining--
+			if injected.remaining == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		fault := injected.Fault
+		return &fault
+	}
+	return nil
+}
+
+// applyFault applies a fault before the request is served. It returns the response writer to serve the request
+// with, or handled as true if the fault has already answered the request.
+func applyFault(w http.ResponseWriter, req *request, fault *Fault) (faulty http.ResponseWriter, handled bool) {
+	switch fault.Kind {
+	case FaultLatency:
+		timer := time.NewTimer(fault.Latency)
+		defer timer.Stop()
+		select {
+		case <-req.Context().Done():
+			return w, true
+		case <-timer.C:
+		}
+	case FaultSlowDown:
+		writeError(w, req, &serviceError{
+			StatusCode: http.StatusServiceUnavailable,
+			Code:       "SlowDown",
+			Message:    "Please reduce your request rate.",
+		})
+		return w, true
+	case FaultClockSkew:
+		writeError(w, req, errRequestTimeTooSkewed())
+		return w, true
+	case FaultDropConnection:
+		if req.ContentLength > 0 {
+			_, _ = io.CopyN(io.Discard, req.Body, req.ContentLength/2)
+			panic(http.ErrAbortHandler)
+		}
+		return &faultyResponseWriter{ResponseWriter: w, kind: fault.Kind}, false
+	case FaultTruncateBody:
+		return &faultyResponseWriter{ResponseWriter: w, kind: fault.Kind}, false
+	}
+	return w, false
+}
+
+// faultyResponseWriter passes only the first half of the body of a response, as given by its Content-Length, and
+// then drops the connection or discards the rest of the body.
+type faultyResponseWriter struct {
+	http.ResponseWriter
+	kind        FaultKind
+	wroteHeader bool
+	dropped     bool
+	// remaining is the number of bytes of the body still passed
+	remaining int64
+	passed    int64
+}
+
+func (w *faultyResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	length, err := strconv.ParseInt(w.Header().Get("Content-Length"), 10, 64)
+	if err == nil {
+		w.remaining = length / 2
+	}
+	if w.kind == FaultTruncateBody {
+		w.Header().Set("Content-Length", strconv.FormatInt(w.remaining, 10))
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *faultyResponseWriter) Write(body []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	passed := min(int64(len(body)), w.remaining)
+	_, err := w.ResponseWriter.Write(body[:passed])
+	if err != nil {
+		return 0, err
+	}
+	w.remaining -= passed
+	w.passed += passed
+
+	if passed < int64(len(body)) && w.kind == FaultDropConnection {
+		w.drop()
+	}
+	return len(body), nil
+}
+
+// finish drops the connection after a response whose body was shorter than the half to pass, e.g. for a response
+// without body.
+func (w *faultyResponseWriter) finish() {
+	if w.kind == FaultDropConnection && !w.dropped {
+		w.drop()
+	}
+}
+
+// drop sends the passed part of the response and closes the connection. Without a passed part of the body,
+// the connection is closed before the response, as a response without body would be complete with its header.
+func (w *faultyResponseWriter) drop() {
+	w.dropped = true
+	if w.passed > 0 {
+		if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	}
+	panic(http.ErrAbortHandler)
+}
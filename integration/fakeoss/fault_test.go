@@ -0,0 +1,138 @@
+package fakeoss_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/integration/fakeoss"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fault injection", func() {
+	var server *fakeoss.Server
+	var bucket *oss.Bucket
+
+	BeforeEach(func() {
+		server = fakeoss.NewServer()
+		server.AddAccessKey(accessKeyID, accessKeySecret)
+		server.CreateBucket(bucketName)
+		DeferCleanup(server.Close)
+
+		client, err := oss.New(server.URL(), accessKeyID, accessKeySecret)
+		Expect(err).ToNot(HaveOccurred())
+		bucket, err = client.Bucket(bucketName)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(bucket.PutObject("existing", strings.NewReader("0123456789"))).To(Succeed())
+	})
+
+	serviceErrorCode := func(err error) string {
+		var serviceError oss.ServiceError
+		Expect(errors.As(err, &serviceError)).To(BeTrue(), "expected a service error, got %v", err)
+		return serviceError.Code
+	}
+
+	It("fails the requests for an operation the given number of times", func() {
+		server.InjectFault(fakeoss.Fault{Operation: fakeoss.OperationPutObject, Times: 2, Kind: fakeoss.FaultSlowDown})
+
+		for i := 0; i < 2; i++ {
+			err := bucket.PutObject("put", strings.NewReader("foo"))
+			Expect(serviceErrorCode(err)).To(Equal("SlowDown"))
+		}
+		Expect(bucket.PutObject("put", strings.NewReader("foo"))).To(Succeed())
+
+		_, err := bucket.GetObjectDetailedMeta("put")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("only fails the requests for the given key and operation until the faults are cleared", func() {
+		server.InjectFault(fakeoss.Fault{Operation: fakeoss.OperationGetObject, Key: "existing", Kind: fakeoss.FaultClockSkew})
+
+		_, err := bucket.GetObject("existing")
+		Expect(serviceErrorCode(err)).To(Equal("RequestTimeTooSkewed"))
+
+		_, err = bucket.GetObjectDetailedMeta("existing")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(bucket.PutObject("other", strings.NewReader("foo"))).To(Succeed())
+		body, err := bucket.GetObject("other")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(body.Close()).To(Succeed())
+
+		server.ClearFaults()
+		body, err = bucket.GetObject("existing")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(body.Close()).To(Succeed())
+	})
+
+	It("drops the connection while receiving a request body, without storing the object", func() {
+		server.InjectFault(fakeoss.Fault{Operation: fakeoss.OperationPutObject, Times: 1, Kind: fakeoss.FaultDropConnection})
+
+		err := bucket.PutObject("put", strings.NewReader(strings.Repeat("x", 1024*1024)))
+		Expect(err).To(HaveOccurred())
+		var serviceError oss.ServiceError
+		Expect(errors.As(err, &serviceError)).To(BeFalse())
+
+		Expect(server.ObjectKeys(bucketName)).To(Equal([]string{"existing"}))
+	})
+
+	It("drops the connection halfway through a response body", func() {
+		server.InjectFault(fakeoss.Fault{Operation: fakeoss.OperationGetObject, Times: 1, Kind: fakeoss.FaultDropConnection})
+
+		response, err := http.Get(signedURL(bucket, "existing"))
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+
+		content, err := io.ReadAll(response.Body)
+		Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+		Expect(string(content)).To(Equal("01234"))
+	})
+
+	It("drops the connection before responding to a request without body", func() {
+		// The HTTP client of the SDK resends idempotent requests once if a reused connection is dropped
+		server.InjectFault(fakeoss.Fault{Operation: fakeoss.OperationHeadObject, Kind: fakeoss.FaultDropConnection})
+
+		_, err := bucket.GetObjectDetailedMeta("existing")
+		Expect(err).To(HaveOccurred())
+
+		server.ClearFaults()
+		_, err = bucket.GetObjectDetailedMeta("existing")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("truncates a response body with a matching Content-Length", func() {
+		server.InjectFault(fakeoss.Fault{Operation: fakeoss.OperationGetObject, Times: 1, Kind: fakeoss.FaultTruncateBody})
+
+		response, err := http.Get(signedURL(bucket, "existing"))
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+
+		Expect(response.ContentLength).To(Equal(int64(5)))
+		content, err := io.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal("01234"))
+	})
+
+	It("delays requests", func() {
+		server.InjectFault(fakeoss.Fault{Operation: fakeoss.OperationGetObject, Kind: fakeoss.FaultLatency, Latency: 500 * time.Millisecond})
+
+		startedAt := time.Now()
+		body, err := bucket.GetObject("existing")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(body.Close()).To(Succeed())
+		Expect(time.Since(startedAt)).To(BeNumerically(">=", 500*time.Millisecond))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, signedURL(bucket, "existing"), nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = http.DefaultClient.Do(request)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+})
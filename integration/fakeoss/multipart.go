@@ -0,0 +1,230 @@
+package fakeoss
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash/crc64"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// minPartSize is the minimum size of all parts of a multipart upload except for the last one
+	minPartSize = 100 * 1024
+	maxParts    = 10000
+)
+
+type upload struct {
+	key       string
+	header    http.Header
+	initiated time.Time
+	parts     map[int]*part
+}
+
+type part struct {
+	data []byte
+	etag string
+}
+
+type initiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+func (s *Server) initiateMultipartUpload(w http.ResponseWriter, req *request, b *bucket) error {
+	uploadID := newRequestID() + newRequestID()[:8]
+	b.uploads[uploadID] = &upload{
+		key:       req.key,
+		header:    objectHeader(req.Header),
+		initiated: time.Now().UTC(),
+		parts:     map[int]*part{},
+	}
+
+	return writeXML(w, initiateMultipartUploadResult{
+		Bucket:   req.bucketName,
+		Key:      encodeKey(req, req.key),
+		UploadID: uploadID,
+	})
+}
+
+// findUpload returns the multipart upload of the uploadId parameter, which has to be an upload of the requested object.
+func findUpload(req *request, b *bucket) (*upload, error) {
+	found, ok := b.uploads[req.query.Get("uploadId")]
+	if !ok || found.key != req.key {
+		return nil, errNoSuchUpload()
+	}
+	return found, nil
+}
+
+type copyPartResult struct {
+	XMLName      xml.Name `xml:"CopyPartResult"`
+	LastModified string   `xml:"LastModified"`
+	ETag         string   `xml:"ETag"`
+}
+
+// uploadPart stores the body of the request as part, or with a x-oss-copy-source header, the byte range of the
+// x-oss-copy-source-range header of another object.
+func (s *Server) uploadPart(w http.ResponseWriter, req *request, b *bucket) error {
+	found, err := findUpload(req, b)
+	if err != nil {
+		return err
+	}
+
+	partNumber, err := strconv.Atoi(req.query.Get("partNumber"))
+	if err != nil || partNumber < 1 || partNumber > maxParts {
+		return errInvalidArgument(fmt.Sprintf("Part number must be an integer between 1 and %d, inclusive.", maxParts))
+	}
+
+	if req.Header.Get("X-Oss-Copy-Source") == "" {
+		err = checkContentMD5(req)
+		if err != nil {
+			return err
+		}
+
+		uploaded := newPart(req.body)
+		found.parts[partNumber] = uploaded
+
+		w.Header().Set("ETag", uploaded.etag)
+		w.Header().Set("X-Oss-Hash-Crc64ecma", strconv.FormatUint(crc64.Checksum(uploaded.data, crcTable), 10))
+		return nil
+	}
+
+	source, err := s.copySource(req)
+	if err != nil {
+		return err
+	}
+
+	data := source.data
+	if sourceRange := req.Header.Get("X-Oss-Copy-Source-Range"); sourceRange != "" {
+		start, end, ranged, err := parseRange(sourceRange, int64(len(source.data)))
+		if err != nil {
+			return err
+		}
+		if ranged {
+			data = source.data[start : end+1]
+		}
+	}
+
+	copied := newPart(data)
+	found.parts[partNumber] = copied
+
+	return writeXML(w, copyPartResult{
+		LastModified: time.Now().UTC().Format(isoTimeFormat),
+		ETag:         copied.etag,
+	})
+}
+
+func newPart(data []byte) *part {
+	sum := md5.Sum(data)
+	return &part{data: data, etag: `"` + strings.ToUpper(hex.EncodeToString(sum[:])) + `"`}
+}
+
+type completeMultipartUploadRequest struct {
+	XMLName xml.Name       `xml:"CompleteMultipartUpload"`
+	Parts   []completePart `xml:"Part"`
+}
+
+type completePart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUploadResult struct {
+	XMLName      xml.Name `xml:"CompleteMultipartUploadResult"`
+	EncodingType string   `xml:"EncodingType,omitempty"`
+	Location     string   `xml:"Location"`
+	Bucket       string   `xml:"Bucket"`
+	Key          string   `xml:"Key"`
+	ETag         string   `xml:"ETag"`
+}
+
+// completeMultipartUpload assembles the parts listed in the body, or with the x-oss-complete-all header, all parts
+// of the upload. Like OSS, the ETag of the object is the MD5 of the MD5s of its parts followed by the number of parts.
+func completeMultipartUpload(w http.ResponseWriter, req *request, b *bucket) error {
+	found, err := findUpload(req, b)
+	if err != nil {
+		return err
+	}
+
+	var completed completeMultipartUploadRequest
+	if strings.EqualFold(req.Header.Get("X-Oss-Complete-All"), "yes") {
+		for partNumber, uploaded := range found.parts {
+			completed.Parts = append(completed.Parts, completePart{PartNumber: partNumber, ETag: uploaded.etag})
+		}
+		sort.Slice(completed.Parts, func(i, j int) bool { return completed.Parts[i].PartNumber < completed.Parts[j].PartNumber })
+	} else if err := xml.Unmarshal(req.body, &completed); err != nil || len(completed.Parts) == 0 {
+		return &serviceError{
+			StatusCode: http.StatusBadRequest,
+			Code:       "MalformedXML",
+			Message:    "The XML you provided was not well-formed or did not validate against our published schema.",
+		}
+	}
+
+	var data bytes.Buffer
+	partMD5s := md5.New()
+	for i, completedPart := range completed.Parts {
+		if i > 0 && completedPart.PartNumber <= completed.Parts[i-1].PartNumber {
+			return &serviceError{
+				StatusCode: http.StatusBadRequest,
+				Code:       "InvalidPartOrder",
+				Message:    "The list of parts was not in ascending order. The parts list must be specified in order by part number.",
+			}
+		}
+
+		uploaded, ok := found.parts[completedPart.PartNumber]
+		if !ok || !etagMatches(completedPart.ETag, uploaded.etag) {
+			return &serviceError{
+				StatusCode: http.StatusBadRequest,
+				Code:       "InvalidPart",
+				Message:    "One or more of the specified parts could not be found or the specified entity tag might not have matched the part's entity tag.",
+			}
+		}
+		if i < len(completed.Parts)-1 && len(uploaded.data) < minPartSize {
+			return &serviceError{
+				StatusCode: http.StatusBadRequest,
+				Code:       "EntityTooSmall",
+				Message:    "Your proposed upload is smaller than the minimum allowed size.",
+			}
+		}
+
+		data.Write(uploaded.data)
+		sum, _ := hex.DecodeString(strings.Trim(uploaded.etag, `"`))
+		partMD5s.Write(sum)
+	}
+
+	assembled := newObject(data.Bytes(), found.header)
+	assembled.etag = fmt.Sprintf(`"%s-%d"`, strings.ToUpper(hex.EncodeToString(partMD5s.Sum(nil))), len(completed.Parts))
+	assembled.contentMD5 = ""
+	assembled.objectType = objectTypeMultipart
+	b.objects[req.key] = assembled
+	delete(b.uploads, req.query.Get("uploadId"))
+
+	w.Header().Set("ETag", assembled.etag)
+	w.Header().Set("X-Oss-Hash-Crc64ecma", strconv.FormatUint(assembled.crc64, 10))
+	return writeXML(w, completeMultipartUploadResult{
+		EncodingType: req.query.Get("encoding-type"),
+		Location:     "http://" + req.Host + "/" + req.bucketName + "/" + req.key,
+		Bucket:       req.bucketName,
+		Key:          encodeKey(req, req.key),
+		ETag:         assembled.etag,
+	})
+}
+
+func abortMultipartUpload(w http.ResponseWriter, req *request, b *bucket) error {
+	_, err := findUpload(req, b)
+	if err != nil {
+		return err
+	}
+
+	delete(b.uploads, req.query.Get("uploadId"))
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
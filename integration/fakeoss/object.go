@@ -0,0 +1,302 @@
+package fakeoss
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash/crc64"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	objectTypeNormal    = "Normal"
+	objectTypeMultipart = "Multipart"
+	storageClass        = "Standard"
+
+	// isoTimeFormat is the format of times in XML documents
+	isoTimeFormat = "2006-01-02T15:04:05.000Z"
+)
+
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+// storedHeaders are the request headers which are stored with an object and returned when getting it,
+// in addition to the user metadata
+var storedHeaders = []string{
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Language",
+	"Content-Type",
+	"Expires",
+	"X-Oss-Server-Side-Encryption",
+	"X-Oss-Server-Side-Encryption-Key-Id",
+	"X-Oss-Server-Side-Data-Encryption",
+}
+
+// object is an immutable object, which is replaced as a whole when it is overwritten.
+type object struct {
+	data   []byte
+	header http.Header
+	etag   string
+	// contentMD5 is only known for objects uploaded with a single request
+	contentMD5   string
+	crc64        uint64
+	objectType   string
+	lastModified time.Time
+}
+
+func newObject(data []byte, header http.Header) *object {
+	sum := md5.Sum(data)
+	return &object{
+		data:         data,
+		header:       header,
+		etag:         `"` + strings.ToUpper(hex.EncodeToString(sum[:])) + `"`,
+		contentMD5:   base64.StdEncoding.EncodeToString(sum[:]),
+		crc64:        crc64.Checksum(data, crcTable),
+		objectType:   objectTypeNormal,
+		lastModified: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// objectHeader returns the headers of a request which are stored with the object it uploads.
+func objectHeader(requestHeader http.Header) http.Header {
+	header := http.Header{}
+	for _, name := range storedHeaders {
+		if value := requestHeader.Get(name); value != "" {
+			header.Set(name, value)
+		}
+	}
+	for name, values := range requestHeader {
+		if strings.HasPrefix(strings.ToLower(name), "x-oss-meta-") {
+			header[name] = values
+		}
+	}
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "application/octet-stream")
+	}
+	return header
+}
+
+func putObject(w http.ResponseWriter, req *request, b *bucket) error {
+	err := checkContentMD5(req)
+	if err != nil {
+		return err
+	}
+
+	uploaded := newObject(req.body, objectHeader(req.Header))
+	b.objects[req.key] = uploaded
+
+	w.Header().Set("ETag", uploaded.etag)
+	w.Header().Set("Content-MD5", uploaded.contentMD5)
+	w.Header().Set("X-Oss-Hash-Crc64ecma", strconv.FormatUint(uploaded.crc64, 10))
+	return nil
+}
+
+// getObject serves GET and HEAD requests for an object, optionally for a single byte range.
+func getObject(w http.ResponseWriter, req *request, b *bucket) error {
+	found, ok := b.objects[req.key]
+	if !ok {
+		return errNoSuchKey(req.key)
+	}
+
+	ifMatch := req.Header.Get("If-Match")
+	if ifMatch != "" && !etagMatches(ifMatch, found.etag) {
+		return errPreconditionFailed()
+	}
+
+	header := w.Header()
+	for name, values := range found.header {
+		header[name] = values
+	}
+	header.Set("ETag", found.etag)
+	header.Set("Last-Modified", found.lastModified.Format(http.TimeFormat))
+	header.Set("Accept-Ranges", "bytes")
+	header.Set("X-Oss-Object-Type", found.objectType)
+	header.Set("X-Oss-Storage-Class", storageClass)
+	header.Set("X-Oss-Hash-Crc64ecma", strconv.FormatUint(found.crc64, 10))
+
+	if req.Method == http.MethodGet {
+		for name := range req.query {
+			if strings.HasPrefix(name, "response-") {
+				header.Set(strings.TrimPrefix(name, "response-"), req.query.Get(name))
+			}
+		}
+	}
+
+	start, end, ranged, err := parseRange(req.Header.Get("Range"), int64(len(found.data)))
+	if err != nil {
+		return err
+	}
+
+	status := http.StatusOK
+	body := found.data
+	if ranged {
+		status = http.StatusPartialContent
+		body = found.data[start : end+1]
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(found.data)))
+	} else if found.contentMD5 != "" {
+		header.Set("Content-MD5", found.contentMD5)
+	}
+
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	if req.Method == http.MethodGet {
+		_, _ = w.Write(body)
+	}
+	return nil
+}
+
+// parseRange returns the first and last byte of a range like bytes=0-99, bytes=100- or bytes=-100. Like OSS,
+// it ignores malformed ranges and ranges of multiple parts, returning ranged as false.
+func parseRange(value string, size int64) (start int64, end int64, ranged bool, err error) {
+	spec, ok := strings.CutPrefix(value, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, false, nil
+	}
+
+	first, last, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, 0, false, nil
+	}
+
+	switch {
+	case first == "":
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix <= 0 {
+			return 0, 0, false, nil
+		}
+		start, end = max(size-suffix, 0), size-1
+	case last == "":
+		start, err = strconv.ParseInt(first, 10, 64)
+		if err != nil {
+			return 0, 0, false, nil
+		}
+		end = size - 1
+	default:
+		start, err = strconv.ParseInt(first, 10, 64)
+		if err != nil {
+			return 0, 0, false, nil
+		}
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false, nil
+		}
+		end = min(end, size-1)
+	}
+
+	if start >= size {
+		return 0, 0, false, &serviceError{
+			StatusCode: http.StatusRequestedRangeNotSatisfiable,
+			Code:       "InvalidRange",
+			Message:    "The requested range cannot be satisfied",
+		}
+	}
+	return start, end, true, nil
+}
+
+type copyObjectResult struct {
+	XMLName      xml.Name `xml:"CopyObjectResult"`
+	LastModified string   `xml:"LastModified"`
+	ETag         string   `xml:"ETag"`
+}
+
+// copyObject copies the object of the x-oss-copy-source header. Unless the metadata directive is REPLACE, the
+// copy keeps the headers and user metadata of the source object.
+func (s *Server) copyObject(w http.ResponseWriter, req *request, b *bucket) error {
+	source, err := s.copySource(req)
+	if err != nil {
+		return err
+	}
+
+	var header http.Header
+	if strings.EqualFold(req.Header.Get("X-Oss-Metadata-Directive"), "REPLACE") {
+		header = objectHeader(req.Header)
+	} else {
+		header = source.header.Clone()
+		for _, name := range storedHeaders {
+			if strings.HasPrefix(name, "X-Oss-Server-Side-") {
+				header.Del(name)
+				if value := req.Header.Get(name); value != "" {
+					header.Set(name, value)
+				}
+			}
+		}
+	}
+
+	copied := *source
+	copied.header = header
+	copied.lastModified = time.Now().UTC().Truncate(time.Second)
+	b.objects[req.key] = &copied
+
+	return writeXML(w, copyObjectResult{LastModified: copied.lastModified.Format(isoTimeFormat), ETag: copied.etag})
+}
+
+// copySource returns the source object of a copy request, identified by the x-oss-copy-source header in the format
+// /<bucket>/<URL-encoded object>, if it matches the x-oss-copy-source-if-match header.
+func (s *Server) copySource(req *request) (*object, error) {
+	bucketName, escapedKey, _ := strings.Cut(strings.TrimPrefix(req.Header.Get("X-Oss-Copy-Source"), "/"), "/")
+	key, err := url.QueryUnescape(escapedKey)
+	if err != nil || key == "" {
+		return nil, errInvalidArgument("Copy Source must mention the source bucket and key: /sourcebucket/sourcekey.")
+	}
+
+	sourceBucket, ok := s.buckets[bucketName]
+	if !ok {
+		return nil, &serviceError{
+			StatusCode: http.StatusNotFound,
+			Code:       "NoSuchBucket",
+			Message:    "The specified bucket does not exist.",
+			BucketName: bucketName,
+		}
+	}
+
+	source, ok := sourceBucket.objects[key]
+	if !ok {
+		return nil, errNoSuchKey(key)
+	}
+
+	ifMatch := req.Header.Get("X-Oss-Copy-Source-If-Match")
+	if ifMatch != "" && !etagMatches(ifMatch, source.etag) {
+		return nil, errPreconditionFailed()
+	}
+
+	return source, nil
+}
+
+// checkContentMD5 verifies the body of a request against its Content-MD5 header, if any.
+func checkContentMD5(req *request) error {
+	contentMD5 := req.Header.Get("Content-MD5")
+	if contentMD5 == "" {
+		return nil
+	}
+
+	sum := md5.Sum(req.body)
+	if contentMD5 != base64.StdEncoding.EncodeToString(sum[:]) {
+		return &serviceError{
+			StatusCode: http.StatusBadRequest,
+			Code:       "InvalidDigest",
+			Message:    "The Content-MD5 you specified was invalid.",
+		}
+	}
+	return nil
+}
+
+func etagMatches(condition string, etag string) bool {
+	return strings.EqualFold(strings.Trim(condition, `"`), strings.Trim(etag, `"`))
+}
+
+// hasOnlyResponseOverrides tells whether all query parameters override headers of the response.
+func hasOnlyResponseOverrides(query url.Values) bool {
+	for name := range query {
+		if !strings.HasPrefix(name, "response-") {
+			return false
+		}
+	}
+	return len(query) > 0
+}
@@ -0,0 +1,237 @@
+package fakeoss
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// postFileName is replaced in the key field of PostObject uploads with the file name of the file field
+const postFileName = "${filename}"
+
+// isPostObject tells whether a request is a PostObject upload, a form posted to a bucket.
+func isPostObject(req *request) bool {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return req.Method == http.MethodPost && req.key == "" && len(req.query) == 0 && mediaType == "multipart/form-data"
+}
+
+// postObject serves a PostObject upload. The form fields before the file field carry the key, the policy and its
+// signature along with the headers stored with the object. It is served with the server locked.
+func (s *Server) postObject(w http.ResponseWriter, req *request, b *bucket) error {
+	_, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || params["boundary"] == "" {
+		return errInvalidArgument("Content-Type of the form is invalid.")
+	}
+
+	// Like the headers they stand for, the names of form fields are case-insensitive
+	fields := map[string]string{}
+	var content []byte
+	var fileName string
+	form := multipart.NewReader(bytes.NewReader(req.body), params["boundary"])
+	for {
+		part, err := form.NextPart()
+		if err == io.EOF {
+			return errInvalidArgument("The file field is missing.")
+		}
+		if err != nil {
+			return errInvalidArgument("The form is malformed.")
+		}
+
+		value, err := io.ReadAll(part)
+		if err != nil {
+			return errInvalidArgument("The form is malformed.")
+		}
+		// Fields after the file field are ignored
+		if strings.EqualFold(part.FormName(), "file") {
+			content, fileName = value, part.FileName()
+			break
+		}
+		fields[strings.ToLower(part.FormName())] = string(value)
+	}
+
+	key := strings.ReplaceAll(fields["key"], postFileName, fileName)
+	if key == "" {
+		return errInvalidArgument("The key field is missing.")
+	}
+
+	encodedPolicy, err := s.verifyPostSignature(fields)
+	if err != nil {
+		return err
+	}
+	err = checkPostPolicy(encodedPolicy, req.bucketName, key, fields, len(content))
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	for name, value := range fields {
+		header.Set(name, value)
+	}
+	uploaded := newObject(content, objectHeader(header))
+	b.objects[key] = uploaded
+
+	w.Header().Set("ETag", uploaded.etag)
+	w.Header().Set("Content-MD5", uploaded.contentMD5)
+	w.Header().Set("X-Oss-Hash-Crc64ecma", strconv.FormatUint(uploaded.crc64, 10))
+	switch fields["success_action_status"] {
+	case "200":
+		w.WriteHeader(http.StatusOK)
+	case "201":
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+	return nil
+}
+
+// verifyPostSignature verifies the V1 or V4 signature of the policy of a PostObject upload and returns the policy.
+func (s *Server) verifyPostSignature(fields map[string]string) (string, error) {
+	encodedPolicy := fields["policy"]
+	if encodedPolicy == "" {
+		return "", &serviceError{StatusCode: http.StatusForbidden, Code: "AccessDenied", Message: "The policy field is missing."}
+	}
+
+	var expected, signature string
+	if fields["x-oss-signature-version"] == v4Algorithm {
+		scope := strings.Split(fields["x-oss-credential"], "/")
+		if len(scope) != 5 || scope[3] != v4Product || scope[4] != v4Terminator {
+			return "", errInvalidArgument("Credential is invalid.")
+		}
+
+		secret, err := s.lockedAccessKeySecret(scope[0])
+		if err != nil {
+			return "", err
+		}
+
+		key := []byte("aliyun_v4" + secret)
+		for _, part := range scope[1:] {
+			key = hmacSHA256(key, part)
+		}
+		expected = hex.EncodeToString(hmacSHA256(key, encodedPolicy))
+		signature = fields["x-oss-signature"]
+	} else {
+		secret, err := s.lockedAccessKeySecret(fields["ossaccesskeyid"])
+		if err != nil {
+			return "", err
+		}
+
+		mac := hmac.New(sha1.New, []byte(secret))
+		mac.Write([]byte(encodedPolicy))
+		expected = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		signature = fields["signature"]
+	}
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "", errSignatureDoesNotMatch(encodedPolicy)
+	}
+	return encodedPolicy, nil
+}
+
+// checkPostPolicy checks the expiration and the conditions of a PostObject policy against the upload. Conditions
+// are either objects with the exact values of fields, or arrays of eq or starts-with, a $-prefixed field and the
+// value, or arrays of content-length-range, the minimum and the maximum size.
+func checkPostPolicy(encodedPolicy string, bucketName string, key string, fields map[string]string, size int) error {
+	policyJSON, err := base64.StdEncoding.DecodeString(encodedPolicy)
+	if err != nil {
+		return errInvalidPolicy("Policy is not base64 encoded.")
+	}
+
+	var policy struct {
+		Expiration string            `json:"expiration"`
+		Conditions []json.RawMessage `json:"conditions"`
+	}
+	err = json.Unmarshal(policyJSON, &policy)
+	if err != nil {
+		return errInvalidPolicy("Policy is not valid JSON.")
+	}
+
+	expiration, err := time.Parse(time.RFC3339, policy.Expiration)
+	if err != nil {
+		return errInvalidPolicy("Policy expiration is invalid.")
+	}
+	if time.Now().After(expiration) {
+		return &serviceError{StatusCode: http.StatusForbidden, Code: "AccessDenied", Message: "Invalid according to Policy: Policy expired."}
+	}
+
+	value := func(field string) string {
+		field = strings.ToLower(strings.TrimPrefix(field, "$"))
+		switch field {
+		case "bucket":
+			return bucketName
+		case "key":
+			return key
+		}
+		return fields[field]
+	}
+
+	for _, raw := range policy.Conditions {
+		var exact map[string]string
+		if json.Unmarshal(raw, &exact) == nil {
+			for field, expected := range exact {
+				if value(field) != expected {
+					return errPolicyConditionFailed(raw)
+				}
+			}
+			continue
+		}
+
+		var condition []any
+		if json.Unmarshal(raw, &condition) != nil || len(condition) != 3 {
+			return errInvalidPolicy(fmt.Sprintf("Condition %s is invalid.", raw))
+		}
+
+		operator, _ := condition[0].(string)
+		if strings.EqualFold(operator, "content-length-range") {
+			minSize, minOK := condition[1].(float64)
+			maxSize, maxOK := condition[2].(float64)
+			switch {
+			case !minOK || !maxOK:
+				return errInvalidPolicy(fmt.Sprintf("Condition %s is invalid.", raw))
+			case float64(size) < minSize:
+				return &serviceError{StatusCode: http.StatusBadRequest, Code: "EntityTooSmall", Message: "Your proposed upload is smaller than the minimum allowed size."}
+			case float64(size) > maxSize:
+				return &serviceError{StatusCode: http.StatusBadRequest, Code: "EntityTooLarge", Message: "Your proposed upload exceeds the maximum allowed size."}
+			}
+			continue
+		}
+
+		field, _ := condition[1].(string)
+		expected, _ := condition[2].(string)
+		switch strings.ToLower(operator) {
+		case "eq":
+			if value(field) != expected {
+				return errPolicyConditionFailed(raw)
+			}
+		case "starts-with":
+			if !strings.HasPrefix(value(field), expected) {
+				return errPolicyConditionFailed(raw)
+			}
+		default:
+			return errInvalidPolicy(fmt.Sprintf("Condition %s is invalid.", raw))
+		}
+	}
+	return nil
+}
+
+func errInvalidPolicy(message string) *serviceError {
+	return &serviceError{StatusCode: http.StatusBadRequest, Code: "InvalidPolicyDocument", Message: message}
+}
+
+func errPolicyConditionFailed(condition json.RawMessage) *serviceError {
+	return &serviceError{
+		StatusCode: http.StatusForbidden,
+		Code:       "AccessDenied",
+		Message:    "Invalid according to Policy: Policy Condition failed: " + string(condition),
+	}
+}
@@ -0,0 +1,381 @@
+// Package fakeoss is an in-memory fake of the Alibaba Cloud OSS API for hermetic tests. It serves the object,
+// multipart and listing requests sent by the OSS SDK and verifies their V1 or V4 signatures.
+package fakeoss
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxClockSkew is the maximum difference between the date of a request and the clock of the server
+const maxClockSkew = 15 * time.Minute
+
+// Server is a fake OSS endpoint serving path-style requests, i.e. http://<endpoint>/<bucket>/<object>, which the
+// SDK sends to endpoints given as IP address.
+type Server struct {
+	httpServer *httptest.Server
+
+	mu         sync.Mutex
+	accessKeys map[string]string
+	buckets    map[string]*bucket
+	faults     []*injectedFault
+}
+
+type bucket struct {
+	objects map[string]*object
+	uploads map[string]*upload
+}
+
+// NewServer starts a fake OSS endpoint without buckets or access keys.
+func NewServer() *Server {
+	server := &Server{
+		accessKeys: map[string]string{},
+		buckets:    map[string]*bucket{},
+	}
+	server.httpServer = httptest.NewServer(http.HandlerFunc(server.serveHTTP))
+	return server
+}
+
+// Endpoint returns the host and port of the server, to be used as endpoint of the SDK or the CLI configuration.
+func (s *Server) Endpoint() string {
+	return s.httpServer.Listener.Addr().String()
+}
+
+// URL returns the base URL of the server.
+func (s *Server) URL() string {
+	return s.httpServer.URL
+}
+
+// Close shuts down the server and blocks until all outstanding requests have completed.
+func (s *Server) Close() {
+	s.httpServer.Close()
+}
+
+// AddAccessKey allows requests signed with the given access key.
+func (s *Server) AddAccessKey(accessKeyID string, accessKeySecret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.accessKeys[accessKeyID] = accessKeySecret
+}
+
+// CreateBucket creates an empty bucket, keeping the objects of an existing bucket of the same name.
+func (s *Server) CreateBucket(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.buckets[name]; !ok {
+		s.buckets[name] = &bucket{objects: map[string]*object{}, uploads: map[string]*upload{}}
+	}
+}
+
+// ObjectKeys returns the keys of all objects in a bucket, e.g. to assert that a test cleaned up after itself.
+func (s *Server) ObjectKeys(bucketName string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []string
+	if b, ok := s.buckets[bucketName]; ok {
+		keys = sortedKeys(b.objects)
+	}
+	return keys
+}
+
+// UploadIDs returns the IDs of the multipart uploads of a bucket which have been neither completed nor aborted.
+func (s *Server) UploadIDs(bucketName string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var uploadIDs []string
+	if b, ok := s.buckets[bucketName]; ok {
+		uploadIDs = sortedKeys(b.uploads)
+	}
+	return uploadIDs
+}
+
+// presignParams are the query parameters of presigned URLs which carry their signature
+var presignParams = []string{
+	"OSSAccessKeyId", "Expires", "Signature", "security-token",
+	"x-oss-signature-version", "x-oss-credential", "x-oss-date", "x-oss-expires", "x-oss-signature",
+	"x-oss-additional-headers", "x-oss-security-token",
+}
+
+// unenforcedParams are the query parameters which are accepted but have no effect on the fake, e.g. the
+// bandwidth limit of a transfer
+var unenforcedParams = []string{"x-oss-traffic-limit"}
+
+// request is a request for a bucket or an object of a bucket, with the request ID of its response.
+type request struct {
+	*http.Request
+	id         string
+	bucketName string
+	key        string
+	query      url.Values
+	body       []byte
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	req := &request{Request: r, id: newRequestID(), query: r.URL.Query()}
+	req.bucketName, req.key, _ = strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+	w.Header().Set("X-Oss-Request-Id", req.id)
+	w.Header().Set("Server", "AliyunOSS")
+	w.Header().Set("Date", time.Now().UTC().Format(http.TimeFormat))
+
+	err := s.authenticate(req)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	for _, param := range append(presignParams, unenforcedParams...) {
+		req.query.Del(param)
+	}
+
+	op := operation(req)
+	if fault := s.takeFault(op, req.key); fault != nil {
+		var handled bool
+		w, handled = applyFault(w, req, fault)
+		if handled {
+			return
+		}
+		if faulty, ok := w.(*faultyResponseWriter); ok {
+			defer faulty.finish()
+		}
+	}
+
+	// The body is read before locking the server, so that slow uploads do not block other requests
+	req.body, err = io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	if op == "" {
+		writeError(w, req, errNotImplemented)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[req.bucketName]
+	if !ok {
+		writeError(w, req, &serviceError{
+			StatusCode: http.StatusNotFound,
+			Code:       "NoSuchBucket",
+			Message:    "The specified bucket does not exist.",
+			BucketName: req.bucketName,
+		})
+		return
+	}
+
+	err = s.serve(w, req, op, b)
+	if err != nil {
+		writeError(w, req, err)
+	}
+}
+
+// operation returns the operation of a request, or an empty operation for requests the fake does not implement.
+func operation(req *request) Operation {
+	if req.bucketName == "" {
+		return ""
+	}
+
+	if req.key == "" {
+		switch {
+		case req.Method == http.MethodGet && req.query.Get("list-type") == "2":
+			return OperationListObjectsV2
+		case req.Method == http.MethodGet && req.query.Has("uploads"):
+			return OperationListMultipartUploads
+		case req.Method == http.MethodPost && req.query.Has("delete"):
+			return OperationDeleteMultipleObjects
+		case isPostObject(req):
+			return OperationPostObject
+		}
+		return ""
+	}
+
+	switch req.Method {
+	case http.MethodPut:
+		switch {
+		case req.query.Has("uploadId") && req.Header.Get("X-Oss-Copy-Source") != "":
+			return OperationUploadPartCopy
+		case req.query.Has("uploadId"):
+			return OperationUploadPart
+		case req.Header.Get("X-Oss-Copy-Source") != "":
+			return OperationCopyObject
+		case len(req.query) == 0:
+			return OperationPutObject
+		}
+	case http.MethodGet:
+		if len(req.query) == 0 || hasOnlyResponseOverrides(req.query) {
+			return OperationGetObject
+		}
+	case http.MethodHead:
+		if req.query.Has("objectMeta") {
+			return OperationGetObjectMeta
+		}
+		if len(req.query) == 0 || hasOnlyResponseOverrides(req.query) {
+			return OperationHeadObject
+		}
+	case http.MethodDelete:
+		if req.query.Has("uploadId") {
+			return OperationAbortMultipartUpload
+		}
+		if len(req.query) == 0 {
+			return OperationDeleteObject
+		}
+	case http.MethodPost:
+		switch {
+		case req.query.Has("uploads"):
+			return OperationInitiateMultipartUpload
+		case req.query.Has("uploadId"):
+			return OperationCompleteMultipartUpload
+		}
+	}
+	return ""
+}
+
+func (s *Server) serve(w http.ResponseWriter, req *request, op Operation, b *bucket) error {
+	switch op {
+	case OperationListObjectsV2:
+		return listObjectsV2(w, req, b)
+	case OperationListMultipartUploads:
+		return listMultipartUploads(w, req, b)
+	case OperationDeleteMultipleObjects:
+		return deleteObjects(w, req, b)
+	case OperationPutObject:
+		return putObject(w, req, b)
+	case OperationPostObject:
+		return s.postObject(w, req, b)
+	case OperationGetObject, OperationHeadObject, OperationGetObjectMeta:
+		return getObject(w, req, b)
+	case OperationDeleteObject:
+		delete(b.objects, req.key)
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	case OperationCopyObject:
+		return s.copyObject(w, req, b)
+	case OperationInitiateMultipartUpload:
+		return s.initiateMultipartUpload(w, req, b)
+	case OperationUploadPart, OperationUploadPartCopy:
+		return s.uploadPart(w, req, b)
+	case OperationCompleteMultipartUpload:
+		return completeMultipartUpload(w, req, b)
+	case OperationAbortMultipartUpload:
+		return abortMultipartUpload(w, req, b)
+	}
+	return errNotImplemented
+}
+
+// serviceError is an OSS error response.
+type serviceError struct {
+	XMLName    xml.Name `xml:"Error"`
+	StatusCode int      `xml:"-"`
+	Code       string   `xml:"Code"`
+	Message    string   `xml:"Message"`
+	RequestID  string   `xml:"RequestId"`
+	HostID     string   `xml:"HostId"`
+	BucketName string   `xml:"BucketName,omitempty"`
+	Key        string   `xml:"Key,omitempty"`
+	// StringToSign is the string the server signed for a request whose signature does not match
+	StringToSign string `xml:"StringToSign,omitempty"`
+}
+
+func (e *serviceError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+var errNotImplemented = &serviceError{
+	StatusCode: http.StatusNotImplemented,
+	Code:       "NotImplemented",
+	Message:    "The request is not implemented by the fake OSS server.",
+}
+
+func errNoSuchKey(key string) *serviceError {
+	return &serviceError{
+		StatusCode: http.StatusNotFound,
+		Code:       "NoSuchKey",
+		Message:    "The specified key does not exist.",
+		Key:        key,
+	}
+}
+
+func errNoSuchUpload() *serviceError {
+	return &serviceError{
+		StatusCode: http.StatusNotFound,
+		Code:       "NoSuchUpload",
+		Message:    "The specified upload does not exist. The upload ID may be invalid, or the upload may have been aborted or completed.",
+	}
+}
+
+func errInvalidArgument(message string) *serviceError {
+	return &serviceError{StatusCode: http.StatusBadRequest, Code: "InvalidArgument", Message: message}
+}
+
+func errPreconditionFailed() *serviceError {
+	return &serviceError{StatusCode: http.StatusPreconditionFailed, Code: "PreconditionFailed", Message: "At least one of the pre-conditions you specified did not hold."}
+}
+
+// writeError writes err as OSS error response. Like OSS, responses to HEAD requests carry the error document
+// base64-encoded in the x-oss-err header instead of the body.
+func writeError(w http.ResponseWriter, req *request, err error) {
+	e, ok := err.(*serviceError)
+	if !ok {
+		e = &serviceError{StatusCode: http.StatusInternalServerError, Code: "InternalError", Message: err.Error()}
+	}
+
+	document := *e
+	document.RequestID = req.id
+	document.HostID = req.Host
+
+	body, marshalErr := xml.Marshal(document)
+	if marshalErr != nil {
+		http.Error(w, marshalErr.Error(), http.StatusInternalServerError)
+		return
+	}
+	body = append([]byte(xml.Header), body...)
+
+	if req.Method == http.MethodHead {
+		w.Header().Set("X-Oss-Err", base64.StdEncoding.EncodeToString(body))
+		w.WriteHeader(e.StatusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(e.StatusCode)
+	_, _ = w.Write(body)
+}
+
+// writeXML writes result as XML document with status 200.
+func writeXML(w http.ResponseWriter, result any) error {
+	body, err := xml.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	body = append([]byte(xml.Header), body...)
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	_, _ = w.Write(body)
+	return nil
+}
+
+// newRequestID returns a random ID in the format of the OSS request IDs.
+func newRequestID() string {
+	id := make([]byte, 12)
+	_, _ = rand.Read(id)
+	return strings.ToUpper(hex.EncodeToString(id))
+}
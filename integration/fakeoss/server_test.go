@@ -0,0 +1,328 @@
+package fakeoss_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/integration/fakeoss"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	accessKeyID     = "test-access-key-id"
+	accessKeySecret = "test-access-key-secret"
+	bucketName      = "test-bucket"
+)
+
+var _ = Describe("Server", func() {
+	var server *fakeoss.Server
+
+	BeforeEach(func() {
+		server = fakeoss.NewServer()
+		server.AddAccessKey(accessKeyID, accessKeySecret)
+		server.CreateBucket(bucketName)
+		DeferCleanup(server.Close)
+	})
+
+	newBucket := func(accessKeyID string, accessKeySecret string, options ...oss.ClientOption) *oss.Bucket {
+		client, err := oss.New(server.URL(), accessKeyID, accessKeySecret, options...)
+		Expect(err).ToNot(HaveOccurred())
+
+		bucket, err := client.Bucket(bucketName)
+		Expect(err).ToNot(HaveOccurred())
+		return bucket
+	}
+
+	serviceError := func(err error) oss.ServiceError {
+		var serviceError oss.ServiceError
+		Expect(errors.As(err, &serviceError)).To(BeTrue(), "expected a service error, got %v", err)
+		return serviceError
+	}
+
+	signatureVersions := []struct {
+		name    string
+		options []oss.ClientOption
+	}{
+		{name: "V1"},
+		{name: "V4", options: []oss.ClientOption{oss.AuthVersion(oss.AuthV4), oss.Region("cn-hangzhou")}},
+		{name: "V4 with additional headers", options: []oss.ClientOption{
+			oss.AuthVersion(oss.AuthV4),
+			oss.Region("cn-hangzhou"),
+			oss.AdditionalHeaders([]string{"host", "user-agent"}),
+		}},
+	}
+
+	for _, signatureVersion := range signatureVersions {
+		signatureVersion := signatureVersion
+
+		Context("with "+signatureVersion.name+" signatures", func() {
+			var bucket *oss.Bucket
+
+			BeforeEach(func() {
+				bucket = newBucket(accessKeyID, accessKeySecret, signatureVersion.options...)
+			})
+
+			It("puts, gets, heads and deletes an object with special characters in its key", func() {
+				key := "dir/a b+c%d.txt"
+
+				err := bucket.PutObject(key, strings.NewReader("foo"), oss.Meta("owner", "test"), oss.ContentType("text/plain"))
+				Expect(err).ToNot(HaveOccurred())
+
+				body, err := bucket.GetObject(key)
+				Expect(err).ToNot(HaveOccurred())
+				content, err := io.ReadAll(body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(body.Close()).To(Succeed())
+				Expect(string(content)).To(Equal("foo"))
+
+				header, err := bucket.GetObjectDetailedMeta(key)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(header.Get("Content-Length")).To(Equal("3"))
+				Expect(header.Get("Content-Type")).To(Equal("text/plain"))
+				Expect(header.Get("X-Oss-Meta-Owner")).To(Equal("test"))
+				Expect(header.Get("ETag")).To(Equal(`"ACBD18DB4CC2F85CEDEF654FCCC4A4D8"`))
+				Expect(header.Get("Content-MD5")).To(Equal("rL0Y20zC+Fzt72VPzMSk2A=="))
+
+				exists, err := bucket.IsObjectExist(key)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(exists).To(BeTrue())
+
+				Expect(bucket.DeleteObject(key)).To(Succeed())
+
+				exists, err = bucket.IsObjectExist(key)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(exists).To(BeFalse())
+			})
+
+			It("lists objects by prefix and delimiter across pages", func() {
+				for _, key := range []string{"list/a", "list/b c", "list/dir/d", "list/dir/e", "other"} {
+					Expect(bucket.PutObject(key, strings.NewReader("foo"))).To(Succeed())
+				}
+
+				var keys, prefixes []string
+				options := []oss.Option{oss.Prefix("list/"), oss.Delimiter("/"), oss.MaxKeys(2)}
+				for {
+					result, err := bucket.ListObjectsV2(options...)
+					Expect(err).ToNot(HaveOccurred())
+
+					for _, object := range result.Objects {
+						keys = append(keys, object.Key)
+						Expect(object.Size).To(BeEquivalentTo(3))
+					}
+					prefixes = append(prefixes, result.CommonPrefixes...)
+
+					if !result.IsTruncated {
+						break
+					}
+					options = []oss.Option{oss.Prefix("list/"), oss.Delimiter("/"), oss.MaxKeys(2), oss.ContinuationToken(result.NextContinuationToken)}
+				}
+
+				Expect(keys).To(Equal([]string{"list/a", "list/b c"}))
+				Expect(prefixes).To(Equal([]string{"list/dir/"}))
+			})
+
+			It("uploads and copies an object in parts", func() {
+				content := bytes.Repeat([]byte("0123456789"), 35*1024)
+				sourceFile := filepath.Join(GinkgoT().TempDir(), "source")
+				Expect(os.WriteFile(sourceFile, content, 0600)).To(Succeed())
+
+				err := bucket.UploadFile("multipart", sourceFile, 100*1024, oss.Routines(3))
+				Expect(err).ToNot(HaveOccurred())
+
+				err = bucket.CopyFile(bucketName, "multipart", "copied", 100*1024, oss.Routines(3))
+				Expect(err).ToNot(HaveOccurred())
+
+				downloadedFile := filepath.Join(GinkgoT().TempDir(), "downloaded")
+				err = bucket.DownloadFile("copied", downloadedFile, 64*1024, oss.Routines(3))
+				Expect(err).ToNot(HaveOccurred())
+
+				downloaded, err := os.ReadFile(downloadedFile)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(downloaded).To(Equal(content))
+
+				header, err := bucket.GetObjectDetailedMeta("copied")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(header.Get("ETag")).To(HaveSuffix(`-4"`))
+				Expect(server.UploadIDs(bucketName)).To(BeEmpty())
+			})
+
+			It("rejects a request signed with the wrong secret", func() {
+				bucket = newBucket(accessKeyID, "wrong-secret", signatureVersion.options...)
+
+				err := bucket.PutObject("object", strings.NewReader("foo"))
+				Expect(serviceError(err).StatusCode).To(Equal(http.StatusForbidden))
+				Expect(serviceError(err).Code).To(Equal("SignatureDoesNotMatch"))
+				Expect(server.ObjectKeys(bucketName)).To(BeEmpty())
+			})
+		})
+	}
+
+	Describe("errors", func() {
+		var bucket *oss.Bucket
+
+		BeforeEach(func() {
+			bucket = newBucket(accessKeyID, accessKeySecret)
+		})
+
+		It("rejects an unknown access key", func() {
+			bucket = newBucket("unknown", accessKeySecret)
+
+			_, err := bucket.GetObject("object")
+			Expect(serviceError(err).Code).To(Equal("InvalidAccessKeyId"))
+		})
+
+		It("reports a missing object, also in the x-oss-err header of HEAD responses", func() {
+			_, err := bucket.GetObject("missing")
+			Expect(serviceError(err).StatusCode).To(Equal(http.StatusNotFound))
+			Expect(serviceError(err).Code).To(Equal("NoSuchKey"))
+			Expect(serviceError(err).RequestID).To(HaveLen(24))
+
+			_, err = bucket.GetObjectDetailedMeta("missing")
+			Expect(serviceError(err).Code).To(Equal("NoSuchKey"))
+		})
+
+		It("reports a missing bucket", func() {
+			client, err := oss.New(server.URL(), accessKeyID, accessKeySecret)
+			Expect(err).ToNot(HaveOccurred())
+			missingBucket, err := client.Bucket("missing-bucket")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = missingBucket.PutObject("object", strings.NewReader("foo"))
+			Expect(serviceError(err).Code).To(Equal("NoSuchBucket"))
+		})
+
+		It("rejects a body which does not match its Content-MD5", func() {
+			err := bucket.PutObject("object", strings.NewReader("foo"), oss.ContentMD5("rL0Y20zC+Fzt72VPzMSk2A=="))
+			Expect(err).ToNot(HaveOccurred())
+
+			err = bucket.PutObject("object", strings.NewReader("bar"), oss.ContentMD5("rL0Y20zC+Fzt72VPzMSk2A=="))
+			Expect(serviceError(err).Code).To(Equal("InvalidDigest"))
+		})
+
+		It("fails a conditional get of a changed object", func() {
+			Expect(bucket.PutObject("object", strings.NewReader("foo"))).To(Succeed())
+
+			_, err := bucket.GetObject("object", oss.IfMatch(`"0000"`))
+			Expect(serviceError(err).StatusCode).To(Equal(http.StatusPreconditionFailed))
+		})
+
+		It("rejects parts smaller than 100 KB except for the last one", func() {
+			upload, err := bucket.InitiateMultipartUpload("object")
+			Expect(err).ToNot(HaveOccurred())
+
+			var parts []oss.UploadPart
+			for partNumber := 1; partNumber <= 2; partNumber++ {
+				part, err := bucket.UploadPart(upload, strings.NewReader("foo"), 3, partNumber)
+				Expect(err).ToNot(HaveOccurred())
+				parts = append(parts, part)
+			}
+
+			_, err = bucket.CompleteMultipartUpload(upload, parts)
+			Expect(serviceError(err).Code).To(Equal("EntityTooSmall"))
+
+			uploads, err := bucket.ListMultipartUploads(oss.Prefix("obj"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uploads.Uploads).To(HaveLen(1))
+			Expect(uploads.Uploads[0].UploadID).To(Equal(upload.UploadID))
+
+			Expect(bucket.AbortMultipartUpload(upload)).To(Succeed())
+			Expect(server.UploadIDs(bucketName)).To(BeEmpty())
+		})
+	})
+
+	Describe("ranged gets", func() {
+		var bucket *oss.Bucket
+
+		BeforeEach(func() {
+			bucket = newBucket(accessKeyID, accessKeySecret)
+			Expect(bucket.PutObject("object", strings.NewReader("0123456789"))).To(Succeed())
+		})
+
+		DescribeTable("returns the requested bytes",
+			func(rangeHeader string, expectedStatus int, expectedContent string) {
+				request, err := http.NewRequest(http.MethodGet, signedURL(bucket, "object"), nil)
+				Expect(err).ToNot(HaveOccurred())
+				request.Header.Set("Range", rangeHeader)
+
+				response, err := http.DefaultClient.Do(request)
+				Expect(err).ToNot(HaveOccurred())
+				defer response.Body.Close()
+
+				content, err := io.ReadAll(response.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.StatusCode).To(Equal(expectedStatus))
+				Expect(string(content)).To(Equal(expectedContent))
+			},
+			Entry("for a closed range", "bytes=2-4", http.StatusPartialContent, "234"),
+			Entry("for a range exceeding the object", "bytes=8-20", http.StatusPartialContent, "89"),
+			Entry("for an open range", "bytes=7-", http.StatusPartialContent, "789"),
+			Entry("for a suffix", "bytes=-2", http.StatusPartialContent, "89"),
+			Entry("ignoring a malformed range", "bytes=4-2", http.StatusOK, "0123456789"),
+			Entry("ignoring multiple ranges", "bytes=0-1,4-5", http.StatusOK, "0123456789"),
+		)
+
+		It("rejects a range starting after the object", func() {
+			_, err := bucket.GetObject("object", oss.Range(10, 20))
+			Expect(serviceError(err).StatusCode).To(Equal(http.StatusRequestedRangeNotSatisfiable))
+		})
+	})
+
+	Describe("presigned URLs", func() {
+		var bucket *oss.Bucket
+
+		BeforeEach(func() {
+			bucket = newBucket(accessKeyID, accessKeySecret)
+		})
+
+		It("accepts uploads and downloads with a V1 signature in the query", func() {
+			putURL, err := bucket.SignURL("signed", oss.HTTPPut, 60)
+			Expect(err).ToNot(HaveOccurred())
+
+			request, err := http.NewRequest(http.MethodPut, putURL, strings.NewReader("foo"))
+			Expect(err).ToNot(HaveOccurred())
+			response, err := http.DefaultClient.Do(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Body.Close()).To(Succeed())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			response, err = http.Get(signedURL(bucket, "signed"))
+			Expect(err).ToNot(HaveOccurred())
+			defer response.Body.Close()
+			content, err := io.ReadAll(response.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal("foo"))
+		})
+
+		It("rejects an expired or modified URL", func() {
+			Expect(bucket.PutObject("signed", strings.NewReader("foo"))).To(Succeed())
+
+			expiringURL, err := bucket.SignURL("signed", oss.HTTPGet, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(func() int {
+				response, err := http.Get(expiringURL)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.Body.Close()).To(Succeed())
+				return response.StatusCode
+			}).WithTimeout(3 * time.Second).WithPolling(200 * time.Millisecond).Should(Equal(http.StatusForbidden))
+
+			response, err := http.Get(strings.Replace(signedURL(bucket, "signed"), "/signed?", "/other?", 1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Body.Close()).To(Succeed())
+			Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+		})
+	})
+})
+
+func signedURL(bucket *oss.Bucket, key string) string {
+	signedURL, err := bucket.SignURL(key, oss.HTTPGet, 60)
+	Expect(err).ToNot(HaveOccurred())
+	return signedURL
+}
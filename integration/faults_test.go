@@ -0,0 +1,245 @@
+package integration_test
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/integration"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/integration/fakeoss"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfers under faults injected into the fake OSS server", func() {
+
+	var blobName string
+	var storageConfig config.AliStorageConfig
+	var configPath string
+	var contentFile string
+
+	BeforeEach(func() {
+		if fakeOSS == nil {
+			Skip("faults can only be injected into the fake OSS server")
+		}
+		DeferCleanup(fakeOSS.ClearFaults)
+
+		blobName = integration.GenerateRandomString()
+		storageConfig = defaultConfig
+		storageConfig.RetryMaxAttempts = 3
+		storageConfig.RetryBaseBackoffMs = 10
+		storageConfig.RetryMaxBackoffMs = 50
+		contentFile = integration.MakeContentFile("foo")
+		DeferCleanup(func() { _ = os.Remove(contentFile) })
+	})
+
+	JustBeforeEach(func() {
+		configPath = integration.MakeConfigFile(&storageConfig)
+		DeferCleanup(func() { _ = os.Remove(configPath) })
+	})
+
+	runCli := func(subcommand string, args ...string) (exitCode int, logEntries []map[string]any) {
+		cliSession, err := integration.RunCli(cliPath, configPath, subcommand, args...)
+		Expect(err).ToNot(HaveOccurred())
+		return cliSession.ExitCode(), integration.LogEntries(cliSession)
+	}
+
+	deleteBlob := func() {
+		fakeOSS.ClearFaults()
+		exitCode, _ := runCli("delete", blobName)
+		Expect(exitCode).To(BeZero())
+	}
+
+	// getBlob downloads the blob and returns its content.
+	getBlob := func() string {
+		downloadedFile, err := os.CreateTemp("", "ali-storage-cli-download")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(downloadedFile.Close()).To(Succeed())
+		defer func() { _ = os.Remove(downloadedFile.Name()) }()
+
+		exitCode, _ := runCli("get", blobName, downloadedFile.Name())
+		Expect(exitCode).To(BeZero())
+
+		content, err := os.ReadFile(downloadedFile.Name())
+		Expect(err).ToNot(HaveOccurred())
+		return string(content)
+	}
+
+	retries := func(operation string) OmegaMatcher {
+		return ContainElement(SatisfyAll(
+			HaveKeyWithValue("msg", "retrying operation"),
+			HaveKeyWithValue("operation", operation),
+			HaveKeyWithValue("key", blobName),
+		))
+	}
+
+	Describe("`put`", func() {
+		It("retries uploads throttled with SlowDown", func() {
+			defer deleteBlob()
+			fakeOSS.InjectFault(fakeoss.Fault{Operation: fakeoss.OperationPutObject, Key: blobName, Times: 2, Kind: fakeoss.FaultSlowDown})
+
+			exitCode, logEntries := runCli("put", contentFile, blobName)
+			Expect(exitCode).To(BeZero())
+			Expect(logEntries).To(retries("upload"))
+
+			Expect(getBlob()).To(Equal("foo"))
+		})
+
+		It("retries an upload whose connection is dropped mid-body", func() {
+			defer deleteBlob()
+			fakeOSS.InjectFault(fakeoss.Fault{Operation: fakeoss.OperationPutObject, Key: blobName, Times: 1, Kind: fakeoss.FaultDropConnection})
+
+			exitCode, logEntries := runCli("put", contentFile, blobName)
+			Expect(exitCode).To(BeZero())
+			Expect(logEntries).To(retries("upload"))
+
+			Expect(getBlob()).To(Equal("foo"))
+		})
+
+		Context("with a read/write timeout", func() {
+			BeforeEach(func() {
+				storageConfig.ReadWriteTimeoutSeconds = 1
+			})
+
+			It("retries an upload timing out while waiting for the response", func() {
+				defer deleteBlob()
+				fakeOSS.InjectFault(fakeoss.Fault{Operation: fakeoss.OperationPutObject, Key: blobName, Times: 1, Kind: fakeoss.FaultLatency, Latency: 3 * time.Second})
+
+				exitCode, logEntries := runCli("put", contentFile, blobName)
+				Expect(exitCode).To(BeZero())
+				Expect(logEntries).To(retries("upload"))
+
+				Expect(getBlob()).To(Equal("foo"))
+			})
+		})
+
+		Context("with a multipart upload", func() {
+			BeforeEach(func() {
+				storageConfig.MultipartThreshold = 100 * 1024
+				storageConfig.UploadPartSize = 100 * 1024
+				storageConfig.CheckpointDir = GinkgoT().TempDir()
+
+				contentFile = integration.MakeContentFile(strings.Repeat("0123456789", 25*1024))
+			})
+
+			It("resumes the upload from its checkpoint after a part upload is dropped", func() {
+				defer deleteBlob()
+				fakeOSS.InjectFault(fakeoss.Fault{Operation: fakeoss.OperationUploadPart, Key: blobName, Times: 1, Kind: fakeoss.FaultDropConnection})
+
+				exitCode, logEntries := runCli("put", contentFile, blobName)
+				Expect(exitCode).To(BeZero())
+				Expect(logEntries).To(retries("upload"))
+
+				Expect(fakeOSS.UploadIDs(bucketName)).To(BeEmpty())
+				Expect(getBlob()).To(Equal(strings.Repeat("0123456789", 25*1024)))
+			})
+		})
+
+		It("exits with 9 if the throttling persists through all retries", func() {
+			fakeOSS.InjectFault(fakeoss.Fault{Operation: fakeoss.OperationPutObject, Key: blobName, Kind: fakeoss.FaultSlowDown})
+
+			exitCode, logEntries := runCli("put", contentFile, blobName)
+			Expect(exitCode).To(Equal(9))
+			Expect(logEntries).To(retries("upload"))
+
+			Expect(fakeOSS.ObjectKeys(bucketName)).ToNot(ContainElement(blobName))
+		})
+
+		It("exits with 8 without retrying if the clock of the host is skewed", func() {
+			fakeOSS.InjectFault(fakeoss.Fault{Operation: fakeoss.OperationPutObject, Key: blobName, Kind: fakeoss.FaultClockSkew})
+
+			exitCode, logEntries := runCli("put", contentFile, blobName)
+			Expect(exitCode).To(Equal(8))
+			Expect(logEntries).ToNot(retries("upload"))
+		})
+	})
+
+	Describe("`delete-recursive`", func() {
+		JustBeforeEach(func() {
+			for _, name := range []string{"a", "b"} {
+				exitCode, _ := runCli("put", contentFile, blobName+"/"+name)
+				Expect(exitCode).To(BeZero())
+			}
+			DeferCleanup(func() {
+				fakeOSS.ClearFaults()
+				exitCode, _ := runCli("delete-recursive", "--yes", blobName+"/")
+				Expect(exitCode).To(BeZero())
+			})
+		})
+
+		It("retries a batch delete throttled with SlowDown", func() {
+			fakeOSS.InjectFault(fakeoss.Fault{Operation: fakeoss.OperationDeleteMultipleObjects, Times: 1, Kind: fakeoss.FaultSlowDown})
+
+			exitCode, logEntries := runCli("delete-recursive", "--yes", blobName+"/")
+			Expect(exitCode).To(BeZero())
+			Expect(logEntries).To(ContainElement(SatisfyAll(
+				HaveKeyWithValue("msg", "retrying operation"),
+				HaveKeyWithValue("operation", "delete_objects"),
+			)))
+
+			Expect(fakeOSS.ObjectKeys(bucketName)).ToNot(ContainElement(HavePrefix(blobName + "/")))
+		})
+
+		It("exits with 9 if the throttling persists through all retries", func() {
+			fakeOSS.InjectFault(fakeoss.Fault{Operation: fakeoss.OperationDeleteMultipleObjects, Kind: fakeoss.FaultSlowDown})
+
+			exitCode, _ := runCli("delete-recursive", "--yes", blobName+"/")
+			Expect(exitCode).To(Equal(9))
+		})
+	})
+
+	Describe("`get`", func() {
+		JustBeforeEach(func() {
+			exitCode, _ := runCli("put", contentFile, blobName)
+			Expect(exitCode).To(BeZero())
+			DeferCleanup(deleteBlob)
+		})
+
+		It("retries a download whose connection is dropped mid-body", func() {
+			fakeOSS.InjectFault(fakeoss.Fault{Operation: fakeoss.OperationGetObject, Key: blobName, Times: 1, Kind: fakeoss.FaultDropConnection})
+
+			downloadedFile := GinkgoT().TempDir() + "/downloaded"
+			exitCode, logEntries := runCli("get", blobName, downloadedFile)
+			Expect(exitCode).To(BeZero())
+			Expect(logEntries).To(retries("download"))
+
+			Expect(os.ReadFile(downloadedFile)).To(Equal([]byte("foo")))
+		})
+
+		It("retries a download whose response is truncated", func() {
+			fakeOSS.InjectFault(fakeoss.Fault{Operation: fakeoss.OperationGetObject, Key: blobName, Times: 1, Kind: fakeoss.FaultTruncateBody})
+
+			downloadedFile := GinkgoT().TempDir() + "/downloaded"
+			exitCode, logEntries := runCli("get", blobName, downloadedFile)
+			Expect(exitCode).To(BeZero())
+			Expect(logEntries).To(retries("download"))
+			// The corrupted byte range is downloaded again instead of being resumed from the checkpoint
+			Expect(logEntries).ToNot(ContainElement(HaveKeyWithValue("attempt", BeNumerically("==", 2))))
+
+			Expect(os.ReadFile(downloadedFile)).To(Equal([]byte("foo")))
+		})
+
+		It("retries a download throttled with SlowDown", func() {
+			fakeOSS.InjectFault(fakeoss.Fault{Key: blobName, Times: 2, Kind: fakeoss.FaultSlowDown})
+
+			downloadedFile := GinkgoT().TempDir() + "/downloaded"
+			exitCode, logEntries := runCli("get", blobName, downloadedFile)
+			Expect(exitCode).To(BeZero())
+			Expect(logEntries).To(retries("download"))
+
+			Expect(os.ReadFile(downloadedFile)).To(Equal([]byte("foo")))
+		})
+
+		It("exits with 10 if the download does not complete within --timeout", func() {
+			fakeOSS.InjectFault(fakeoss.Fault{Operation: fakeoss.OperationGetObject, Key: blobName, Kind: fakeoss.FaultLatency, Latency: 10 * time.Second})
+
+			downloadedFile := GinkgoT().TempDir() + "/downloaded"
+			cliSession, err := integration.RunCliWithFlags(cliPath, configPath, []string{"--timeout", "1s"}, "get", blobName, downloadedFile)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(Equal(10))
+			Expect(downloadedFile).ToNot(BeAnExistingFile())
+		})
+	})
+})
@@ -2,8 +2,15 @@ package integration_test
 
 import (
 	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"io"
 	"io/ioutil"
+	"mime/multipart"
+	"net/http"
 	"os"
+	"strings"
 
 	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
 	"github.com/cloudfoundry/bosh-ali-storage-cli/integration"
@@ -45,7 +52,13 @@ var _ = Describe("General testing for all Ali regions", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(cliSession.ExitCode()).To(BeZero())
 
-			Expect(string(cliSession.Err.Contents())).To(MatchRegexp("File '" + blobName + "' exists in bucket '" + bucketName + "'"))
+			Expect(integration.LogEntries(cliSession)).To(ContainElement(SatisfyAll(
+				HaveKeyWithValue("msg", "operation completed"),
+				HaveKeyWithValue("operation", "exists"),
+				HaveKeyWithValue("bucket", bucketName),
+				HaveKeyWithValue("key", blobName),
+				HaveKeyWithValue("exists", true),
+			)))
 		})
 
 		It("overwrites an existing file", func() {
@@ -84,6 +97,42 @@ var _ = Describe("General testing for all Ali regions", func() {
 			Expect(string(gottenBytes)).To(Equal("updated content"))
 		})
 
+		It("uploads a large file using multipart upload", func() {
+			defer func() {
+				cliSession, err := integration.RunCli(cliPath, configPath, "delete", blobName)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cliSession.ExitCode()).To(BeZero())
+			}()
+
+			cfg := defaultConfig
+			cfg.MultipartThreshold = 1
+			cfg.UploadPartSize = 100 * 1024
+			cfg.CheckpointDir = os.TempDir()
+			multipartConfigPath := integration.MakeConfigFile(&cfg)
+			defer func() { _ = os.Remove(multipartConfigPath) }()
+
+			content := integration.GenerateRandomString(350 * 1024)
+			contentFile = integration.MakeContentFile(content)
+
+			cliSession, err := integration.RunCli(cliPath, multipartConfigPath, "put", contentFile, blobName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+			Expect(integration.LogEntries(cliSession)).To(ContainElement(SatisfyAll(
+				HaveKeyWithValue("msg", "using multipart upload"),
+				HaveKeyWithValue("operation", "upload"),
+			)))
+
+			outputFilePath := "/tmp/" + integration.GenerateRandomString()
+			defer func() { _ = os.Remove(outputFilePath) }()
+
+			cliSession, err = integration.RunCli(cliPath, configPath, "get", blobName, outputFilePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+
+			fileContent, _ := os.ReadFile(outputFilePath)
+			Expect(string(fileContent)).To(Equal(content))
+		})
+
 		It("returns the appropriate error message", func() {
 			cfg := &config.AliStorageConfig{
 				AccessKeyID:     accessKeyID,
@@ -96,7 +145,7 @@ var _ = Describe("General testing for all Ali regions", func() {
 
 			cliSession, err := integration.RunCli(cliPath, configPath, "put", contentFile, blobName)
 			Expect(err).ToNot(HaveOccurred())
-			Expect(cliSession.ExitCode()).To(Equal(1))
+			Expect(cliSession.ExitCode()).To(Equal(5))
 
 			consoleOutput := bytes.NewBuffer(cliSession.Err.Contents()).String()
 			Expect(consoleOutput).To(ContainSubstring("upload failure"))
@@ -126,6 +175,222 @@ var _ = Describe("General testing for all Ali regions", func() {
 			fileContent, _ := ioutil.ReadFile(outputFilePath)
 			Expect(string(fileContent)).To(Equal("foo"))
 		})
+
+		It("returns 3 and reports the OSS error as JSON for a not existing blob", func() {
+			outputFilePath := "/tmp/" + integration.GenerateRandomString()
+			defer func() { _ = os.Remove(outputFilePath) }()
+
+			cliSession, err := integration.RunCliWithFlags(cliPath, configPath, []string{"--error-format", "json"}, "get", blobName, outputFilePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(Equal(3))
+
+			var report map[string]interface{}
+			errorLines := strings.Split(strings.TrimSpace(string(cliSession.Err.Contents())), "\n")
+			Expect(json.Unmarshal([]byte(errorLines[len(errorLines)-1]), &report)).To(Succeed())
+			Expect(report).To(HaveKeyWithValue("kind", "not_found"))
+			Expect(report).To(HaveKeyWithValue("exit_code", BeNumerically("==", 3)))
+			Expect(report).To(HaveKeyWithValue("status_code", BeNumerically("==", 404)))
+			Expect(report).To(HaveKeyWithValue("request_id", Not(BeEmpty())))
+		})
+
+		It("downloads a file in parallel byte ranges", func() {
+			outputFilePath := "/tmp/" + integration.GenerateRandomString()
+
+			defer func() {
+				cliSession, err := integration.RunCli(cliPath, configPath, "delete", blobName)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cliSession.ExitCode()).To(BeZero())
+
+				_ = os.Remove(outputFilePath)
+			}()
+
+			content := integration.GenerateRandomString(100 * 1024)
+			contentFile = integration.MakeContentFile(content)
+
+			cliSession, err := integration.RunCli(cliPath, configPath, "put", contentFile, blobName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+
+			cfg := defaultConfig
+			cfg.DownloadPartSize = 16 * 1024
+			cfg.DownloadConcurrency = 3
+			cfg.CheckpointDir = os.TempDir()
+			rangedConfigPath := integration.MakeConfigFile(&cfg)
+			defer func() { _ = os.Remove(rangedConfigPath) }()
+
+			cliSession, err = integration.RunCli(cliPath, rangedConfigPath, "get", blobName, outputFilePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+
+			fileContent, _ := os.ReadFile(outputFilePath)
+			Expect(string(fileContent)).To(Equal(content))
+		})
+	})
+
+	Describe("Invoking `put` and `get` with `-`", func() {
+		It("uploads from stdin and downloads to stdout", func() {
+			defer func() {
+				cliSession, err := integration.RunCli(cliPath, configPath, "delete", blobName)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cliSession.ExitCode()).To(BeZero())
+			}()
+
+			cliSession, err := integration.RunCliWithStdin(cliPath, configPath, strings.NewReader("foo"), "put", "-", blobName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+
+			cliSession, err = integration.RunCli(cliPath, configPath, "get", blobName, "-")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+			Expect(string(cliSession.Out.Contents())).To(Equal("foo"))
+		})
+
+		It("uploads a large stream using multipart upload", func() {
+			defer func() {
+				cliSession, err := integration.RunCli(cliPath, configPath, "delete", blobName)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cliSession.ExitCode()).To(BeZero())
+			}()
+
+			cfg := defaultConfig
+			cfg.MultipartThreshold = 1
+			cfg.UploadPartSize = 100 * 1024
+			multipartConfigPath := integration.MakeConfigFile(&cfg)
+			defer func() { _ = os.Remove(multipartConfigPath) }()
+
+			content := integration.GenerateRandomString(350 * 1024)
+
+			cliSession, err := integration.RunCliWithStdin(cliPath, multipartConfigPath, strings.NewReader(content), "put", "-", blobName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+			Expect(integration.LogEntries(cliSession)).To(ContainElement(SatisfyAll(
+				HaveKeyWithValue("msg", "using multipart upload"),
+				HaveKeyWithValue("operation", "upload_stream"),
+			)))
+
+			cliSession, err = integration.RunCli(cliPath, configPath, "get", blobName, "-")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+			Expect(string(cliSession.Out.Contents())).To(Equal(content))
+		})
+	})
+
+	Describe("Invoking `get` with client-side encryption", func() {
+		It("decrypts a blob encrypted on `put` and refuses to download it without the key", func() {
+			outputFilePath := "/tmp/" + integration.GenerateRandomString()
+
+			defer func() {
+				cliSession, err := integration.RunCli(cliPath, configPath, "delete", blobName)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cliSession.ExitCode()).To(BeZero())
+
+				_ = os.Remove(outputFilePath)
+			}()
+
+			keyFile := integration.MakeContentFile(base64.StdEncoding.EncodeToString([]byte(integration.GenerateRandomString(32))))
+			defer func() { _ = os.Remove(keyFile) }()
+
+			cfg := defaultConfig
+			cfg.ClientSideEncryptionKeyFile = keyFile
+			encryptingConfigPath := integration.MakeConfigFile(&cfg)
+			defer func() { _ = os.Remove(encryptingConfigPath) }()
+
+			cliSession, err := integration.RunCli(cliPath, encryptingConfigPath, "put", contentFile, blobName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+
+			cliSession, err = integration.RunCli(cliPath, encryptingConfigPath, "get", blobName, outputFilePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+
+			fileContent, _ := os.ReadFile(outputFilePath)
+			Expect(string(fileContent)).To(Equal("foo"))
+
+			cliSession, err = integration.RunCli(cliPath, configPath, "get", blobName, outputFilePath+".plain")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(Equal(5))
+			Expect(string(cliSession.Err.Contents())).To(ContainSubstring("no client_side_encryption_key_file is configured"))
+		})
+	})
+
+	Describe("Invoking `copy` and `move`", func() {
+		It("copies and moves a blob server-side", func() {
+			copiedBlobName := blobName + "-copy"
+			movedBlobName := blobName + "-moved"
+			outputFilePath := "/tmp/" + integration.GenerateRandomString()
+
+			defer func() {
+				for _, blob := range []string{copiedBlobName, movedBlobName} {
+					cliSession, err := integration.RunCli(cliPath, configPath, "delete", blob)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(cliSession.ExitCode()).To(BeZero())
+				}
+
+				_ = os.Remove(outputFilePath)
+			}()
+
+			cliSession, err := integration.RunCli(cliPath, configPath, "put", contentFile, blobName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+
+			cliSession, err = integration.RunCli(cliPath, configPath, "copy", blobName, copiedBlobName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+
+			cliSession, err = integration.RunCli(cliPath, configPath, "move", blobName, movedBlobName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+
+			cliSession, err = integration.RunCli(cliPath, configPath, "exists", blobName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(Equal(3))
+
+			for _, blob := range []string{copiedBlobName, movedBlobName} {
+				cliSession, err = integration.RunCli(cliPath, configPath, "get", blob, outputFilePath)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cliSession.ExitCode()).To(BeZero())
+
+				fileContent, _ := os.ReadFile(outputFilePath)
+				Expect(string(fileContent)).To(Equal("foo"))
+			}
+		})
+
+		It("copies a large blob using multipart copy", func() {
+			copiedBlobName := blobName + "-copy"
+
+			defer func() {
+				for _, blob := range []string{blobName, copiedBlobName} {
+					cliSession, err := integration.RunCli(cliPath, configPath, "delete", blob)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(cliSession.ExitCode()).To(BeZero())
+				}
+			}()
+
+			contentFile = integration.MakeContentFile(integration.GenerateRandomString(350 * 1024))
+
+			cliSession, err := integration.RunCli(cliPath, configPath, "put", contentFile, blobName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+
+			cfg := defaultConfig
+			cfg.MultipartThreshold = 1
+			cfg.UploadPartSize = 100 * 1024
+			multipartConfigPath := integration.MakeConfigFile(&cfg)
+			defer func() { _ = os.Remove(multipartConfigPath) }()
+
+			cliSession, err = integration.RunCli(cliPath, multipartConfigPath, "copy", blobName, copiedBlobName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+			Expect(integration.LogEntries(cliSession)).To(ContainElement(SatisfyAll(
+				HaveKeyWithValue("msg", "using multipart copy"),
+				HaveKeyWithValue("operation", "copy"),
+			)))
+
+			cliSession, err = integration.RunCli(cliPath, configPath, "stat", copiedBlobName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+			Expect(string(cliSession.Out.Contents())).To(ContainSubstring(`"size":358400`))
+		})
 	})
 
 	Describe("Invoking `delete`", func() {
@@ -150,6 +415,39 @@ var _ = Describe("General testing for all Ali regions", func() {
 		})
 	})
 
+	Describe("Invoking `delete-recursive`", func() {
+		It("deletes all blobs below a prefix once confirmed", func() {
+			blobs := []string{blobName + "/a", blobName + "/dir/b"}
+
+			for _, blob := range blobs {
+				cliSession, err := integration.RunCli(cliPath, configPath, "put", contentFile, blob)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cliSession.ExitCode()).To(BeZero())
+			}
+
+			cliSession, err := integration.RunCli(cliPath, configPath, "delete-recursive", "--dry-run", blobName+"/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+			Expect(string(cliSession.Out.Contents())).To(ContainSubstring("Would delete 2 blobs below '" + blobName + "/'"))
+
+			cliSession, err = integration.RunCli(cliPath, configPath, "delete-recursive", blobName+"/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(Equal(2))
+			Expect(string(cliSession.Err.Contents())).To(ContainSubstring("without --yes"))
+
+			cliSession, err = integration.RunCli(cliPath, configPath, "delete-recursive", "--yes", blobName+"/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+			Expect(string(cliSession.Out.Contents())).To(ContainSubstring("Deleted 2 of 2 blobs below '" + blobName + "/'"))
+
+			for _, blob := range blobs {
+				cliSession, err = integration.RunCli(cliPath, configPath, "exists", blob)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cliSession.ExitCode()).To(Equal(3))
+			}
+		})
+	})
+
 	Describe("Invoking `exists`", func() {
 		It("returns 0 for an existing blob", func() {
 			defer func() {
@@ -167,6 +465,77 @@ var _ = Describe("General testing for all Ali regions", func() {
 			Expect(cliSession.ExitCode()).To(Equal(0))
 		})
 
+		DescribeTable("returns URLs pinning the headers of the request, which are printed along with the URL",
+			func(authVersion string) {
+				cfg := defaultConfig
+				cfg.AuthVersion = authVersion
+				if fakeOSS != nil {
+					cfg.Region = "cn-hangzhou"
+				}
+				_ = os.Remove(configPath)
+				configPath = integration.MakeConfigFile(&cfg)
+
+				sign := func(args ...string) map[string]any {
+					cliSession, err := integration.RunCli(cliPath, configPath, "sign", append([]string{"--json"}, args...)...)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(cliSession.ExitCode()).To(BeZero())
+
+					var signed map[string]any
+					Expect(json.Unmarshal(cliSession.Out.Contents(), &signed)).To(Succeed())
+					return signed
+				}
+
+				send := func(signed map[string]any, body io.Reader) *http.Response {
+					request, err := http.NewRequest(signed["method"].(string), signed["url"].(string), body)
+					Expect(err).ToNot(HaveOccurred())
+					for name, value := range signed["headers"].(map[string]any) {
+						request.Header.Set(name, value.(string))
+					}
+					response, err := http.DefaultClient.Do(request)
+					Expect(err).ToNot(HaveOccurred())
+					DeferCleanup(response.Body.Close)
+					return response
+				}
+
+				md5Sum := md5.Sum([]byte("foo"))
+				contentMD5 := base64.StdEncoding.EncodeToString(md5Sum[:])
+
+				signed := sign("--content-type", "text/plain", "--content-md5", contentMD5, "--traffic-limit", "819200", blobName, "put", "60s")
+				Expect(signed["headers"]).To(Equal(map[string]any{"Content-Type": "text/plain", "Content-Md5": contentMD5}))
+
+				// The headers are part of the signature, so that the upload cannot change them
+				request, err := http.NewRequest(http.MethodPut, signed["url"].(string), strings.NewReader("foo"))
+				Expect(err).ToNot(HaveOccurred())
+				request.Header.Set("Content-Type", "text/html")
+				request.Header.Set("Content-Md5", contentMD5)
+				response, err := http.DefaultClient.Do(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.Body.Close()).To(Succeed())
+				Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+
+				Expect(send(signed, strings.NewReader("foo")).StatusCode).To(Equal(http.StatusOK))
+
+				response = send(sign("--response-content-disposition", "attachment; filename=foo.txt", blobName, "get", "60s"), nil)
+				Expect(response.StatusCode).To(Equal(http.StatusOK))
+				Expect(response.Header.Get("Content-Disposition")).To(Equal("attachment; filename=foo.txt"))
+				Expect(response.Header.Get("Content-Type")).To(Equal("text/plain"))
+				Expect(io.ReadAll(response.Body)).To(Equal([]byte("foo")))
+
+				response = send(sign(blobName, "head", "60s"), nil)
+				Expect(response.StatusCode).To(Equal(http.StatusOK))
+				Expect(response.ContentLength).To(Equal(int64(3)))
+
+				response = send(sign(blobName, "delete", "60s"), nil)
+				Expect(response.StatusCode).To(Equal(http.StatusNoContent))
+
+				cliSession, err := integration.RunCli(cliPath, configPath, "exists", blobName)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cliSession.ExitCode()).To(Equal(3))
+			},
+			Entry("with V1 signatures", config.AuthVersionV1),
+			Entry("with V4 signatures", config.AuthVersionV4),
+		)
+
 		It("returns 3 for a not existing blob", func() {
 			cliSession, err := integration.RunCli(cliPath, configPath, "exists", blobName)
 			Expect(err).ToNot(HaveOccurred())
@@ -174,20 +543,116 @@ var _ = Describe("General testing for all Ali regions", func() {
 		})
 	})
 
+	Describe("Invoking `stat`", func() {
+		It("shows the server-side encryption of an uploaded blob", func() {
+			defer func() {
+				cliSession, err := integration.RunCli(cliPath, configPath, "delete", blobName)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cliSession.ExitCode()).To(BeZero())
+			}()
+
+			cfg := defaultConfig
+			cfg.ServerSideEncryption = "AES256"
+			encryptingConfigPath := integration.MakeConfigFile(&cfg)
+			defer func() { _ = os.Remove(encryptingConfigPath) }()
+
+			cliSession, err := integration.RunCli(cliPath, encryptingConfigPath, "put", contentFile, blobName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+
+			cliSession, err = integration.RunCli(cliPath, configPath, "stat", blobName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+
+			properties := bytes.NewBuffer(cliSession.Out.Contents()).String()
+			Expect(properties).To(ContainSubstring(`"size":3`))
+			Expect(properties).To(ContainSubstring(`"server_side_encryption":"AES256"`))
+		})
+
+		It("returns 3 for a not existing blob", func() {
+			cliSession, err := integration.RunCli(cliPath, configPath, "stat", blobName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(Equal(3))
+			Expect(cliSession.Out.Contents()).To(BeEmpty())
+		})
+	})
+
+	Describe("Invoking `list`", func() {
+		It("lists the blobs below a prefix", func() {
+			blobs := []string{blobName + "/a", blobName + "/b", blobName + "/dir/c"}
+
+			defer func() {
+				for _, blob := range blobs {
+					cliSession, err := integration.RunCli(cliPath, configPath, "delete", blob)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(cliSession.ExitCode()).To(BeZero())
+				}
+			}()
+
+			for _, blob := range blobs {
+				cliSession, err := integration.RunCli(cliPath, configPath, "put", contentFile, blob)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cliSession.ExitCode()).To(BeZero())
+			}
+
+			cliSession, err := integration.RunCli(cliPath, configPath, "list", blobName+"/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+			Expect(string(cliSession.Out.Contents())).To(Equal(strings.Join(blobs, "\n") + "\n"))
+
+			cliSession, err = integration.RunCli(cliPath, configPath, "list", "--delimiter", "/", "--json", blobName+"/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+
+			lines := strings.Split(strings.TrimSpace(string(cliSession.Out.Contents())), "\n")
+			Expect(lines).To(HaveLen(3))
+			Expect(lines[0]).To(MatchJSON(`{"prefix":"` + blobName + `/dir/"}`))
+			Expect(lines[1]).To(ContainSubstring(`"key":"` + blobName + `/a","size":3`))
+
+			cliSession, err = integration.RunCli(cliPath, configPath, "list", "--max-keys", "1", blobName+"/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+			Expect(string(cliSession.Out.Contents())).To(Equal(blobs[0] + "\n"))
+		})
+	})
+
 	Describe("Invoking `sign`", func() {
-		It("returns 0 for an existing blob", func() {
-			cliSession, err := integration.RunCli(cliPath, configPath, "sign", "some-blob", "get", "60s")
+		It("returns URLs which upload and download the blob without credentials", func() {
+			defer func() {
+				cliSession, err := integration.RunCli(cliPath, configPath, "delete", blobName)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cliSession.ExitCode()).To(BeZero())
+			}()
+
+			cliSession, err := integration.RunCli(cliPath, configPath, "sign", blobName, "put", "60s")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+
+			putUrl := strings.TrimSpace(string(cliSession.Out.Contents()))
+			Expect(putUrl).To(HavePrefix(objectURL(blobName) + "?"))
+
+			request, err := http.NewRequest(http.MethodPut, putUrl, strings.NewReader("foo"))
+			Expect(err).ToNot(HaveOccurred())
+			response, err := http.DefaultClient.Do(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Body.Close()).To(Succeed())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			cliSession, err = integration.RunCli(cliPath, configPath, "sign", blobName, "get", "60s")
 			Expect(err).ToNot(HaveOccurred())
 			Expect(cliSession.ExitCode()).To(BeZero())
 
-			getUrl := bytes.NewBuffer(cliSession.Out.Contents()).String()
-			Expect(getUrl).To(MatchRegexp("http://" + bucketName + "." + endpoint + "/some-blob"))
+			getUrl := strings.TrimSpace(string(cliSession.Out.Contents()))
+			Expect(getUrl).To(HavePrefix(objectURL(blobName) + "?"))
 
-			cliSession, err = integration.RunCli(cliPath, configPath, "sign", "some-blob", "put", "60s")
+			response, err = http.Get(getUrl)
 			Expect(err).ToNot(HaveOccurred())
+			defer func() { _ = response.Body.Close() }()
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
 
-			putUrl := bytes.NewBuffer(cliSession.Out.Contents()).String()
-			Expect(putUrl).To(MatchRegexp("http://" + bucketName + "." + endpoint + "/some-blob"))
+			content, err := io.ReadAll(response.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal("foo"))
 		})
 
 		It("returns 3 for a not existing blob", func() {
@@ -197,6 +662,175 @@ var _ = Describe("General testing for all Ali regions", func() {
 		})
 	})
 
+	Describe("Invoking with V4 signatures", func() {
+		BeforeEach(func() {
+			cfg := defaultConfig
+			cfg.AuthVersion = config.AuthVersionV4
+			if fakeOSS != nil {
+				// The region of OSS endpoints is derived from the endpoint, but not of the IP address of the fake
+				cfg.Region = "cn-hangzhou"
+			}
+
+			_ = os.Remove(configPath)
+			configPath = integration.MakeConfigFile(&cfg)
+		})
+
+		It("uploads and downloads a blob and returns URLs signed with V4 signatures", func() {
+			defer func() {
+				cliSession, err := integration.RunCli(cliPath, configPath, "delete", blobName)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cliSession.ExitCode()).To(BeZero())
+			}()
+
+			cliSession, err := integration.RunCli(cliPath, configPath, "put", contentFile, blobName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+
+			cliSession, err = integration.RunCli(cliPath, configPath, "sign", blobName, "put", "60s")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+
+			putUrl := strings.TrimSpace(string(cliSession.Out.Contents()))
+			Expect(putUrl).To(HavePrefix(objectURL(blobName) + "?"))
+			Expect(putUrl).To(ContainSubstring("x-oss-signature-version=OSS4-HMAC-SHA256"))
+
+			request, err := http.NewRequest(http.MethodPut, putUrl, strings.NewReader("bar"))
+			Expect(err).ToNot(HaveOccurred())
+			response, err := http.DefaultClient.Do(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Body.Close()).To(Succeed())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			cliSession, err = integration.RunCli(cliPath, configPath, "sign", blobName, "get", "60s")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+
+			getUrl := strings.TrimSpace(string(cliSession.Out.Contents()))
+			response, err = http.Get(getUrl)
+			Expect(err).ToNot(HaveOccurred())
+			defer func() { _ = response.Body.Close() }()
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			content, err := io.ReadAll(response.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal("bar"))
+
+			response, err = http.Get(strings.Replace(getUrl, "x-oss-expires=60", "x-oss-expires=600", 1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.Body.Close()).To(Succeed())
+			Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+
+			downloadedFile, err := os.CreateTemp("", "ali-storage-cli-download")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(downloadedFile.Close()).To(Succeed())
+			defer func() { _ = os.Remove(downloadedFile.Name()) }()
+
+			cliSession, err = integration.RunCli(cliPath, configPath, "get", blobName, downloadedFile.Name())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(BeZero())
+			Expect(os.ReadFile(downloadedFile.Name())).To(Equal([]byte("bar")))
+		})
+	})
+
+	Describe("Invoking `sign-post`", func() {
+		DescribeTable("returns form fields which upload blobs satisfying the conditions of the policy without credentials",
+			func(authVersion string) {
+				cfg := defaultConfig
+				cfg.AuthVersion = authVersion
+				if fakeOSS != nil {
+					cfg.Region = "cn-hangzhou"
+				}
+				_ = os.Remove(configPath)
+				configPath = integration.MakeConfigFile(&cfg)
+
+				prefix := blobName + "/"
+				defer func() {
+					cliSession, err := integration.RunCli(cliPath, configPath, "delete-recursive", "--yes", prefix)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(cliSession.ExitCode()).To(BeZero())
+				}()
+
+				cliSession, err := integration.RunCli(cliPath, configPath, "sign-post", "--key-prefix", prefix, "--max-size", "5", "--content-type", "text/plain", "60s")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cliSession.ExitCode()).To(BeZero())
+
+				var signed struct {
+					URL    string            `json:"url"`
+					Fields map[string]string `json:"fields"`
+				}
+				Expect(json.Unmarshal(cliSession.Out.Contents(), &signed)).To(Succeed())
+				Expect(signed.Fields).To(HaveKeyWithValue("key", prefix+"${filename}"))
+
+				post := func(fields map[string]string, fileName string, content string) *http.Response {
+					body := &bytes.Buffer{}
+					form := multipart.NewWriter(body)
+					for name, value := range fields {
+						Expect(form.WriteField(name, value)).To(Succeed())
+					}
+					file, err := form.CreateFormFile("file", fileName)
+					Expect(err).ToNot(HaveOccurred())
+					_, err = file.Write([]byte(content))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(form.Close()).To(Succeed())
+
+					response, err := http.Post(signed.URL, form.FormDataContentType(), body)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(response.Body.Close()).To(Succeed())
+					return response
+				}
+
+				withField := func(name string, value string) map[string]string {
+					fields := map[string]string{}
+					for fieldName, fieldValue := range signed.Fields {
+						fields[fieldName] = fieldValue
+					}
+					fields[name] = value
+					return fields
+				}
+
+				Expect(post(signed.Fields, "foo.txt", "foo").StatusCode).To(Equal(http.StatusNoContent))
+				Expect(post(withField("key", prefix+"bar.txt"), "ignored.txt", "bar").StatusCode).To(Equal(http.StatusNoContent))
+
+				Expect(post(withField("key", "outside/foo.txt"), "foo.txt", "foo").StatusCode).To(Equal(http.StatusForbidden))
+				Expect(post(withField("Content-Type", "text/html"), "foo.txt", "foo").StatusCode).To(Equal(http.StatusForbidden))
+				Expect(post(signed.Fields, "large.txt", "too large").StatusCode).To(Equal(http.StatusBadRequest))
+
+				cliSession, err = integration.RunCli(cliPath, configPath, "list", prefix)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cliSession.ExitCode()).To(BeZero())
+				Expect(string(cliSession.Out.Contents())).To(Equal(prefix + "bar.txt\n" + prefix + "foo.txt\n"))
+
+				cliSession, err = integration.RunCli(cliPath, configPath, "stat", prefix+"foo.txt")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cliSession.ExitCode()).To(BeZero())
+				Expect(string(cliSession.Out.Contents())).To(ContainSubstring(`"content_type":"text/plain"`))
+			},
+			Entry("with a V1 signature", config.AuthVersionV1),
+			Entry("with a V4 signature", config.AuthVersionV4),
+		)
+	})
+
+	Describe("Invoking with an invalid configuration", func() {
+		It("reports all problems and exits with 5 before sending any request", func() {
+			cfg := &config.AliStorageConfig{
+				AccessKeyID: accessKeyID,
+				Endpoint:    "ftp://" + endpoint,
+				BucketName:  "Not_A_Bucket",
+			}
+
+			configPath = integration.MakeConfigFile(cfg)
+
+			cliSession, err := integration.RunCli(cliPath, configPath, "exists", blobName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cliSession.ExitCode()).To(Equal(5))
+
+			consoleOutput := bytes.NewBuffer(cliSession.Err.Contents()).String()
+			Expect(consoleOutput).To(ContainSubstring("access_key_secret is required"))
+			Expect(consoleOutput).To(ContainSubstring("bucket_name 'Not_A_Bucket'"))
+			Expect(consoleOutput).To(ContainSubstring("must use the http or https scheme"))
+		})
+	})
+
 	Describe("Invoking `-v`", func() {
 		It("returns the cli version", func() {
 			configPath := integration.MakeConfigFile(&defaultConfig)
@@ -1,11 +1,14 @@
 package integration_test
 
 import (
-	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
-	"github.com/onsi/gomega/gexec"
 	"os"
 	"testing"
 
+	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/integration"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/integration/fakeoss"
+	"github.com/onsi/gomega/gexec"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -22,6 +25,9 @@ var endpoint string
 var bucketName string
 var defaultConfig config.AliStorageConfig
 
+// fakeOSS is the in-process OSS server the suite runs against unless the credentials of a real bucket are given
+var fakeOSS *fakeoss.Server
+
 var _ = BeforeSuite(func() {
 	if len(cliPath) == 0 {
 		var err error
@@ -30,16 +36,25 @@ var _ = BeforeSuite(func() {
 	}
 
 	accessKeyID = os.Getenv("ACCESS_KEY_ID")
-	Expect(accessKeyID).ToNot(BeEmpty(), "ACCESS_KEY_ID must be set")
+	if accessKeyID == "" {
+		accessKeyID = "fake-" + integration.GenerateRandomString(16)
+		accessKeySecret = integration.GenerateRandomString(30)
+		bucketName = "bosh-ali-storage-cli-test"
 
-	accessKeySecret = os.Getenv("ACCESS_KEY_SECRET")
-	Expect(accessKeySecret).ToNot(BeEmpty(), "ACCESS_KEY_SECRET must be set")
+		fakeOSS = fakeoss.NewServer()
+		fakeOSS.AddAccessKey(accessKeyID, accessKeySecret)
+		fakeOSS.CreateBucket(bucketName)
+		endpoint = fakeOSS.Endpoint()
+	} else {
+		accessKeySecret = os.Getenv("ACCESS_KEY_SECRET")
+		Expect(accessKeySecret).ToNot(BeEmpty(), "ACCESS_KEY_SECRET must be set")
 
-	endpoint = os.Getenv("ENDPOINT")
-	Expect(endpoint).ToNot(BeEmpty(), "ENDPOINT must be set")
+		endpoint = os.Getenv("ENDPOINT")
+		Expect(endpoint).ToNot(BeEmpty(), "ENDPOINT must be set")
 
-	bucketName = os.Getenv("BUCKET_NAME")
-	Expect(bucketName).ToNot(BeEmpty(), "BUCKET_NAME must be set")
+		bucketName = os.Getenv("BUCKET_NAME")
+		Expect(bucketName).ToNot(BeEmpty(), "BUCKET_NAME must be set")
+	}
 
 	defaultConfig = config.AliStorageConfig{
 		AccessKeyID:     accessKeyID,
@@ -50,5 +65,17 @@ var _ = BeforeSuite(func() {
 })
 
 var _ = AfterSuite(func() {
+	if fakeOSS != nil {
+		fakeOSS.Close()
+	}
 	gexec.CleanupBuildArtifacts()
 })
+
+// objectURL returns the URL of an object without query, in the path style the SDK uses for the IP address of the
+// fake OSS server, or in the virtual-hosted style of OSS endpoints.
+func objectURL(object string) string {
+	if fakeOSS != nil {
+		return "http://" + endpoint + "/" + bucketName + "/" + object
+	}
+	return "http://" + bucketName + "." + endpoint + "/" + object
+}
@@ -5,9 +5,11 @@ import (
 	"github.com/onsi/ginkgo/v2"
 	"github.com/onsi/gomega"
 	"github.com/onsi/gomega/gexec"
+	"io"
 	"math/rand"
 	"os"
 	"os/exec"
+	"strings"
 	"time"
 
 	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
@@ -51,13 +53,33 @@ func MakeContentFile(content string) string {
 }
 
 func RunCli(cliPath string, configPath string, subcommand string, args ...string) (*gexec.Session, error) {
+	return RunCliWithStdin(cliPath, configPath, nil, subcommand, args...)
+}
+
+func RunCliWithStdin(cliPath string, configPath string, stdin io.Reader, subcommand string, args ...string) (*gexec.Session, error) {
+	return runCli(cliPath, configPath, nil, stdin, subcommand, args...)
+}
+
+// RunCliWithFlags runs the CLI with global flags, e.g. --error-format, which precede the subcommand.
+func RunCliWithFlags(cliPath string, configPath string, flags []string, subcommand string, args ...string) (*gexec.Session, error) {
+	return runCli(cliPath, configPath, flags, nil, subcommand, args...)
+}
+
+func runCli(cliPath string, configPath string, flags []string, stdin io.Reader, subcommand string, args ...string) (*gexec.Session, error) {
+	// Debug records in JSON are asserted on with LogEntries
 	cmdArgs := []string{
 		"-c",
 		configPath,
-		subcommand,
+		"--log-level",
+		"debug",
+		"--log-format",
+		"json",
 	}
+	cmdArgs = append(cmdArgs, flags...)
+	cmdArgs = append(cmdArgs, subcommand)
 	cmdArgs = append(cmdArgs, args...)
 	command := exec.Command(cliPath, cmdArgs...)
+	command.Stdin = stdin
 	gexecSession, err := gexec.Start(command, ginkgo.GinkgoWriter, ginkgo.GinkgoWriter)
 	if err != nil {
 		return nil, err
@@ -65,3 +87,15 @@ func RunCli(cliPath string, configPath string, subcommand string, args ...string
 	gexecSession.Wait(1 * time.Minute)
 	return gexecSession, nil
 }
+
+// LogEntries returns the JSON log records written to stderr by the CLI, skipping the plain error messages of commands.
+func LogEntries(session *gexec.Session) []map[string]any {
+	var entries []map[string]any
+	for _, line := range strings.Split(string(session.Err.Contents()), "\n") {
+		var entry map[string]any
+		if json.Unmarshal([]byte(line), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
@@ -1,51 +1,189 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/cloudfoundry/bosh-ali-storage-cli/client"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/clierror"
 	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
+	"io"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 )
 
 var version string
 
+// Values of the --error-format flag
+const (
+	errorFormatText = "text"
+	errorFormatJSON = "json"
+)
+
+// Values of the --log-level and --log-format flags
+const (
+	logLevelDebug = "debug"
+	logLevelInfo  = "info"
+	logLevelWarn  = "warn"
+	logLevelError = "error"
+	logLevelQuiet = "quiet"
+
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// streamPath stands for stdin as the source of `put` and for stdout as the destination of `get`
+const streamPath = "-"
+
+// errBlobMissing is returned by `exists` for a missing blob. It exits with the exit code of a missing blob,
+// but is not reported as an error, as a missing blob is an expected outcome of `exists`.
+var errBlobMissing = clierror.New(clierror.KindNotFound, errors.New("blob does not exist"))
+
+// errInvalidFlags is returned for flags which could not be parsed. The flag set has already reported them along with its usage.
+var errInvalidFlags = clierror.New(clierror.KindUsage, errors.New("invalid flags"))
+
+// blobstore are the operations of client.AliBlobstore used by the commands
+type blobstore interface {
+	Put(ctx context.Context, sourceFilePath string, destinationObject string) error
+	PutReader(ctx context.Context, key string, reader io.Reader, size int64, opts client.PutOptions) error
+	Get(ctx context.Context, sourceObject string, destinationFilePath string) error
+	GetWriter(ctx context.Context, key string, writer io.Writer, opts client.GetOptions) error
+	Delete(ctx context.Context, object string) error
+	DeleteRecursive(ctx context.Context, prefix string) (client.DeleteResult, error)
+	Copy(ctx context.Context, sourceObject string, destinationBucketName string, destinationObject string) error
+	Move(ctx context.Context, sourceObject string, destinationBucketName string, destinationObject string) error
+	Exists(ctx context.Context, object string) (bool, error)
+	Stat(ctx context.Context, object string) (client.ObjectProperties, error)
+	List(ctx context.Context, prefix string, delimiter string, maxKeys int) (client.ListResult, error)
+	SignURL(ctx context.Context, object string, action string, expiredInSec int64, opts client.SignOptions) (client.SignedURL, error)
+	SignPost(ctx context.Context, expiredInSec int64, opts client.PostPolicyOptions) (client.SignedPostPolicy, error)
+}
+
+// cli runs the commands with its streams, environment and blobstore, which are replaced in tests.
+type cli struct {
+	stdin        io.Reader
+	stdout       io.Writer
+	stderr       io.Writer
+	lookupEnv    config.LookupEnvFunc
+	newBlobstore func(config.AliStorageConfig) (blobstore, error)
+}
+
 func main() {
+	// An interrupt or termination signal cancels the running command, which aborts incomplete multipart uploads
+	// and removes partial downloads. A second signal terminates the CLI immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
 
-	configPath := flag.String("c", "", "configuration path")
-	showVer := flag.Bool("v", false, "version")
-	flag.Parse()
+	c := cli{
+		stdin:        os.Stdin,
+		stdout:       os.Stdout,
+		stderr:       os.Stderr,
+		lookupEnv:    os.LookupEnv,
+		newBlobstore: newBlobstore,
+	}
+	exitCode := c.run(ctx, os.Args[1:])
 
-	if *showVer {
-		fmt.Printf("version %s\n", version)
-		os.Exit(0)
+	stop()
+	os.Exit(exitCode)
+}
+
+// newBlobstore creates the blobstore of a validated configuration.
+func newBlobstore(aliConfig config.AliStorageConfig) (blobstore, error) {
+	storageClient, err := client.NewStorageClient(aliConfig)
+	if err != nil {
+		return nil, clierror.New(clierror.KindInvalidConfig, err)
+	}
+
+	blobstoreClient, err := client.New(storageClient)
+	if err != nil {
+		return nil, err
 	}
+	return &blobstoreClient, nil
+}
+
+// run runs the command of args and returns the exit code of the CLI.
+func (c cli) run(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("bosh-ali-storage-cli", flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	configPath := flags.String("c", "", "configuration path (optional if configured through environment variables or a profile file)")
+	showVer := flags.Bool("v", false, "version")
+	timeout := flags.Duration("timeout", 0, "cancel the command after the given duration, e.g. 10m (default: no timeout)")
+	logLevel := flags.String("log-level", logLevelQuiet, "minimum level of the log written to stderr, one of debug, info, warn, error or quiet")
+	logFormat := flags.String("log-format", logFormatText, "format of the log, one of text or json")
+	errorFormat := flags.String("error-format", errorFormatText, "format of the error of a failed command, one of text or json")
 
-	configFile, err := os.Open(*configPath)
+	err := parseFlags(flags, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
 	if err != nil {
-		log.Fatalln(err)
+		return errInvalidFlags.ExitCode()
+	}
+
+	if *errorFormat != errorFormatText && *errorFormat != errorFormatJSON {
+		return c.fail(errorFormatText, clierror.Newf(clierror.KindUsage, "error format '%s' is not one of text or json", *errorFormat))
 	}
 
-	aliConfig, err := config.NewFromReader(configFile)
+	logger, err := newLogger(c.stderr, *logLevel, *logFormat)
 	if err != nil {
-		log.Fatalln(err)
+		return c.fail(*errorFormat, clierror.New(clierror.KindUsage, err))
 	}
+	slog.SetDefault(logger)
 
-	storageClient, err := client.NewStorageClient(aliConfig)
+	if *showVer {
+		fmt.Fprintf(c.stdout, "version %s\n", version)
+		return 0
+	}
+
+	aliConfig, err := config.Load(*configPath, c.lookupEnv)
 	if err != nil {
-		log.Fatalln(err)
+		return c.fail(*errorFormat, clierror.New(clierror.KindInvalidConfig, err))
 	}
 
-	blobstoreClient, err := client.New(storageClient)
+	// The configuration is validated before any request is sent, as OSS reports most configuration problems
+	// only as generic request failures
+	err = aliConfig.Validate()
 	if err != nil {
-		log.Fatalln(err)
+		return c.fail(*errorFormat, clierror.New(clierror.KindInvalidConfig, err))
+	}
+
+	blobstoreClient, err := c.newBlobstore(aliConfig)
+	if err != nil {
+		return c.fail(*errorFormat, err)
+	}
+
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	err = c.runCommand(ctx, blobstoreClient, flags.Args())
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errBlobMissing), errors.Is(err, errInvalidFlags):
+		return clierror.Classify(err).ExitCode()
+	default:
+		return c.fail(*errorFormat, err)
 	}
+}
 
-	nonFlagArgs := flag.Args()
-	if len(nonFlagArgs) < 2 {
-		log.Fatalf("Expected at least two arguments got %d\n", len(nonFlagArgs))
+// runCommand runs the command of the non-flag arguments against blobstoreClient.
+func (c cli) runCommand(ctx context.Context, blobstoreClient blobstore, nonFlagArgs []string) error {
+	if len(nonFlagArgs) < 2 && !(len(nonFlagArgs) == 1 && nonFlagArgs[0] == "list") {
+		return clierror.Newf(clierror.KindUsage, "Expected at least two arguments got %d", len(nonFlagArgs))
 	}
 
 	cmd := nonFlagArgs[0]
@@ -53,82 +191,394 @@ func main() {
 	switch cmd {
 	case "put":
 		if len(nonFlagArgs) != 3 {
-			log.Fatalf("Put method expected 3 arguments got %d\n", len(nonFlagArgs))
+			return clierror.Newf(clierror.KindUsage, "Put method expected 3 arguments got %d", len(nonFlagArgs))
 		}
 		sourceFilePath, destination := nonFlagArgs[1], nonFlagArgs[2]
 
+		// `-` uploads the content read from stdin
+		if sourceFilePath == streamPath {
+			err := blobstoreClient.PutReader(ctx, destination, c.stdin, -1, client.PutOptions{})
+			return operationError(cmd, err)
+		}
+
 		_, err := os.Stat(sourceFilePath)
 		if err != nil {
-			log.Fatalln(err)
+			return err
 		}
 
-		err = blobstoreClient.Put(sourceFilePath, destination)
-		fatalLog(cmd, err)
+		err = blobstoreClient.Put(ctx, sourceFilePath, destination)
+		return operationError(cmd, err)
 
 	case "get":
 		if len(nonFlagArgs) != 3 {
-			log.Fatalf("Get method expected 3 arguments got %d\n", len(nonFlagArgs))
+			return clierror.Newf(clierror.KindUsage, "Get method expected 3 arguments got %d", len(nonFlagArgs))
 		}
 		source, destinationFilePath := nonFlagArgs[1], nonFlagArgs[2]
 
-		err = blobstoreClient.Get(source, destinationFilePath)
-		fatalLog(cmd, err)
+		// `-` writes the content of the blob to stdout
+		if destinationFilePath == streamPath {
+			err := blobstoreClient.GetWriter(ctx, source, c.stdout, client.GetOptions{})
+			return operationError(cmd, err)
+		}
+
+		err := blobstoreClient.Get(ctx, source, destinationFilePath)
+		return operationError(cmd, err)
 
 	case "delete":
 		if len(nonFlagArgs) != 2 {
-			log.Fatalf("Delete method expected 2 arguments got %d\n", len(nonFlagArgs))
+			return clierror.Newf(clierror.KindUsage, "Delete method expected 2 arguments got %d", len(nonFlagArgs))
+		}
+
+		err := blobstoreClient.Delete(ctx, nonFlagArgs[1])
+		return operationError(cmd, err)
+
+	case "delete-recursive":
+		deleteFlags := c.commandFlags(cmd)
+		dryRun := deleteFlags.Bool("dry-run", false, "only list the blobs which would be deleted")
+		confirmed := deleteFlags.Bool("yes", false, "confirm deleting all blobs below the prefix")
+		err := parseFlags(deleteFlags, nonFlagArgs[1:])
+		if err != nil {
+			return err
+		}
+
+		if deleteFlags.NArg() != 1 {
+			return clierror.Newf(clierror.KindUsage, "Delete-recursive method expected 1 argument got %d", deleteFlags.NArg())
+		}
+		prefix := deleteFlags.Arg(0)
+		// An empty prefix matches every blob of the bucket
+		if prefix == "" {
+			return clierror.Newf(clierror.KindUsage, "Delete-recursive method expected a non-empty prefix")
+		}
+
+		if *dryRun {
+			listed, err := blobstoreClient.List(ctx, prefix, "", 0)
+			if err != nil {
+				return operationError(cmd, err)
+			}
+
+			for _, object := range listed.Objects {
+				fmt.Fprintln(c.stdout, object.Key)
+			}
+			fmt.Fprintf(c.stdout, "Would delete %d blobs below '%s'\n", len(listed.Objects), prefix)
+			return nil
+		}
+
+		// Deleting a whole tree of blobs cannot be undone, so it has to be confirmed explicitly
+		if !*confirmed {
+			return clierror.Newf(clierror.KindUsage, "Refusing to delete all blobs below '%s' without --yes, use --dry-run to list them first", prefix)
 		}
 
-		err = blobstoreClient.Delete(nonFlagArgs[1])
-		fatalLog(cmd, err)
+		result, err := blobstoreClient.DeleteRecursive(ctx, prefix)
+		for _, failure := range result.Failed {
+			c.errorLog().Printf("Failed to delete %s: %s\n", failure.Key, failure.Error)
+		}
+		fmt.Fprintf(c.stdout, "Deleted %d of %d blobs below '%s'\n", len(result.Deleted), len(result.Deleted)+len(result.Failed), prefix)
+		return operationError(cmd, err)
+
+	case "copy", "move":
+		copyFlags := c.commandFlags(cmd)
+		destinationBucketName := copyFlags.String("dest-bucket", "", "bucket of the destination blob (default: the configured bucket)")
+		err := parseFlags(copyFlags, nonFlagArgs[1:])
+		if err != nil {
+			return err
+		}
+
+		if copyFlags.NArg() != 2 {
+			return clierror.Newf(clierror.KindUsage, "%s method expected 2 arguments got %d", strings.ToUpper(cmd[:1])+cmd[1:], copyFlags.NArg())
+		}
+		source, destination := copyFlags.Arg(0), copyFlags.Arg(1)
+
+		if cmd == "copy" {
+			err = blobstoreClient.Copy(ctx, source, *destinationBucketName, destination)
+		} else {
+			err = blobstoreClient.Move(ctx, source, *destinationBucketName, destination)
+		}
+		return operationError(cmd, err)
 
 	case "exists":
 		if len(nonFlagArgs) != 2 {
-			log.Fatalf("Exists method expected 2 arguments got %d\n", len(nonFlagArgs))
+			return clierror.Newf(clierror.KindUsage, "Exists method expected 2 arguments got %d", len(nonFlagArgs))
+		}
+
+		// The exit status is 0 if the object exists and 3 if it does not. A failure to tell, e.g. for
+		// invalid credentials or an unreachable endpoint, exits with the exit code of the failure.
+		exists, err := blobstoreClient.Exists(ctx, nonFlagArgs[1])
+		if err != nil {
+			return operationError(cmd, err)
+		}
+		if !exists {
+			return errBlobMissing
+		}
+		return nil
+
+	case "stat":
+		if len(nonFlagArgs) != 2 {
+			return clierror.Newf(clierror.KindUsage, "Stat method expected 2 arguments got %d", len(nonFlagArgs))
+		}
+
+		// Like `exists`, a missing blob exits with 3
+		properties, err := blobstoreClient.Stat(ctx, nonFlagArgs[1])
+		if err != nil {
+			return operationError(cmd, err)
+		}
+
+		output, err := json.Marshal(properties)
+		if err != nil {
+			return operationError(cmd, err)
+		}
+
+		fmt.Fprintln(c.stdout, string(output))
+		return nil
+
+	case "list":
+		listFlags := c.commandFlags(cmd)
+		delimiter := listFlags.String("delimiter", "", "group keys containing the delimiter after the prefix into common prefixes")
+		maxKeys := listFlags.Int("max-keys", 0, "maximum number of listed keys (default: unlimited)")
+		jsonOutput := listFlags.Bool("json", false, "print JSON lines with the properties of each blob")
+		err := parseFlags(listFlags, nonFlagArgs[1:])
+		if err != nil {
+			return err
 		}
 
-		var exists bool
-		exists, err = blobstoreClient.Exists(nonFlagArgs[1])
+		if listFlags.NArg() > 1 {
+			return clierror.Newf(clierror.KindUsage, "List method expected at most 1 argument got %d", listFlags.NArg())
+		}
 
-		// If the object exists the exit status is 0, otherwise it is 3
-		// We are using `3` since `1` and `2` have special meanings
-		if err == nil && !exists {
-			os.Exit(3)
+		result, err := blobstoreClient.List(ctx, listFlags.Arg(0), *delimiter, *maxKeys)
+		if err != nil {
+			return operationError(cmd, err)
 		}
 
+		err = printListResult(c.stdout, result, *jsonOutput)
+		return operationError(cmd, err)
+
 	case "sign":
-		if len(nonFlagArgs) != 4 {
-			log.Fatalf("Sign method expects 3 arguments got %d\n", len(nonFlagArgs)-1)
+		signFlags := c.commandFlags(cmd)
+		var options client.SignOptions
+		signFlags.StringVar(&options.ContentType, "content-type", "", "Content-Type the upload has to be sent with")
+		signFlags.StringVar(&options.ContentMD5, "content-md5", "", "base64 encoded Content-MD5 the upload has to be sent with")
+		signFlags.StringVar(&options.ResponseContentDisposition, "response-content-disposition", "", "Content-Disposition of the response of the download")
+		signFlags.Int64Var(&options.TrafficLimit, "traffic-limit", 0, "bandwidth limit of the transfer in bit/s (default: unlimited)")
+		signFlags.StringVar(&options.ServerSideEncryption, "server-side-encryption", "", "server-side encryption of the upload (default: the configured server_side_encryption)")
+		signFlags.StringVar(&options.SSEKMSKeyID, "sse-kms-key-id", "", "KMS key of the server-side encryption of the upload")
+		jsonOutput := signFlags.Bool("json", false, "print the url, method and headers as JSON")
+		err := parseFlags(signFlags, nonFlagArgs[1:])
+		if err != nil {
+			return err
 		}
 
-		object, action := nonFlagArgs[1], nonFlagArgs[2]
+		if signFlags.NArg() != 3 {
+			return clierror.Newf(clierror.KindUsage, "Sign method expects 3 arguments got %d", signFlags.NArg())
+		}
+
+		object, action := signFlags.Arg(0), signFlags.Arg(1)
 
-		if action != "get" && action != "put" {
-			log.Fatalf("Action not implemented: %s. Available actions are 'get' and 'put'", action)
+		method := strings.ToUpper(action)
+		if method != http.MethodGet && method != http.MethodPut && method != http.MethodHead && method != http.MethodDelete {
+			return clierror.Newf(clierror.KindUsage, "Action not implemented: %s. Available actions are 'get', 'put', 'head' and 'delete'", action)
+		}
+
+		err = options.Validate(method)
+		if err != nil {
+			return clierror.New(clierror.KindUsage, err)
 		}
 
-		duration, err := time.ParseDuration(nonFlagArgs[3])
+		duration, err := time.ParseDuration(signFlags.Arg(2))
 		if err != nil {
-			log.Fatalf("Expiration should be in the format of a duration i.e. 1h, 60m, 3600s. Got: %s", nonFlagArgs[3])
+			return clierror.Newf(clierror.KindUsage, "Expiration should be in the format of a duration i.e. 1h, 60m, 3600s. Got: %s", signFlags.Arg(2))
 		}
 
 		expiredInSec := int64(duration.Seconds())
-		signedURL, err := blobstoreClient.Sign(object, action, expiredInSec)
+		signedURL, err := blobstoreClient.SignURL(ctx, object, action, expiredInSec, options)
+
+		if err != nil {
+			return fmt.Errorf("Failed to sign request: %w", err)
+		}
+
+		return printSignedURL(c.stdout, signedURL, *jsonOutput)
 
+	case "sign-post":
+		postFlags := c.commandFlags(cmd)
+		var options client.PostPolicyOptions
+		postFlags.StringVar(&options.KeyPrefix, "key-prefix", "", "prefix the keys of the uploaded blobs have to start with")
+		postFlags.BoolVar(&options.AllowAnyKey, "any-key", false, "allow uploads to any key without --key-prefix, overwriting any blob")
+		postFlags.Int64Var(&options.MinContentLength, "min-size", 0, "minimum size of the uploaded blobs in bytes")
+		postFlags.Int64Var(&options.MaxContentLength, "max-size", 0, "maximum size of the uploaded blobs in bytes (default: 5 GB if --min-size is set)")
+		postFlags.StringVar(&options.ContentType, "content-type", "", "Content-Type the uploads have to be sent with (default: any type)")
+		err := parseFlags(postFlags, nonFlagArgs[1:])
 		if err != nil {
-			log.Fatalf("Failed to sign request: %s", err)
+			return err
 		}
 
-		fmt.Println(signedURL)
-		os.Exit(0)
+		if postFlags.NArg() != 1 {
+			return clierror.Newf(clierror.KindUsage, "Sign-post method expects 1 argument got %d", postFlags.NArg())
+		}
+
+		err = options.Validate()
+		if err != nil {
+			return clierror.New(clierror.KindUsage, err)
+		}
+
+		duration, err := time.ParseDuration(postFlags.Arg(0))
+		if err != nil {
+			return clierror.Newf(clierror.KindUsage, "Expiration should be in the format of a duration i.e. 1h, 60m, 3600s. Got: %s", postFlags.Arg(0))
+		}
+		if duration < time.Second {
+			return clierror.Newf(clierror.KindUsage, "Expiration should be at least 1s. Got: %s", postFlags.Arg(0))
+		}
+
+		signedPolicy, err := blobstoreClient.SignPost(ctx, int64(duration.Seconds()), options)
+		if err != nil {
+			return fmt.Errorf("Failed to sign policy: %w", err)
+		}
+
+		output, err := json.Marshal(signedPolicy)
+		if err != nil {
+			return operationError(cmd, err)
+		}
+
+		fmt.Fprintln(c.stdout, string(output))
+		return nil
 
 	default:
-		log.Fatalf("unknown command: '%s'\n", cmd)
+		return clierror.Newf(clierror.KindUsage, "unknown command: '%s'", cmd)
+	}
+}
+
+// commandFlags returns the flag set of the flags of a command, which reports invalid flags on stderr.
+func (c cli) commandFlags(cmd string) *flag.FlagSet {
+	flags := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	return flags
+}
+
+// parseFlags parses the flags of args, returning flag.ErrHelp for -h and errInvalidFlags for invalid flags.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	err := flags.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return errInvalidFlags
+	}
+	return err
+}
+
+// printListResult prints one line per common prefix and blob, either the plain key or a JSON object.
+func printListResult(writer io.Writer, result client.ListResult, jsonOutput bool) error {
+	for _, prefix := range result.CommonPrefixes {
+		if !jsonOutput {
+			fmt.Fprintln(writer, prefix)
+			continue
+		}
+
+		line, err := json.Marshal(map[string]string{"prefix": prefix})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(writer, string(line))
+	}
+
+	for _, object := range result.Objects {
+		if !jsonOutput {
+			fmt.Fprintln(writer, object.Key)
+			continue
+		}
+
+		line, err := json.Marshal(object)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(writer, string(line))
+	}
+
+	return nil
+}
+
+// printSignedURL prints the signed URL alone, as callers read the output as the URL, or a JSON object with the URL,
+// method and headers the request has to send.
+func printSignedURL(writer io.Writer, signedURL client.SignedURL, jsonOutput bool) error {
+	if jsonOutput {
+		output, err := json.Marshal(signedURL)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(writer, string(output))
+		return nil
 	}
+
+	fmt.Fprintln(writer, signedURL.URL)
+	return nil
 }
 
-func fatalLog(cmd string, err error) {
+// operationError adds the command to the error of a failed operation.
+func operationError(cmd string, err error) error {
 	if err != nil {
-		log.Fatalf("performing operation %s: %s\n", cmd, err)
+		return fmt.Errorf("performing operation %s: %w", cmd, err)
+	}
+	return nil
+}
+
+// errorReport is the JSON object written to stderr for a failed command with --error-format json
+type errorReport struct {
+	Error      string        `json:"error"`
+	Kind       clierror.Kind `json:"kind"`
+	ExitCode   int           `json:"exit_code"`
+	Code       string        `json:"code,omitempty"`
+	Message    string        `json:"message,omitempty"`
+	RequestID  string        `json:"request_id,omitempty"`
+	StatusCode int           `json:"status_code,omitempty"`
+}
+
+// errorLog returns the logger of the errors of commands, which are written to stderr independent of the log level.
+func (c cli) errorLog() *log.Logger {
+	return log.New(c.stderr, "", log.LstdFlags)
+}
+
+// fail writes err to stderr in the given format and returns the exit code of its kind,
+// so that callers can tell e.g. a missing blob, denied access and an invalid configuration apart.
+func (c cli) fail(errorFormat string, err error) int {
+	classified := clierror.Classify(err)
+
+	if errorFormat == errorFormatJSON {
+		output, _ := json.Marshal(errorReport{
+			Error:      err.Error(),
+			Kind:       classified.Kind,
+			ExitCode:   classified.ExitCode(),
+			Code:       classified.Code,
+			Message:    classified.Message,
+			RequestID:  classified.RequestID,
+			StatusCode: classified.StatusCode,
+		})
+		fmt.Fprintln(c.stderr, string(output))
+	} else {
+		c.errorLog().Println(err)
+	}
+
+	return classified.ExitCode()
+}
+
+// newLogger returns the logger writing records of at least level to writer in the given format.
+// The quiet level discards all records, so that the output of the CLI is limited to the result and errors of the command.
+func newLogger(writer io.Writer, level string, format string) (*slog.Logger, error) {
+	levels := map[string]slog.Level{
+		logLevelDebug: slog.LevelDebug,
+		logLevelInfo:  slog.LevelInfo,
+		logLevelWarn:  slog.LevelWarn,
+		logLevelError: slog.LevelError,
+	}
+
+	minLevel, ok := levels[level]
+	if level == logLevelQuiet {
+		writer = io.Discard
+	} else if !ok {
+		return nil, fmt.Errorf("log level '%s' is not one of debug, info, warn, error or quiet", level)
+	}
+
+	options := &slog.HandlerOptions{Level: minLevel}
+	switch format {
+	case logFormatText:
+		return slog.New(slog.NewTextHandler(writer, options)), nil
+	case logFormatJSON:
+		return slog.New(slog.NewJSONHandler(writer, options)), nil
+	default:
+		return nil, fmt.Errorf("log format '%s' is not one of text or json", format)
 	}
 }
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/client"
+	"github.com/cloudfoundry/bosh-ali-storage-cli/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeBlobstore records the calls of the commands and returns the configured results
+type fakeBlobstore struct {
+	blobstore
+
+	calls      []string
+	exists     bool
+	properties client.ObjectProperties
+	signedURL  client.SignedURL
+	err        error
+	stdin      string
+}
+
+func (f *fakeBlobstore) PutReader(_ context.Context, key string, reader io.Reader, _ int64, _ client.PutOptions) error {
+	f.calls = append(f.calls, "put-reader "+key)
+	content, err := io.ReadAll(reader)
+	Expect(err).ToNot(HaveOccurred())
+	f.stdin = string(content)
+	return f.err
+}
+
+func (f *fakeBlobstore) Delete(_ context.Context, object string) error {
+	f.calls = append(f.calls, "delete "+object)
+	return f.err
+}
+
+func (f *fakeBlobstore) DeleteRecursive(_ context.Context, prefix string) (client.DeleteResult, error) {
+	f.calls = append(f.calls, "delete-recursive "+prefix)
+	return client.DeleteResult{}, f.err
+}
+
+func (f *fakeBlobstore) Exists(_ context.Context, object string) (bool, error) {
+	f.calls = append(f.calls, "exists "+object)
+	return f.exists, f.err
+}
+
+func (f *fakeBlobstore) Stat(_ context.Context, object string) (client.ObjectProperties, error) {
+	f.calls = append(f.calls, "stat "+object)
+	return f.properties, f.err
+}
+
+func (f *fakeBlobstore) SignURL(_ context.Context, object string, action string, expiredInSec int64, opts client.SignOptions) (client.SignedURL, error) {
+	f.calls = append(f.calls, fmt.Sprintf("sign %s %s %d %+v", object, action, expiredInSec, opts))
+	return f.signedURL, f.err
+}
+
+func (f *fakeBlobstore) SignPost(_ context.Context, expiredInSec int64, opts client.PostPolicyOptions) (client.SignedPostPolicy, error) {
+	f.calls = append(f.calls, fmt.Sprintf("sign-post %d %+v", expiredInSec, opts))
+	return client.SignedPostPolicy{URL: "https://the-bucket-url", Fields: map[string]string{"key": opts.KeyPrefix + "${filename}"}}, f.err
+}
+
+var _ = Describe("cli", func() {
+	var (
+		fake           *fakeBlobstore
+		stdin          string
+		stdout, stderr *bytes.Buffer
+		env            map[string]string
+	)
+
+	BeforeEach(func() {
+		fake = &fakeBlobstore{}
+		stdin = ""
+		stdout = &bytes.Buffer{}
+		stderr = &bytes.Buffer{}
+		env = map[string]string{
+			"ALI_ACCESS_KEY_ID":     "foo_access_key_id",
+			"ALI_ACCESS_KEY_SECRET": "foo_access_key_secret",
+			"ALI_ENDPOINT":          "oss-cn-hangzhou.aliyuncs.com",
+			"ALI_BUCKET_NAME":       "foo-bucket-name",
+		}
+	})
+
+	run := func(args ...string) int {
+		c := cli{
+			stdin:  strings.NewReader(stdin),
+			stdout: stdout,
+			stderr: stderr,
+			lookupEnv: func(name string) (string, bool) {
+				value, ok := env[name]
+				return value, ok
+			},
+			newBlobstore: func(aliConfig config.AliStorageConfig) (blobstore, error) {
+				Expect(aliConfig.BucketName).To(Equal("foo-bucket-name"))
+				return fake, nil
+			},
+		}
+		return c.run(context.Background(), args)
+	}
+
+	serviceError := func(statusCode int, code string) error {
+		return oss.ServiceError{StatusCode: statusCode, Code: code, Message: "foo message", RequestID: "foo-request-id"}
+	}
+
+	Describe("exists", func() {
+		It("exits with 0 for an existing blob", func() {
+			fake.exists = true
+
+			Expect(run("exists", "blob")).To(Equal(0))
+			Expect(fake.calls).To(Equal([]string{"exists blob"}))
+			Expect(stderr.String()).To(BeEmpty())
+		})
+
+		It("exits with 3 without an error for a missing blob", func() {
+			Expect(run("exists", "blob")).To(Equal(3))
+			Expect(stderr.String()).To(BeEmpty())
+		})
+
+		It("reports a failure to tell whether the blob exists", func() {
+			fake.err = serviceError(http.StatusForbidden, "InvalidAccessKeyId")
+
+			Expect(run("exists", "blob")).To(Equal(7))
+			Expect(stderr.String()).To(ContainSubstring("performing operation exists"))
+			Expect(stderr.String()).To(ContainSubstring("InvalidAccessKeyId"))
+		})
+
+		It("reports a failure for a blob reported as existing along with an error", func() {
+			fake.exists = true
+			fake.err = errors.New("connection reset")
+
+			Expect(run("exists", "blob")).To(Equal(1))
+			Expect(stderr.String()).To(ContainSubstring("connection reset"))
+		})
+	})
+
+	Describe("stat", func() {
+		It("prints the properties of the blob as JSON", func() {
+			fake.properties = client.ObjectProperties{Size: 3, ETag: "foo-etag"}
+
+			Expect(run("stat", "blob")).To(Equal(0))
+			Expect(stdout.String()).To(ContainSubstring(`"size":3`))
+			Expect(stdout.String()).To(ContainSubstring(`"etag":"foo-etag"`))
+		})
+
+		It("exits with 3 for a missing blob", func() {
+			fake.err = client.ErrNotFound
+
+			Expect(run("stat", "blob")).To(Equal(3))
+			Expect(stdout.String()).To(BeEmpty())
+			Expect(stderr.String()).To(ContainSubstring("blob not found"))
+		})
+	})
+
+	Describe("sign", func() {
+		BeforeEach(func() {
+			fake.signedURL = client.SignedURL{
+				URL:     "https://the-signed-url",
+				Method:  http.MethodPut,
+				Headers: map[string]string{"Content-Type": "text/plain", "Content-Md5": "rL0Y20zC+Fzt72VPzMSk2A=="},
+			}
+		})
+
+		It("prints only the url", func() {
+			Expect(run("sign", "--content-type", "text/plain", "--content-md5", "rL0Y20zC+Fzt72VPzMSk2A==", "blob", "put", "1h")).To(Equal(0))
+			Expect(fake.calls).To(ConsistOf(ContainSubstring("sign blob put 3600 {ContentType:text/plain ContentMD5:rL0Y20zC+Fzt72VPzMSk2A==")))
+			Expect(stdout.String()).To(Equal("https://the-signed-url\n"))
+		})
+
+		It("prints the url, method and headers as JSON with --json", func() {
+			Expect(run("sign", "--json", "blob", "put", "1h")).To(Equal(0))
+
+			var output map[string]interface{}
+			Expect(json.Unmarshal(stdout.Bytes(), &output)).To(Succeed())
+			Expect(output).To(Equal(map[string]interface{}{
+				"url":     "https://the-signed-url",
+				"method":  "PUT",
+				"headers": map[string]interface{}{"Content-Type": "text/plain", "Content-Md5": "rL0Y20zC+Fzt72VPzMSk2A=="},
+			}))
+		})
+	})
+
+	It("prints the signed POST policy as JSON", func() {
+		Expect(run("sign-post", "--key-prefix", "uploads/", "--max-size", "1024", "--content-type", "text/plain", "10m")).To(Equal(0))
+		Expect(fake.calls).To(Equal([]string{"sign-post 600 {KeyPrefix:uploads/ AllowAnyKey:false MinContentLength:0 MaxContentLength:1024 ContentType:text/plain}"}))
+
+		var output map[string]interface{}
+		Expect(json.Unmarshal(stdout.Bytes(), &output)).To(Succeed())
+		Expect(output).To(HaveKeyWithValue("url", "https://the-bucket-url"))
+		Expect(output).To(HaveKeyWithValue("fields", map[string]interface{}{"key": "uploads/${filename}"}))
+	})
+
+	It("signs a POST policy for any key with --any-key", func() {
+		Expect(run("sign-post", "--any-key", "10m")).To(Equal(0))
+		Expect(fake.calls).To(Equal([]string{"sign-post 600 {KeyPrefix: AllowAnyKey:true MinContentLength:0 MaxContentLength:0 ContentType:}"}))
+	})
+
+	It("uploads stdin with `put -`", func() {
+		stdin = "foo"
+
+		Expect(run("put", "-", "blob")).To(Equal(0))
+		Expect(fake.calls).To(Equal([]string{"put-reader blob"}))
+		Expect(fake.stdin).To(Equal("foo"))
+	})
+
+	It("refuses to delete recursively without --yes", func() {
+		Expect(run("delete-recursive", "prefix/")).To(Equal(2))
+		Expect(fake.calls).To(BeEmpty())
+		Expect(stderr.String()).To(ContainSubstring("without --yes"))
+	})
+
+	It("refuses to delete recursively with an empty prefix", func() {
+		Expect(run("delete-recursive", "--yes", "")).To(Equal(2))
+		Expect(fake.calls).To(BeEmpty())
+		Expect(stderr.String()).To(ContainSubstring("non-empty prefix"))
+	})
+
+	It("reports the OSS error as JSON with --error-format json", func() {
+		fake.err = serviceError(http.StatusForbidden, "AccessDenied")
+
+		Expect(run("--error-format", "json", "delete", "blob")).To(Equal(6))
+
+		var report map[string]interface{}
+		Expect(json.Unmarshal(stderr.Bytes(), &report)).To(Succeed())
+		Expect(report).To(Equal(map[string]interface{}{
+			"error":       `performing operation delete: oss: service returned error: StatusCode=403, ErrorCode=AccessDenied, ErrorMessage="foo message", RequestId=foo-request-id`,
+			"kind":        "access_denied",
+			"exit_code":   float64(6),
+			"code":        "AccessDenied",
+			"message":     "foo message",
+			"request_id":  "foo-request-id",
+			"status_code": float64(403),
+		}))
+	})
+
+	DescribeTable("exits with 2 for invalid usage without calling the blobstore",
+		func(args ...string) {
+			Expect(run(args...)).To(Equal(2))
+			Expect(fake.calls).To(BeEmpty())
+			Expect(stderr.String()).ToNot(BeEmpty())
+		},
+		Entry("no command", "exists"),
+		Entry("an unknown command", "foo", "blob"),
+		Entry("missing arguments", "put", "blob"),
+		Entry("an unknown flag", "--foo", "exists", "blob"),
+		Entry("an unknown command flag", "list", "--foo"),
+		Entry("an invalid sign action", "sign", "blob", "post", "1h"),
+		Entry("a sign option which does not apply to the action", "sign", "--content-type", "text/plain", "blob", "get", "1h"),
+		Entry("an inverted sign-post size range", "sign-post", "--key-prefix", "uploads/", "--min-size", "10", "--max-size", "5", "1h"),
+		Entry("a sign-post without a key prefix", "sign-post", "1h"),
+		Entry("a sign-post expiring immediately", "sign-post", "--key-prefix", "uploads/", "0s"),
+		Entry("an invalid error format", "--error-format", "yaml", "exists", "blob"),
+		Entry("an invalid log level", "--log-level", "trace", "exists", "blob"),
+	)
+
+	It("exits with 5 for an invalid configuration", func() {
+		delete(env, "ALI_BUCKET_NAME")
+
+		Expect(run("exists", "blob")).To(Equal(5))
+		Expect(fake.calls).To(BeEmpty())
+		Expect(stderr.String()).To(ContainSubstring("bucket_name is required"))
+	})
+
+	It("prints the version without a configuration", func() {
+		env = map[string]string{}
+
+		Expect(run("-v")).To(Equal(0))
+		Expect(stdout.String()).To(HavePrefix("version"))
+	})
+
+	It("fails for a local file which does not exist", func() {
+		Expect(run("put", "/does/not/exist", "blob")).To(Equal(1))
+		Expect(fake.calls).To(BeEmpty())
+		Expect(stderr.String()).To(ContainSubstring("no such file or directory"))
+	})
+})